@@ -6,7 +6,9 @@ package align
 
 import (
 	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/io/seqio"
 	"code.google.com/p/biogo/io/seqio/fasta"
+	"code.google.com/p/biogo/seq"
 	"code.google.com/p/biogo/seq/linear"
 	check "launchpad.net/gocheck"
 	"strings"
@@ -22,6 +24,25 @@ var _ = check.Suite(&S{})
 
 func (s *S) TestWarning(c *check.C) { c.Log("\nFIXME: Tests only in example tests.\n") }
 
+func (s *S) TestWorkspaceReuse(c *check.C) {
+	long, _ := benchSeqs(false)
+	short := linear.NewSeq("short", alphabet.BytesToLetters([]byte("ACGTACGTTGCA")), alphabet.DNAgapped)
+	shortq := linear.NewSeq("shortq", alphabet.BytesToLetters([]byte("ACGTTGCA")), alphabet.DNAgapped)
+	for _, a := range []WorkspaceAligner{
+		SW(benchMatrix), NW(benchMatrix), Fitted(benchMatrix),
+		SWAffine(benchAffine), NWAffine(benchAffine), FittedAffine(benchAffine),
+	} {
+		ws := &Workspace{}
+		_, err := a.AlignWith(ws, long, long)
+		c.Assert(err, check.Equals, nil)
+		want, err := a.Align(short, shortq)
+		c.Assert(err, check.Equals, nil)
+		got, err := a.AlignWith(ws, short, shortq)
+		c.Assert(err, check.Equals, nil)
+		c.Check(got, check.DeepEquals, want, check.Commentf("%T", a))
+	}
+}
+
 func BenchmarkSWAlign(b *testing.B) {
 	b.StopTimer()
 	t := &linear.Seq{}
@@ -113,3 +134,106 @@ func BenchmarkNWAffineAlign(b *testing.B) {
 		needle.Align(nwsa, nwsb)
 	}
 }
+
+func benchSeqs(quality bool) (a, b seq.Sequence) {
+	var t seqio.SequenceAppender
+	if quality {
+		qt := &linear.QSeq{}
+		qt.Alpha = alphabet.DNAgapped
+		t = qt
+	} else {
+		lt := &linear.Seq{}
+		lt.Alpha = alphabet.DNAgapped
+		t = lt
+	}
+	r := fasta.NewReader(strings.NewReader(crspFa), t)
+	a, _ = r.Read()
+	b, _ = r.Read()
+	return a, b
+}
+
+var benchMatrix = Linear{
+	{0, -1, -1, -1, -1},
+	{-1, 2, -1, -1, -1},
+	{-1, -1, 2, -1, -1},
+	{-1, -1, -1, 2, -1},
+	{-1, -1, -1, -1, 2},
+}
+
+func benchAlignWith(b *testing.B, a WorkspaceAligner, quality, reuse bool) {
+	b.StopTimer()
+	sa, sb := benchSeqs(quality)
+	var ws *Workspace
+	if reuse {
+		ws = &Workspace{}
+	}
+	b.ReportAllocs()
+	b.StartTimer()
+	for i := 0; i < b.N; i++ {
+		a.AlignWith(ws, sa, sb)
+	}
+}
+
+func BenchmarkSWLetters(b *testing.B)  { benchAlignWith(b, SW(benchMatrix), false, false) }
+func BenchmarkSWQLetters(b *testing.B) { benchAlignWith(b, SW(benchMatrix), true, false) }
+func BenchmarkSWLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, SW(benchMatrix), false, true)
+}
+func BenchmarkSWQLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, SW(benchMatrix), true, true)
+}
+func BenchmarkNWLetters(b *testing.B)  { benchAlignWith(b, NW(benchMatrix), false, false) }
+func BenchmarkNWQLetters(b *testing.B) { benchAlignWith(b, NW(benchMatrix), true, false) }
+func BenchmarkNWLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, NW(benchMatrix), false, true)
+}
+func BenchmarkNWQLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, NW(benchMatrix), true, true)
+}
+func BenchmarkFittedLetters(b *testing.B)  { benchAlignWith(b, Fitted(benchMatrix), false, false) }
+func BenchmarkFittedQLetters(b *testing.B) { benchAlignWith(b, Fitted(benchMatrix), true, false) }
+func BenchmarkFittedLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, Fitted(benchMatrix), false, true)
+}
+func BenchmarkFittedQLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, Fitted(benchMatrix), true, true)
+}
+
+var benchAffine = Affine{Matrix: benchMatrix, GapOpen: -5}
+
+func BenchmarkSWAffineLetters(b *testing.B) {
+	benchAlignWith(b, SWAffine(benchAffine), false, false)
+}
+func BenchmarkSWAffineQLetters(b *testing.B) {
+	benchAlignWith(b, SWAffine(benchAffine), true, false)
+}
+func BenchmarkSWAffineLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, SWAffine(benchAffine), false, true)
+}
+func BenchmarkSWAffineQLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, SWAffine(benchAffine), true, true)
+}
+func BenchmarkNWAffineLetters(b *testing.B) {
+	benchAlignWith(b, NWAffine(benchAffine), false, false)
+}
+func BenchmarkNWAffineQLetters(b *testing.B) {
+	benchAlignWith(b, NWAffine(benchAffine), true, false)
+}
+func BenchmarkNWAffineLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, NWAffine(benchAffine), false, true)
+}
+func BenchmarkNWAffineQLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, NWAffine(benchAffine), true, true)
+}
+func BenchmarkFittedAffineLetters(b *testing.B) {
+	benchAlignWith(b, FittedAffine(benchAffine), false, false)
+}
+func BenchmarkFittedAffineQLetters(b *testing.B) {
+	benchAlignWith(b, FittedAffine(benchAffine), true, false)
+}
+func BenchmarkFittedAffineLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, FittedAffine(benchAffine), false, true)
+}
+func BenchmarkFittedAffineQLettersWorkspace(b *testing.B) {
+	benchAlignWith(b, FittedAffine(benchAffine), true, true)
+}
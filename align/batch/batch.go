@@ -0,0 +1,182 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package batch provides concurrent alignment of streams of query sequences against a set
+// of reference sequences.
+package batch
+
+import (
+	"code.google.com/p/biogo/align"
+	"code.google.com/p/biogo/concurrent"
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/io/seqio"
+	"code.google.com/p/biogo/seq"
+
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// A Hit is the alignment of a query to a single reference.
+type Hit struct {
+	Reference seq.Sequence
+	Alignment []feat.Pair
+	Score     int
+}
+
+// A Result holds the alignments of a query to each of the references of an Aligner.
+type Result struct {
+	// Index is the position of the query in the input stream.
+	Index int
+	Query seq.Sequence
+
+	// Hits holds the alignment of the query to each reference in the
+	// order of the Aligner's References.
+	Hits []Hit
+
+	// Err holds any error returned by the reader or during alignment.
+	Err error
+}
+
+// An Aligner aligns a stream of query sequences to a set of reference sequences using a pool
+// of workers.
+//
+// New is called once for each worker to obtain the align.Aligner used by that worker, so any
+// buffers held by the returned aligner are reused for every alignment performed by the worker
+// and need not be safe for concurrent use. Threads is the number of workers; if Threads is
+// less than one or greater than GOMAXPROCS, GOMAXPROCS workers are used. Buffer is the maximum
+// number of queries held in flight; if Buffer is less than one, twice the number of workers
+// is used.
+type Aligner struct {
+	References []seq.Sequence
+	New        func() align.Aligner
+	Threads    int
+	Buffer     int
+}
+
+type scorer interface {
+	Score() int
+}
+
+// job is a concurrent.Operator that aligns a single query.
+type job struct {
+	index   int
+	query   seq.Sequence
+	refs    []seq.Sequence
+	pool    chan align.Aligner
+	promise *concurrent.Promise
+}
+
+func (j *job) Operation() (v interface{}, err error) {
+	res := Result{Index: j.index, Query: j.query}
+	defer func() {
+		if r := recover(); r != nil {
+			res.Err = fmt.Errorf("batch: alignment panic: %v", r)
+			res.Hits = nil
+			j.promise.Fulfill(res)
+		}
+		v = j.index
+	}()
+
+	a := <-j.pool
+	defer func() { j.pool <- a }()
+
+	res.Hits = make([]Hit, 0, len(j.refs))
+	for _, r := range j.refs {
+		aln, err := a.Align(r, j.query)
+		if err != nil {
+			res.Err = err
+			res.Hits = nil
+			break
+		}
+		h := Hit{Reference: r, Alignment: aln}
+		for _, fp := range aln {
+			if s, ok := fp.(scorer); ok {
+				h.Score += s.Score()
+			}
+		}
+		res.Hits = append(res.Hits, h)
+	}
+	j.promise.Fulfill(res)
+
+	return j.index, nil
+}
+
+// Align reads query sequences from r and aligns each to all the references, returning a
+// channel on which the results are sent in the order the queries were read. The channel
+// is closed when r returns io.EOF or after a Result holding any other read error is sent.
+func (a *Aligner) Align(r seqio.Reader) (<-chan Result, error) {
+	if a.New == nil {
+		return nil, errors.New("batch: no aligner constructor")
+	}
+	threads := a.Threads
+	if available := runtime.GOMAXPROCS(0); threads > available || threads < 1 {
+		threads = available
+	}
+	buffer := a.Buffer
+	if buffer < 1 {
+		buffer = 2 * threads
+	}
+
+	pool := make(chan align.Aligner, threads)
+	for i := 0; i < threads; i++ {
+		pool <- a.New()
+	}
+	refs := append([]seq.Sequence(nil), a.References...)
+
+	var (
+		queue   = make(chan concurrent.Operator)
+		pending = make(chan *concurrent.Promise, buffer)
+		results = make(chan Result, buffer)
+		proc    = concurrent.NewProcessor(queue, buffer, threads)
+	)
+
+	// Drain the processor; job results are delivered by promise.
+	go func() {
+		for {
+			v, err := proc.Result()
+			if v == nil && err == nil {
+				return
+			}
+		}
+	}()
+
+	// Read queries and submit them for alignment.
+	go func() {
+		defer func() {
+			close(pending)
+			proc.Close()
+		}()
+		for i := 0; ; i++ {
+			s, err := r.Read()
+			if err != nil {
+				if err != io.EOF {
+					p := concurrent.NewPromise(false, false, false)
+					p.Fulfill(Result{Index: i, Err: err})
+					pending <- p
+				}
+				return
+			}
+			p := concurrent.NewPromise(false, false, false)
+			pending <- p
+			proc.Process(&job{index: i, query: s, refs: refs, pool: pool, promise: p})
+		}
+	}()
+
+	// Deliver results in input order.
+	go func() {
+		defer close(results)
+		for p := range pending {
+			m := <-p.Wait()
+			res, _ := m.Value.(Result)
+			if m.Err != nil && res.Err == nil {
+				res.Err = m.Err
+			}
+			results <- res
+		}
+	}()
+
+	return results, nil
+}
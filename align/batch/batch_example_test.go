@@ -0,0 +1,72 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package batch
+
+import (
+	"code.google.com/p/biogo/align"
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/io/seqio/fasta"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+	"strings"
+)
+
+const queries = `>q1
+GACCTAGCATGA
+>q2
+TTAGGCTAGCAG
+>q3
+CCCCCCCC
+>q4
+AGCATGACTAGC
+`
+
+func ExampleAligner_Align() {
+	refs := []seq.Sequence{
+		linear.NewSeq("r1", alphabet.BytesToLetters([]byte("GACCTAGCATGACTAGCTAGGCTAGCAGTCA")), alphabet.DNAgapped),
+		linear.NewSeq("r2", alphabet.BytesToLetters([]byte("TTTTTTTTTTAGGCTAGCAGTTTTTTTTTTT")), alphabet.DNAgapped),
+	}
+
+	// w(gap) = -5
+	// w(match) = +2
+	// w(mismatch) = -3
+	sw := align.SW{
+		{0, -5, -5, -5, -5},
+		{-5, 2, -3, -3, -3},
+		{-5, -3, 2, -3, -3},
+		{-5, -3, -3, 2, -3},
+		{-5, -3, -3, -3, 2},
+	}
+
+	b := &Aligner{
+		References: refs,
+		New:        func() align.Aligner { return align.WithWorkspace(sw, nil) },
+		Threads:    4,
+	}
+	results, err := b.Align(fasta.NewReader(strings.NewReader(queries), linear.NewSeq("", nil, alphabet.DNAgapped)))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for r := range results {
+		if r.Err != nil {
+			fmt.Println(r.Err)
+			continue
+		}
+		fmt.Printf("%d %s:", r.Index, r.Query.Name())
+		for _, h := range r.Hits {
+			fmt.Printf(" %s=%d", h.Reference.Name(), h.Score)
+		}
+		fmt.Println()
+	}
+
+	// Output:
+	// 0 q1: r1=24 r2=12
+	// 1 q2: r1=22 r2=24
+	// 2 q3: r1=4 r2=2
+	// 3 q4: r1=24 r2=10
+}
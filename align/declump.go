@@ -0,0 +1,361 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/feat"
+
+	"fmt"
+)
+
+// letterIndices checks that reference and query are alignable and returns the alphabet
+// indices of their letters.
+func letterIndices(reference, query AlphabetSlicer) (rIdx, qIdx []int, err error) {
+	alpha := reference.Alphabet()
+	if alpha == nil {
+		return nil, nil, ErrNoAlphabet
+	}
+	if alpha != query.Alphabet() {
+		return nil, nil, ErrMismatchedAlphabets
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, nil, ErrNotGappedAlphabet
+	}
+	index := alpha.LetterIndex()
+	switch rSeq := reference.Slice().(type) {
+	case alphabet.Letters:
+		qSeq, ok := query.Slice().(alphabet.Letters)
+		if !ok {
+			return nil, nil, ErrMismatchedTypes
+		}
+		rIdx = make([]int, len(rSeq))
+		for i, l := range rSeq {
+			rIdx[i] = index[l]
+		}
+		qIdx = make([]int, len(qSeq))
+		for i, l := range qSeq {
+			qIdx[i] = index[l]
+		}
+	case alphabet.QLetters:
+		qSeq, ok := query.Slice().(alphabet.QLetters)
+		if !ok {
+			return nil, nil, ErrMismatchedTypes
+		}
+		rIdx = make([]int, len(rSeq))
+		for i, l := range rSeq {
+			rIdx[i] = index[l.L]
+		}
+		qIdx = make([]int, len(qSeq))
+		for i, l := range qSeq {
+			qIdx[i] = index[l.L]
+		}
+	default:
+		return nil, nil, ErrTypeNotHandled
+	}
+	return rIdx, qIdx, nil
+}
+
+// flatten returns the elements of the square matrix m in row-major order.
+func flatten(m Linear) ([]int, error) {
+	let := len(m)
+	la := make([]int, 0, let*let)
+	for _, row := range m {
+		if len(row) != let {
+			return nil, ErrMatrixNotSquare
+		}
+		la = append(la, row...)
+	}
+	return la, nil
+}
+
+// AlignAll aligns two sequences using the Smith-Waterman algorithm with the declumping method
+// of Waterman and Eggert (1987), returning successive non-intersecting local alignments in
+// descending score order. No two returned alignments share a reference/query position pair.
+// Alignment stops when the best remaining alignment scores less than min or when n alignments
+// have been found. If n is less than or equal to zero, all alignments scoring at least min are
+// returned. An error is returned if the scoring matrix is not square, or the sequence data types
+// or alphabets do not match.
+func (a SW) AlignAll(reference, query AlphabetSlicer, min, n int) ([][]feat.Pair, error) {
+	la, err := flatten(Linear(a))
+	if err != nil {
+		return nil, err
+	}
+	rIdx, qIdx, err := letterIndices(reference, query)
+	if err != nil {
+		return nil, err
+	}
+	let := len(a)
+	r, c := len(rIdx)+1, len(qIdx)+1
+	table := make([]int, r*c)
+	blocked := make([]bool, r*c)
+
+	// fill fills the table from row from, stopping when a row beyond
+	// last is unchanged since subsequent rows cannot change.
+	fill := func(from, last int) {
+		var scores [3]int
+		for i := from; i < r; i++ {
+			changed := false
+			for j := 1; j < c; j++ {
+				p := i*c + j
+				rVal, qVal := rIdx[i-1], qIdx[j-1]
+				score := 0
+				if !blocked[p] && rVal >= 0 && qVal >= 0 {
+					scores = [3]int{
+						diag: table[p-c-1] + la[rVal*let+qVal],
+						up:   table[p-c] + la[rVal*let],
+						left: table[p-1] + la[qVal],
+					}
+					score = max2(max(&scores), 0)
+				}
+				if table[p] != score {
+					table[p] = score
+					changed = true
+				}
+			}
+			if !changed && i > last {
+				return
+			}
+		}
+	}
+	fill(1, r)
+
+	var alns [][]feat.Pair
+	for n <= 0 || len(alns) < n {
+		maxS, maxI, maxJ := 0, 0, 0
+		for i := 1; i < r; i++ {
+			for j := 1; j < c; j++ {
+				if s := table[i*c+j]; s >= maxS { // greedy so make farthest down and right
+					maxS, maxI, maxJ = s, i, j
+				}
+			}
+		}
+		if maxS == 0 || maxS < min {
+			break
+		}
+
+		var aln []feat.Pair
+		score, last := 0, diag
+		i, j := maxI, maxJ
+		for i > 0 && j > 0 {
+			p := i*c + j
+			if table[p] == 0 {
+				break
+			}
+			blocked[p] = true
+			rVal, qVal := rIdx[i-1], qIdx[j-1]
+			var (
+				move int
+				prev int
+			)
+			switch table[p] {
+			case table[p-c-1] + la[rVal*let+qVal]:
+				move, prev = diag, p-c-1
+			case table[p-c] + la[rVal*let]:
+				move, prev = up, p-c
+			case table[p-1] + la[qVal]:
+				move, prev = left, p-1
+			default:
+				panic(fmt.Sprintf("align: sw internal error: no path at row: %d col:%d\n", i, j))
+			}
+			if last != move && (i != maxI || j != maxJ) {
+				aln = append(aln, &featPair{
+					a:     feature{start: i, end: maxI},
+					b:     feature{start: j, end: maxJ},
+					score: score,
+				})
+				maxI, maxJ = i, j
+				score = 0
+			}
+			score += table[p] - table[prev]
+			switch move {
+			case diag:
+				i--
+				j--
+			case up:
+				i--
+			case left:
+				j--
+			}
+			last = move
+		}
+		aln = append(aln, &featPair{
+			a:     feature{start: i, end: maxI},
+			b:     feature{start: j, end: maxJ},
+			score: score,
+		})
+		for i, j := 0, len(aln)-1; i < j; i, j = i+1, j-1 {
+			aln[i], aln[j] = aln[j], aln[i]
+		}
+		alns = append(alns, aln)
+
+		fill(i+1, aln[len(aln)-1].Features()[0].End())
+	}
+
+	return alns, nil
+}
+
+// AlignAll aligns two sequences using the Smith-Waterman algorithm with the declumping method
+// of Waterman and Eggert (1987), returning successive non-intersecting local alignments in
+// descending score order. No two returned alignments share a reference/query position pair.
+// Alignment stops when the best remaining alignment scores less than min or when n alignments
+// have been found. If n is less than or equal to zero, all alignments scoring at least min are
+// returned. An error is returned if the scoring matrix is not square, or the sequence data types
+// or alphabets do not match.
+func (a SWAffine) AlignAll(reference, query AlphabetSlicer, min, n int) ([][]feat.Pair, error) {
+	la, err := flatten(a.Matrix)
+	if err != nil {
+		return nil, err
+	}
+	rIdx, qIdx, err := letterIndices(reference, query)
+	if err != nil {
+		return nil, err
+	}
+	let := len(a.Matrix)
+	r, c := len(rIdx)+1, len(qIdx)+1
+	table := make([][3]int, r*c)
+	blocked := make([]bool, r*c)
+
+	// fill fills the table from row from, stopping when a row beyond
+	// last is unchanged since subsequent rows cannot change.
+	fill := func(from, last int) {
+		var scores [3]int
+		for i := from; i < r; i++ {
+			changed := false
+			for j := 1; j < c; j++ {
+				p := i*c + j
+				rVal, qVal := rIdx[i-1], qIdx[j-1]
+				var cell [3]int
+				if !blocked[p] && rVal >= 0 && qVal >= 0 {
+					scores = [3]int{
+						diag: table[p-c-1][diag],
+						up:   table[p-c-1][up],
+						left: table[p-c-1][left],
+					}
+					cell = [3]int{
+						diag: max2(max(&scores)+la[rVal*let+qVal], 0),
+						up: max2(max2(
+							table[p-c][diag]+a.GapOpen+la[rVal*let],
+							table[p-c][up]+la[rVal*let],
+						), 0),
+						left: max2(max2(
+							table[p-1][diag]+a.GapOpen+la[qVal],
+							table[p-1][left]+la[qVal],
+						), 0),
+					}
+				}
+				if table[p] != cell {
+					table[p] = cell
+					changed = true
+				}
+			}
+			if !changed && i > last {
+				return
+			}
+		}
+	}
+	fill(1, r)
+
+	var alns [][]feat.Pair
+	for n <= 0 || len(alns) < n {
+		maxS, maxI, maxJ := 0, 0, 0
+		for i := 1; i < r; i++ {
+			for j := 1; j < c; j++ {
+				if s := table[i*c+j][diag]; s >= maxS { // greedy so make farthest down and right
+					maxS, maxI, maxJ = s, i, j
+				}
+			}
+		}
+		if maxS == 0 || maxS < min {
+			break
+		}
+
+		var aln []feat.Pair
+		score, last, layer := 0, diag, diag
+		i, j := maxI, maxJ
+		for i > 0 && j > 0 {
+			p := i*c + j
+			v := table[p][layer]
+			if v == 0 {
+				break
+			}
+			blocked[p] = true
+			rVal, qVal := rIdx[i-1], qIdx[j-1]
+			var (
+				move      = layer
+				prev      int
+				prevLayer int
+			)
+			switch layer {
+			case diag:
+				prev = p - c - 1
+				s := v - la[rVal*let+qVal]
+				switch s {
+				case table[prev][diag]:
+					prevLayer = diag
+				case table[prev][up]:
+					prevLayer = up
+				case table[prev][left]:
+					prevLayer = left
+				default:
+					panic(fmt.Sprintf("align: sw affine internal error: no path at row: %d col:%d layer:%s\n", i, j, "mul"[layer:layer+1]))
+				}
+			case up:
+				prev = p - c
+				switch v {
+				case table[prev][up] + la[rVal*let]:
+					prevLayer = up
+				case table[prev][diag] + a.GapOpen + la[rVal*let]:
+					prevLayer = diag
+				default:
+					panic(fmt.Sprintf("align: sw affine internal error: no path at row: %d col:%d layer:%s\n", i, j, "mul"[layer:layer+1]))
+				}
+			case left:
+				prev = p - 1
+				switch v {
+				case table[prev][left] + la[qVal]:
+					prevLayer = left
+				case table[prev][diag] + a.GapOpen + la[qVal]:
+					prevLayer = diag
+				default:
+					panic(fmt.Sprintf("align: sw affine internal error: no path at row: %d col:%d layer:%s\n", i, j, "mul"[layer:layer+1]))
+				}
+			}
+			if last != move && (i != maxI || j != maxJ) {
+				aln = append(aln, &featPair{
+					a:     feature{start: i, end: maxI},
+					b:     feature{start: j, end: maxJ},
+					score: score,
+				})
+				maxI, maxJ = i, j
+				score = 0
+			}
+			score += v - table[prev][prevLayer]
+			switch move {
+			case diag:
+				i--
+				j--
+			case up:
+				i--
+			case left:
+				j--
+			}
+			last, layer = move, prevLayer
+		}
+		aln = append(aln, &featPair{
+			a:     feature{start: i, end: maxI},
+			b:     feature{start: j, end: maxJ},
+			score: score,
+		})
+		for i, j := 0, len(aln)-1; i < j; i, j = i+1, j-1 {
+			aln[i], aln[j] = aln[j], aln[i]
+		}
+		alns = append(alns, aln)
+
+		fill(i+1, aln[len(aln)-1].Features()[0].End())
+	}
+
+	return alns, nil
+}
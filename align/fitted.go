@@ -18,6 +18,12 @@ type Fitted Linear
 // Align aligns two sequences using the Needleman-Wunsch algorithm. It returns an alignment description
 // or an error if the scoring matrix is not square, or the sequence data types or alphabets do not match.
 func (a Fitted) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	return a.AlignWith(nil, reference, query)
+}
+
+// AlignWith aligns two sequences as for Align using the dynamic programming buffers held
+// by ws. If ws is nil, new buffers are allocated.
+func (a Fitted) AlignWith(ws *Workspace, reference, query AlphabetSlicer) ([]feat.Pair, error) {
 	alpha := reference.Alphabet()
 	if alpha == nil {
 		return nil, ErrNoAlphabet
@@ -34,13 +40,13 @@ func (a Fitted) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
 		if !ok {
 			return nil, ErrMismatchedTypes
 		}
-		return a.alignLetters(rSeq, qSeq, alpha)
+		return a.alignLetters(rSeq, qSeq, alpha, ws)
 	case alphabet.QLetters:
 		qSeq, ok := query.Slice().(alphabet.QLetters)
 		if !ok {
 			return nil, ErrMismatchedTypes
 		}
-		return a.alignQLetters(rSeq, qSeq, alpha)
+		return a.alignQLetters(rSeq, qSeq, alpha, ws)
 	default:
 		return nil, ErrTypeNotHandled
 	}
@@ -84,9 +84,9 @@ func pointerFittedAffineLetters(rSeq, qSeq alphabet.Letters, i, j, l int, table
 	}
 }
 
-func (a FittedAffine) alignLetters(rSeq, qSeq alphabet.Letters, alpha alphabet.Alphabet) ([]feat.Pair, error) {
+func (a FittedAffine) alignLetters(rSeq, qSeq alphabet.Letters, alpha alphabet.Alphabet, ws *Workspace) ([]feat.Pair, error) {
 	let := len(a.Matrix)
-	la := make([]int, 0, let*let)
+	la := ws.scores(let * let)
 	for _, row := range a.Matrix {
 		if len(row) != let {
 			return nil, ErrMatrixNotSquare
@@ -96,7 +96,7 @@ func (a FittedAffine) alignLetters(rSeq, qSeq alphabet.Letters, alpha alphabet.A
 
 	index := alpha.LetterIndex()
 	r, c := rSeq.Len()+1, qSeq.Len()+1
-	table := make([][3]int, r*c)
+	table := ws.affine(r * c)
 	table[0] = [3]int{
 		diag: 0,
 		up:   minInt,
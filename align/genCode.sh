@@ -125,3 +125,87 @@ cat < nw_affine_type.got \
 | gofmt -r 'rSeq[i] -> rSeq[i].L' \
 | gofmt -r 'qSeq[i] -> qSeq[i].L' \
 >> nw_affine_qletters.go
+
+echo -e $WARNING\
+> sw_quality_qletters.go
+cat < sw_type.got \
+| gofmt -r 'alignType -> alignQLetters' \
+| gofmt -r 'Type -> alphabet.QLetters' \
+| gofmt -r 'drawSWTableType -> drawSWQualityTableQLetters' \
+| gofmt -r 'pointerSWType -> pointerSWQualityQLetters' \
+| gofmt -r 'SW -> SWQuality' \
+| gofmt -r 'rSeq[i] -> rSeq[i].L' \
+| gofmt -r 'qSeq[i] -> qSeq[i].L' \
+| gofmt -r 'la[rVal*let+qVal] -> scaleQuality(la[rVal*let+qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+| gofmt -r 'a[rVal][qVal] -> scaleQuality(a[rVal][qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+>> sw_quality_qletters.go
+
+echo -e $WARNING\
+> sw_affine_quality_qletters.go
+cat < sw_affine_type.got \
+| gofmt -r 'alignType -> alignQLetters' \
+| gofmt -r 'Type -> alphabet.QLetters' \
+| gofmt -r 'drawSWAffineTableType -> drawSWAffineQualityTableQLetters' \
+| gofmt -r 'pointerSWAffineType -> pointerSWAffineQualityQLetters' \
+| gofmt -r 'SWAffine -> SWAffineQuality' \
+| gofmt -r 'rSeq[i] -> rSeq[i].L' \
+| gofmt -r 'qSeq[i] -> qSeq[i].L' \
+| gofmt -r 'la[rVal*let+qVal] -> scaleQuality(la[rVal*let+qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+| gofmt -r 'a[rVal][qVal] -> scaleQuality(a[rVal][qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+>> sw_affine_quality_qletters.go
+
+echo -e $WARNING\
+> nw_quality_qletters.go
+cat < nw_type.got \
+| gofmt -r 'alignType -> alignQLetters' \
+| gofmt -r 'Type -> alphabet.QLetters' \
+| gofmt -r 'drawNWTableType -> drawNWQualityTableQLetters' \
+| gofmt -r 'pointerNWType -> pointerNWQualityQLetters' \
+| gofmt -r 'NW -> NWQuality' \
+| gofmt -r 'rSeq[i] -> rSeq[i].L' \
+| gofmt -r 'qSeq[i] -> qSeq[i].L' \
+| gofmt -r 'la[rVal*let+qVal] -> scaleQuality(la[rVal*let+qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+| gofmt -r 'a[rVal][qVal] -> scaleQuality(a[rVal][qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+>> nw_quality_qletters.go
+
+echo -e $WARNING\
+> nw_affine_quality_qletters.go
+cat < nw_affine_type.got \
+| gofmt -r 'alignType -> alignQLetters' \
+| gofmt -r 'Type -> alphabet.QLetters' \
+| gofmt -r 'drawNWAffineTableType -> drawNWAffineQualityTableQLetters' \
+| gofmt -r 'pointerNWAffineType -> pointerNWAffineQualityQLetters' \
+| gofmt -r 'NWAffine -> NWAffineQuality' \
+| gofmt -r 'rSeq[i] -> rSeq[i].L' \
+| gofmt -r 'qSeq[i] -> qSeq[i].L' \
+| gofmt -r 'la[rVal*let+qVal] -> scaleQuality(la[rVal*let+qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+| gofmt -r 'a[rVal][qVal] -> scaleQuality(a[rVal][qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+>> nw_affine_quality_qletters.go
+
+echo -e $WARNING\
+> fitted_quality_qletters.go
+cat < fitted_type.got \
+| gofmt -r 'alignType -> alignQLetters' \
+| gofmt -r 'Type -> alphabet.QLetters' \
+| gofmt -r 'drawFittedTableType -> drawFittedQualityTableQLetters' \
+| gofmt -r 'pointerFittedType -> pointerFittedQualityQLetters' \
+| gofmt -r 'Fitted -> FittedQuality' \
+| gofmt -r 'rSeq[i] -> rSeq[i].L' \
+| gofmt -r 'qSeq[i] -> qSeq[i].L' \
+| gofmt -r 'la[rVal*let+qVal] -> scaleQuality(la[rVal*let+qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+| gofmt -r 'a[rVal][qVal] -> scaleQuality(a[rVal][qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+>> fitted_quality_qletters.go
+
+echo -e $WARNING\
+> fitted_affine_quality_qletters.go
+cat < fitted_affine_type.got \
+| gofmt -r 'alignType -> alignQLetters' \
+| gofmt -r 'Type -> alphabet.QLetters' \
+| gofmt -r 'drawFittedAffineTableType -> drawFittedAffineQualityTableQLetters' \
+| gofmt -r 'pointerFittedAffineType -> pointerFittedAffineQualityQLetters' \
+| gofmt -r 'FittedAffine -> FittedAffineQuality' \
+| gofmt -r 'rSeq[i] -> rSeq[i].L' \
+| gofmt -r 'qSeq[i] -> qSeq[i].L' \
+| gofmt -r 'la[rVal*let+qVal] -> scaleQuality(la[rVal*let+qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+| gofmt -r 'a[rVal][qVal] -> scaleQuality(a[rVal][qVal], rSeq[i-1].Q, qSeq[j-1].Q)' \
+>> fitted_affine_quality_qletters.go
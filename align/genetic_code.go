@@ -0,0 +1,63 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+
+	"errors"
+)
+
+// A GeneticCode is a codon translation table. Codons are indexed by base in TCAG order, so the
+// table is laid out in the same order as the NCBI genetic code amino acid strings.
+type GeneticCode [64]alphabet.Letter
+
+// StandardCode is the standard genetic code, NCBI translation table 1.
+var StandardCode = MustGeneticCode(NewGeneticCode("FFLLSSSSYY**CC*WLLLLPPPPHHQQRRRRIIIMTTTTNNKKSSRRVVVVAAAADDEEGGGG"))
+
+// NewGeneticCode returns a GeneticCode described by the 64 letter NCBI amino acid string aa.
+func NewGeneticCode(aa string) (*GeneticCode, error) {
+	if len(aa) != 64 {
+		return nil, errors.New("align: genetic code must have 64 codons")
+	}
+	var g GeneticCode
+	for i := range g {
+		g[i] = alphabet.Letter(aa[i])
+	}
+	return &g, nil
+}
+
+// MustGeneticCode is a helper that wraps a call to a function returning (*GeneticCode, error)
+// and panics if the error is non-nil.
+func MustGeneticCode(g *GeneticCode, err error) *GeneticCode {
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// codonBase holds a lookup for the TCAG order index of nucleotide letters.
+var codonBase = func() [256]int8 {
+	var t [256]int8
+	for i := range t {
+		t[i] = -1
+	}
+	for i, b := range "tcag" {
+		t[b] = int8(i)
+		t[b&^' '] = int8(i)
+	}
+	t['u'], t['U'] = 0, 0
+	return t
+}()
+
+// Translate returns the amino acid encoded by the codon a, b, c. If any of the letters is not
+// one of ACGTU the ambiguous amino acid letter 'X' is returned.
+func (g *GeneticCode) Translate(a, b, c alphabet.Letter) alphabet.Letter {
+	i, j, k := codonBase[a], codonBase[b], codonBase[c]
+	if i < 0 || j < 0 || k < 0 {
+		return 'X'
+	}
+	return g[int(i)<<4|int(j)<<2|int(k)]
+}
@@ -0,0 +1,146 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package matrix
+
+import (
+	"code.google.com/p/biogo/alphabet"
+
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Read reads an NCBI or EMBOSS format scoring matrix from r and returns it organised to allow
+// direct lookup using the letter indices of alpha, so the returned matrix may be used as an
+// align.Linear. Lines beginning with '#' are comments. Letters in the matrix that are not valid
+// in alpha are ignored and pairs of letters not described by the matrix are given a score of
+// zero, including the gap penalties of a gapped alphabet unless the matrix defines the gap letter.
+func Read(r io.Reader, alpha alphabet.Alphabet) ([][]int, error) {
+	if alpha == nil {
+		return nil, errors.New("matrix: no alphabet")
+	}
+	var (
+		ind  = alpha.LetterIndex()
+		cols []int
+		mat  [][]int
+		line int
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line++
+		l := strings.TrimSpace(sc.Text())
+		if len(l) == 0 || l[0] == '#' {
+			continue
+		}
+		f := strings.Fields(l)
+		if cols == nil {
+			cols = make([]int, len(f))
+			for i, c := range f {
+				if len(c) != 1 {
+					return nil, fmt.Errorf("matrix: invalid column letter %q at line %d", c, line)
+				}
+				cols[i] = ind[c[0]]
+			}
+			mat = make([][]int, alpha.Len())
+			for i := range mat {
+				mat[i] = make([]int, alpha.Len())
+			}
+			continue
+		}
+		if len(f[0]) != 1 {
+			return nil, fmt.Errorf("matrix: invalid row letter %q at line %d", f[0], line)
+		}
+		if len(f)-1 != len(cols) {
+			return nil, fmt.Errorf("matrix: row length mismatch at line %d: %d != %d", line, len(f)-1, len(cols))
+		}
+		row := ind[f[0][0]]
+		for i, v := range f[1:] {
+			s, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("matrix: invalid score at line %d: %v", line, err)
+			}
+			if row < 0 || cols[i] < 0 {
+				continue
+			}
+			mat[row][cols[i]] = s
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if mat == nil {
+		return nil, errors.New("matrix: no matrix data")
+	}
+	return mat, nil
+}
+
+// Write writes the scoring matrix m, organised for lookup using the letter indices of alpha, to
+// w in NCBI format. The gap letter of alpha is omitted if all its scores are zero.
+func Write(w io.Writer, m [][]int, alpha alphabet.Alphabet) error {
+	if alpha == nil {
+		return errors.New("matrix: no alphabet")
+	}
+	if len(m) != alpha.Len() {
+		return fmt.Errorf("matrix: matrix does not match alphabet: %d != %d", len(m), alpha.Len())
+	}
+	for _, r := range m {
+		if len(r) != len(m) {
+			return errors.New("matrix: matrix is not square")
+		}
+	}
+
+	gap := alpha.IndexOf(alpha.Gap())
+	if gap >= 0 {
+		for i := range m {
+			if m[gap][i] != 0 || m[i][gap] != 0 {
+				gap = -1
+				break
+			}
+		}
+	}
+	var (
+		idx   []int
+		width = 2
+	)
+	for i := range m {
+		if i == gap {
+			continue
+		}
+		idx = append(idx, i)
+		for _, j := range idx {
+			if l := len(strconv.Itoa(m[i][j])); l >= width {
+				width = l + 1
+			}
+			if l := len(strconv.Itoa(m[j][i])); l >= width {
+				width = l + 1
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, " ")
+	for _, i := range idx {
+		fmt.Fprintf(bw, "%*c", width, toUpper(alpha.Letter(i)))
+	}
+	fmt.Fprintln(bw)
+	for _, i := range idx {
+		fmt.Fprintf(bw, "%c", toUpper(alpha.Letter(i)))
+		for _, j := range idx {
+			fmt.Fprintf(bw, "%*d", width, m[i][j])
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+func toUpper(l alphabet.Letter) alphabet.Letter {
+	if l >= 'a' {
+		return l &^ ' '
+	}
+	return l
+}
@@ -0,0 +1,62 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package matrix
+
+import (
+	"code.google.com/p/biogo/alphabet"
+
+	"bytes"
+	check "launchpad.net/gocheck"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestRead(c *check.C) {
+	for _, t := range []struct {
+		file  string
+		alpha alphabet.Alphabet
+		mat   [][]int
+	}{
+		{"NUC.4.4", alphabet.DNAredundant, NUC_4_4},
+		{"BLOSUM62", alphabet.Protein, BLOSUM62},
+		{"PAM250", alphabet.Protein, PAM250},
+	} {
+		f, err := os.Open(filepath.Join("matrices", t.file))
+		c.Assert(err, check.Equals, nil)
+		m, err := Read(f, t.alpha)
+		f.Close()
+		c.Check(err, check.Equals, nil)
+		c.Check(m, check.DeepEquals, t.mat, check.Commentf("%s", t.file))
+	}
+}
+
+func (s *S) TestRoundTrip(c *check.C) {
+	var buf bytes.Buffer
+	c.Assert(Write(&buf, BLOSUM62, alphabet.Protein), check.Equals, nil)
+	m, err := Read(&buf, alphabet.Protein)
+	c.Check(err, check.Equals, nil)
+	c.Check(m, check.DeepEquals, BLOSUM62)
+}
+
+func (s *S) TestReadErrors(c *check.C) {
+	for _, in := range []string{
+		"",
+		"# comment only\n",
+		"   A  C\nA  1\n",
+		"   A  C\nA  1  x\n",
+	} {
+		_, err := Read(strings.NewReader(in), alphabet.DNA)
+		c.Check(err, check.NotNil, check.Commentf("%q", in))
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package msa
+
+import (
+	"code.google.com/p/biogo/align"
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq"
+
+	"errors"
+)
+
+// A DistanceFunc returns a distance between two sequences in the range [0, 1].
+type DistanceFunc func(a, b seq.Sequence) (float64, error)
+
+// lower returns the lower cased letters of s.
+func lower(s seq.Sequence) []alphabet.Letter {
+	l := make([]alphabet.Letter, 0, s.Len())
+	for i := s.Start(); i < s.End(); i++ {
+		l = append(l, s.At(i).L|('a'-'A'))
+	}
+	return l
+}
+
+// KmerDistance returns a DistanceFunc that calculates the k-mer distance of Edgar (2004),
+// one minus the fraction of k-mers shared by the two sequences. K-mers containing the
+// alphabet's gap letter are ignored.
+func KmerDistance(k int) DistanceFunc {
+	return func(a, b seq.Sequence) (float64, error) {
+		if k < 1 {
+			return 0, errors.New("msa: k-mer length less than one")
+		}
+		if a.Alphabet() != b.Alphabet() {
+			return 0, align.ErrMismatchedAlphabets
+		}
+		gap := a.Alphabet().Gap() | ('a' - 'A')
+		ca, na := kmers(lower(a), k, gap)
+		cb, nb := kmers(lower(b), k, gap)
+		n := na
+		if nb < n {
+			n = nb
+		}
+		if n == 0 {
+			return 1, nil
+		}
+		var shared int
+		for km, c := range ca {
+			if cb[km] < c {
+				c = cb[km]
+			}
+			shared += c
+		}
+		return 1 - float64(shared)/float64(n), nil
+	}
+}
+
+// kmers returns the counts of k-mers in l not containing gap and their total.
+func kmers(l []alphabet.Letter, k int, gap alphabet.Letter) (map[string]int, int) {
+	c := make(map[string]int)
+	var n int
+	for i := 0; i+k <= len(l); i++ {
+		km := l[i : i+k]
+		ok := true
+		for _, b := range km {
+			if b == gap {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			c[alphabet.Letters(km).String()]++
+			n++
+		}
+	}
+	return c, n
+}
+
+// AlignmentDistance returns a DistanceFunc that calculates one minus the fractional identity
+// of the aligned positions of a global affine alignment of the two sequences.
+func AlignmentDistance(a align.Affine) DistanceFunc {
+	return func(x, y seq.Sequence) (float64, error) {
+		aln, err := align.NWAffine(a).Align(x, y)
+		if err != nil {
+			return 0, err
+		}
+		lx, ly := lower(x), lower(y)
+		var id, n int
+		for _, fp := range aln {
+			f := fp.Features()
+			if f[0].Len() == 0 || f[1].Len() == 0 {
+				continue
+			}
+			for k := 0; k < f[0].Len(); k++ {
+				if lx[f[0].Start()+k] == ly[f[1].Start()+k] {
+					id++
+				}
+				n++
+			}
+		}
+		if n == 0 {
+			return 1, nil
+		}
+		return 1 - float64(id)/float64(n), nil
+	}
+}
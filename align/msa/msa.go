@@ -0,0 +1,203 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package msa implements progressive multiple sequence alignment in the manner of ClustalW
+// and MUSCLE.
+//
+// Pairwise distances between the input sequences are used to construct a guide tree, and
+// the sequences are then aligned as profiles in the order given by the tree, with optional
+// iterative refinement of the resulting alignment.
+package msa
+
+import (
+	"code.google.com/p/biogo/align"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/multi"
+
+	"errors"
+)
+
+// DefaultKmer is the k-mer length used for distance calculation when a Builder has a nil
+// Distance field.
+const DefaultKmer = 3
+
+// A Builder constructs progressive multiple sequence alignments.
+//
+// Aligner is used for all profile alignments. If Distance is nil, KmerDistance(DefaultKmer)
+// is used and if Tree is nil, UPGMA is used. Refine is the maximum number of iterative
+// refinement passes made over the progressive alignment; in each pass each sequence is
+// removed from the alignment and realigned to the remaining profile, with the new alignment
+// retained if it improves the sum of pairs score.
+type Builder struct {
+	Aligner  align.ProfileAffine
+	Distance DistanceFunc
+	Tree     TreeFunc
+	Refine   int
+}
+
+// Distances returns the matrix of pairwise distances between the sequences in s.
+func (b Builder) Distances(s []seq.Sequence) ([][]float64, error) {
+	dist := b.Distance
+	if dist == nil {
+		dist = KmerDistance(DefaultKmer)
+	}
+	d := make([][]float64, len(s))
+	for i := range d {
+		d[i] = make([]float64, len(s))
+	}
+	for i := range s {
+		for j := i + 1; j < len(s); j++ {
+			v, err := dist(s[i], s[j])
+			if err != nil {
+				return nil, err
+			}
+			d[i][j], d[j][i] = v, v
+		}
+	}
+	return d, nil
+}
+
+// GuideTree returns the guide tree for the sequences in s.
+func (b Builder) GuideTree(s []seq.Sequence) (*Node, error) {
+	d, err := b.Distances(s)
+	if err != nil {
+		return nil, err
+	}
+	tree := b.Tree
+	if tree == nil {
+		tree = UPGMA
+	}
+	return tree(d), nil
+}
+
+// Align returns a multiple sequence alignment of the sequences in s with the given id. The
+// rows of the returned alignment are in the same order as s and the alignment's consensus
+// is calculated by seq.DefaultConsensus.
+func (b Builder) Align(id string, s []seq.Sequence) (*multi.Multi, error) {
+	if len(s) == 0 {
+		return nil, errors.New("msa: no sequences")
+	}
+	t, err := b.GuideTree(s)
+	if err != nil {
+		return nil, err
+	}
+	p, err := b.Progressive(t, s)
+	if err != nil {
+		return nil, err
+	}
+	p, err = b.refine(p)
+	if err != nil {
+		return nil, err
+	}
+	return p.Multi(id, seq.DefaultConsensus)
+}
+
+// Progressive returns a profile of the alignment of the sequences in s following the guide
+// tree t. The rows of the returned profile are in the same order as s.
+func (b Builder) Progressive(t *Node, s []seq.Sequence) (*align.Profile, error) {
+	p, order, err := b.progressive(t, s)
+	if err != nil {
+		return nil, err
+	}
+	perm := make([]int, len(order))
+	for i, o := range order {
+		perm[o] = i
+	}
+	return p.Subset(perm...), nil
+}
+
+// progressive returns the profile of the alignment of the leaves below n and the indices
+// into s of the profile's rows.
+func (b Builder) progressive(n *Node, s []seq.Sequence) (*align.Profile, []int, error) {
+	if n.IsLeaf() {
+		if n.Seq < 0 || n.Seq >= len(s) {
+			return nil, nil, errors.New("msa: guide tree leaf out of range")
+		}
+		p, err := align.NewSequenceProfile(s[n.Seq])
+		return p, []int{n.Seq}, err
+	}
+	l, lo, err := b.progressive(n.Left, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, ro, err := b.progressive(n.Right, s)
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := b.Aligner.Merge(l, r)
+	return p, append(lo, ro...), err
+}
+
+// refine performs up to b.Refine leave-one-out refinement passes over p.
+func (b Builder) refine(p *align.Profile) (*align.Profile, error) {
+	n := p.Rows()
+	if n < 3 {
+		return p, nil
+	}
+	best := SumOfPairs(p, b.Aligner.Matrix)
+	for pass := 0; pass < b.Refine; pass++ {
+		improved := false
+		for i := 0; i < n; i++ {
+			rest := make([]int, 0, n-1)
+			for j := 0; j < n; j++ {
+				if j != i {
+					rest = append(rest, j)
+				}
+			}
+			q, err := b.Aligner.Merge(p.Subset(rest...), p.Subset(i))
+			if err != nil {
+				return nil, err
+			}
+			// Restore the original row order; the removed row is last in q.
+			perm := make([]int, n)
+			for j := range perm {
+				switch {
+				case j < i:
+					perm[j] = j
+				case j == i:
+					perm[j] = n - 1
+				default:
+					perm[j] = j - 1
+				}
+			}
+			q = q.Subset(perm...)
+			if s := SumOfPairs(q, b.Aligner.Matrix); s > best {
+				p, best = q, s
+				improved = true
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return p, nil
+}
+
+// SumOfPairs returns the sum of pairs score of the alignment described by p using the
+// scoring matrix m. Gap letters are scored using the gap penalties of m and gap-gap pairs
+// score zero.
+func SumOfPairs(p *align.Profile, m align.Linear) float64 {
+	n := float64(p.Rows())
+	var score float64
+	for col := 0; col < p.Len(); col++ {
+		f := p.Freqs(col)
+		for a, fa := range f {
+			if fa == 0 || a >= len(m) {
+				continue
+			}
+			ca := fa * n
+			for b := a; b < len(f) && b < len(m[a]); b++ {
+				if f[b] == 0 {
+					continue
+				}
+				if a == b {
+					score += ca * (ca - 1) / 2 * float64(m[a][a])
+				} else {
+					score += ca * f[b] * n * float64(m[a][b])
+				}
+			}
+		}
+	}
+	return score
+}
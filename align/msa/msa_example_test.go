@@ -0,0 +1,73 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package msa
+
+import (
+	"code.google.com/p/biogo/align"
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+)
+
+func ExampleBuilder_Align() {
+	var s []seq.Sequence
+	for _, r := range []struct{ id, seq string }{
+		{"a", "ACGTTGCAACGTAGCT"},
+		{"b", "ACGTAGCAACGTAGCT"},
+		{"c", "ACGTTCAGGACGTAGCT"},
+		{"d", "ACTTGCAACGTTAGCT"},
+	} {
+		s = append(s, linear.NewSeq(r.id, alphabet.BytesToLetters([]byte(r.seq)), alphabet.DNAgapped))
+	}
+
+	// w(gap) = -5
+	// w(match) = +4
+	// w(mismatch) = -5
+	//
+	// w(open) = -5
+	b := Builder{
+		Aligner: align.ProfileAffine{
+			Matrix: align.Linear{
+				{0, -5, -5, -5, -5},
+				{-5, 4, -5, -5, -5},
+				{-5, -5, 4, -5, -5},
+				{-5, -5, -5, 4, -5},
+				{-5, -5, -5, -5, 4},
+			},
+			GapOpen: -5,
+		},
+		Tree:   NeighborJoining,
+		Refine: 2,
+	}
+
+	t, err := b.GuideTree(s)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%v\n\n", t.Leaves())
+
+	m, err := b.Align("example", s)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, r := range m.Seq {
+		fmt.Printf("%-s\n", r)
+	}
+	fmt.Printf("\n%-s\n", m.Consensus(false))
+
+	// Output:
+	// [0 3 1 2]
+	//
+	// ACGTTGCA--ACG-TAGCT
+	// ACGTAGCA--ACG-TAGCT
+	// ACGTT-CAGGACG-TAGCT
+	// AC-TTGCA--ACGTTAGCT
+	//
+	// acgttgca--acg-tagct
+}
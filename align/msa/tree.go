@@ -0,0 +1,172 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package msa
+
+import (
+	"fmt"
+	"math"
+)
+
+// A Node is a node of a binary guide tree. Leaf nodes have nil Left and Right fields and
+// hold the index of the sequence they represent in Seq. Length is the length of the branch
+// leading to the node.
+type Node struct {
+	Left, Right *Node
+	Seq         int
+	Length      float64
+}
+
+// IsLeaf returns whether the node is a leaf.
+func (n *Node) IsLeaf() bool { return n.Left == nil && n.Right == nil }
+
+// Leaves returns the sequence indices of the leaves below n in left to right order.
+func (n *Node) Leaves() []int {
+	if n.IsLeaf() {
+		return []int{n.Seq}
+	}
+	return append(n.Left.Leaves(), n.Right.Leaves()...)
+}
+
+// String returns a Newick representation of the tree rooted at n, without the terminating
+// semicolon.
+func (n *Node) String() string {
+	if n.IsLeaf() {
+		return fmt.Sprintf("%d:%.3g", n.Seq, n.Length)
+	}
+	return fmt.Sprintf("(%v,%v):%.3g", n.Left, n.Right, n.Length)
+}
+
+// A TreeFunc constructs a guide tree from a symmetric distance matrix.
+type TreeFunc func(d [][]float64) *Node
+
+var (
+	_ TreeFunc = UPGMA
+	_ TreeFunc = NeighborJoining
+)
+
+// UPGMA returns a rooted guide tree constructed from the symmetric distance matrix d using
+// the unweighted pair group method with arithmetic mean.
+func UPGMA(d [][]float64) *Node {
+	n := len(d)
+	if n == 0 {
+		return nil
+	}
+	dist := copyMatrix(d)
+	nodes := make([]*Node, n)
+	size := make([]int, n)
+	height := make([]float64, n)
+	for i := range nodes {
+		nodes[i] = &Node{Seq: i}
+		size[i] = 1
+	}
+	for active := n; active > 1; active-- {
+		bi, bj := closest(dist, nodes, func(i, j int) float64 { return dist[i][j] })
+		h := dist[bi][bj] / 2
+		nodes[bi].Length = h - height[bi]
+		nodes[bj].Length = h - height[bj]
+		for k := range nodes {
+			if nodes[k] == nil || k == bi || k == bj {
+				continue
+			}
+			dk := (dist[bi][k]*float64(size[bi]) + dist[bj][k]*float64(size[bj])) / float64(size[bi]+size[bj])
+			dist[bi][k], dist[k][bi] = dk, dk
+		}
+		nodes[bi] = &Node{Left: nodes[bi], Right: nodes[bj], Seq: -1}
+		size[bi] += size[bj]
+		height[bi] = h
+		nodes[bj] = nil
+	}
+	for _, nd := range nodes {
+		if nd != nil {
+			return nd
+		}
+	}
+	panic("msa: no root")
+}
+
+// NeighborJoining returns a guide tree constructed from the symmetric distance matrix d
+// using the neighbour-joining method of Saitou and Nei (1987). The returned tree is rooted
+// at the final join.
+func NeighborJoining(d [][]float64) *Node {
+	n := len(d)
+	if n == 0 {
+		return nil
+	}
+	dist := copyMatrix(d)
+	nodes := make([]*Node, n)
+	for i := range nodes {
+		nodes[i] = &Node{Seq: i}
+	}
+	r := make([]float64, n)
+	for active := n; active > 2; active-- {
+		for i := range nodes {
+			r[i] = 0
+			if nodes[i] == nil {
+				continue
+			}
+			for j := range nodes {
+				if nodes[j] != nil && i != j {
+					r[i] += dist[i][j]
+				}
+			}
+		}
+		m := float64(active - 2)
+		bi, bj := closest(dist, nodes, func(i, j int) float64 {
+			return m*dist[i][j] - r[i] - r[j]
+		})
+		li := dist[bi][bj]/2 + (r[bi]-r[bj])/(2*m)
+		nodes[bi].Length = math.Max(li, 0)
+		nodes[bj].Length = math.Max(dist[bi][bj]-li, 0)
+		for k := range nodes {
+			if nodes[k] == nil || k == bi || k == bj {
+				continue
+			}
+			dk := (dist[bi][k] + dist[bj][k] - dist[bi][bj]) / 2
+			dist[bi][k], dist[k][bi] = dk, dk
+		}
+		nodes[bi] = &Node{Left: nodes[bi], Right: nodes[bj], Seq: -1}
+		nodes[bj] = nil
+	}
+	var last []int
+	for i, nd := range nodes {
+		if nd != nil {
+			last = append(last, i)
+		}
+	}
+	if len(last) == 1 {
+		return nodes[last[0]]
+	}
+	l := dist[last[0]][last[1]] / 2
+	nodes[last[0]].Length, nodes[last[1]].Length = l, l
+	return &Node{Left: nodes[last[0]], Right: nodes[last[1]], Seq: -1}
+}
+
+// closest returns the pair of active nodes with the lowest value of the criterion f.
+func closest(d [][]float64, nodes []*Node, f func(i, j int) float64) (bi, bj int) {
+	best := math.Inf(1)
+	bi, bj = -1, -1
+	for i := range d {
+		if nodes[i] == nil {
+			continue
+		}
+		for j := i + 1; j < len(d); j++ {
+			if nodes[j] == nil {
+				continue
+			}
+			if v := f(i, j); v < best || bi < 0 {
+				best, bi, bj = v, i, j
+			}
+		}
+	}
+	return bi, bj
+}
+
+func copyMatrix(d [][]float64) [][]float64 {
+	c := make([][]float64, len(d))
+	for i, r := range d {
+		c[i] = append([]float64(nil), r...)
+	}
+	return c
+}
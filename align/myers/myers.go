@@ -0,0 +1,215 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package myers implements Myers' bit-parallel edit distance algorithm for global edit distance
+// calculation and approximate pattern search.
+//
+// The algorithm is described in Myers (1999) "A fast bit-vector algorithm for approximate
+// string matching based on dynamic programming" J ACM 46:395-415, with the formulation of
+// Hyyrö (2001).
+package myers
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/seq/linear"
+
+	"errors"
+	"fmt"
+)
+
+// MaxLen is the maximum length of a Pattern.
+const MaxLen = 64
+
+var (
+	ErrNoAlphabet     = errors.New("myers: no alphabet")
+	ErrEmptyPattern   = errors.New("myers: empty pattern")
+	ErrPatternTooLong = errors.New("myers: pattern too long")
+)
+
+// A Pattern is a preprocessed search pattern.
+type Pattern struct {
+	m     int
+	high  uint64
+	peq   [256]uint64
+	rpeq  [256]uint64
+	alpha alphabet.Alphabet
+}
+
+// NewPattern returns a Pattern for the letters p in the alphabet alpha. Letters are matched
+// by their index in alpha's LetterIndex, so matching is case insensitive for case insensitive
+// alphabets, and letters that are not valid in alpha do not match any letter. If alpha is
+// one of the redundant nucleic acid alphabets, alphabet.DNAredundant or alphabet.RNAredundant,
+// IUPAC ambiguity codes in either the pattern or the text match any of the bases they represent.
+// An error is returned if alpha is nil or p is empty or longer than MaxLen.
+func NewPattern(p alphabet.Letters, alpha alphabet.Alphabet) (*Pattern, error) {
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if len(p) == 0 {
+		return nil, ErrEmptyPattern
+	}
+	if len(p) > MaxLen {
+		return nil, ErrPatternTooLong
+	}
+
+	// In the redundant alphabets the index of a letter is the set of
+	// bases it represents, with a bit for each of a, c, g and t/u.
+	redundant := alpha == alphabet.DNAredundant || alpha == alphabet.RNAredundant
+	match := func(a, b int) bool {
+		if a < 0 || b < 0 {
+			return false
+		}
+		if redundant {
+			return a&b != 0
+		}
+		return a == b
+	}
+
+	pat := &Pattern{m: len(p), high: 1 << uint(len(p)-1), alpha: alpha}
+	index := alpha.LetterIndex()
+	for l := range pat.peq {
+		t := index[l]
+		for i, pl := range p {
+			if match(index[pl], t) {
+				pat.peq[l] |= 1 << uint(i)
+				pat.rpeq[l] |= 1 << uint(len(p)-1-i)
+			}
+		}
+	}
+
+	return pat, nil
+}
+
+// Len returns the length of the pattern.
+func (p *Pattern) Len() int { return p.m }
+
+// Alphabet returns the alphabet of the pattern.
+func (p *Pattern) Alphabet() alphabet.Alphabet { return p.alpha }
+
+// step advances the column state pv, mv by one text letter with match vector eq, returning
+// the new state and the change in score of the last pattern row. If global is true the
+// first row of the dynamic programming matrix increases by one for each text letter,
+// otherwise it is zero.
+func step(pv, mv, eq, high uint64, global bool) (uint64, uint64, int) {
+	xv := eq | mv
+	xh := (((eq & pv) + pv) ^ pv) | eq
+	ph := mv | ^(xh | pv)
+	mh := pv & xh
+
+	var d int
+	if ph&high != 0 {
+		d = 1
+	} else if mh&high != 0 {
+		d = -1
+	}
+
+	ph <<= 1
+	mh <<= 1
+	if global {
+		ph |= 1
+	}
+	return mh | ^(xv | ph), ph & xv, d
+}
+
+// Distance returns the Levenshtein edit distance between the pattern and t.
+func (p *Pattern) Distance(t alphabet.Letters) int {
+	pv, mv, score := ^uint64(0), uint64(0), p.m
+	for _, l := range t {
+		var d int
+		pv, mv, d = step(pv, mv, p.peq[l], p.high, true)
+		score += d
+	}
+	return score
+}
+
+// A Match is an approximate match of a pattern in a text.
+type Match struct {
+	From, To int
+	Distance int
+	Loc      feat.Feature
+}
+
+func (m *Match) Start() int             { return m.From }
+func (m *Match) End() int               { return m.To }
+func (m *Match) Len() int               { return m.To - m.From }
+func (m *Match) Location() feat.Feature { return m.Loc }
+func (m *Match) Description() string    { return "myers match" }
+func (m *Match) Name() string {
+	if m.Loc != nil {
+		return m.Loc.Name()
+	}
+	return ""
+}
+func (m *Match) String() string { return fmt.Sprintf("[%d,%d)=%d", m.From, m.To, m.Distance) }
+
+// Find returns all occurrences of the pattern in t with at most k errors. For each run of
+// consecutive end positions with at most k errors, the end position with the fewest errors
+// is reported, and the start of the match is the start of the shortest alignment of the
+// pattern ending there with that number of errors. The returned features are *Match values
+// with a nil Loc; FindIn returns matches located on a sequence.
+func (p *Pattern) Find(t alphabet.Letters, k int) []feat.Feature {
+	return p.find(t, k, nil)
+}
+
+// FindIn returns all occurrences of the pattern in the letters of s with at most k errors, as
+// described for Find. The Loc of each returned *Match is s.
+func (p *Pattern) FindIn(s *linear.Seq, k int) []feat.Feature {
+	return p.find(s.Seq, k, s)
+}
+
+func (p *Pattern) find(t alphabet.Letters, k int, loc feat.Feature) []feat.Feature {
+	var (
+		hits []feat.Feature
+
+		pv, mv, score = ^uint64(0), uint64(0), p.m
+
+		in      bool
+		best    int
+		bestEnd int
+	)
+	report := func() {
+		hits = append(hits, &Match{
+			From:     p.start(t, bestEnd, best),
+			To:       bestEnd,
+			Distance: best,
+			Loc:      loc,
+		})
+	}
+	for j, l := range t {
+		var d int
+		pv, mv, d = step(pv, mv, p.peq[l], p.high, false)
+		score += d
+		switch {
+		case score <= k && (!in || score < best):
+			in, best, bestEnd = true, score, j+1
+		case score > k && in:
+			report()
+			in = false
+		}
+	}
+	if in {
+		report()
+	}
+
+	return hits
+}
+
+// start returns the start position of the shortest alignment of the pattern to t ending at
+// end with dist errors. It aligns the reversed pattern to the text preceding end.
+func (p *Pattern) start(t alphabet.Letters, end, dist int) int {
+	if dist >= p.m {
+		return end
+	}
+	pv, mv, score := ^uint64(0), uint64(0), p.m
+	for j := end - 1; j >= 0 && j >= end-p.m-dist; j-- {
+		var d int
+		pv, mv, d = step(pv, mv, p.rpeq[t[j]], p.high, true)
+		score += d
+		if score <= dist {
+			return j
+		}
+	}
+	panic("myers: internal error: no match start")
+}
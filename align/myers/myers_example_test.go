@@ -0,0 +1,41 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package myers
+
+import (
+	"code.google.com/p/biogo/alphabet"
+
+	"fmt"
+)
+
+func ExamplePattern_Distance() {
+	p, err := NewPattern(alphabet.Letters("GATTACA"), alphabet.DNA)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(p.Distance(alphabet.Letters("GCATGCT")))
+
+	// Output:
+	// 4
+}
+
+func ExamplePattern_Find() {
+	// An adapter with an ambiguous base.
+	p, err := NewPattern(alphabet.Letters("AGATCGGAAGN"), alphabet.DNAredundant)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	read := alphabet.Letters("TTGCAGGACTAGATCGAAGCCTTACGGATCGGAAGTCAGATCGGAAGA")
+	for _, m := range p.Find(read, 1) {
+		fmt.Println(m, read[m.Start():m.End()])
+	}
+
+	// Output:
+	// [10,20)=1 AGATCGAAGC
+	// [26,36)=1 GATCGGAAGT
+	// [37,48)=0 AGATCGGAAGA
+}
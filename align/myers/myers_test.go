@@ -0,0 +1,117 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package myers
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"testing"
+)
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+// levenshtein returns the edit distance between a and b and the minimum semi-global
+// distance of a against b ending at each position of b.
+func levenshtein(a, b []byte) (int, []int) {
+	global := make([]int, len(b)+1)
+	semi := make([]int, len(b)+1)
+	for j := range global {
+		global[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		pg, ps := global[0], semi[0]
+		global[0], semi[0] = i, i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			global[j], pg = min3(pg+cost, global[j]+1, global[j-1]+1), global[j]
+			semi[j], ps = min3(ps+cost, semi[j]+1, semi[j-1]+1), semi[j]
+		}
+	}
+	return global[len(b)], semi
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+func randSeq(n int, r *rand.Rand) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = "acgt"[r.Intn(4)]
+	}
+	return b
+}
+
+func (s *S) TestDistance(c *check.C) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		a, b := randSeq(1+r.Intn(MaxLen), r), randSeq(r.Intn(100), r)
+		p, err := NewPattern(alphabet.BytesToLetters(a), alphabet.DNA)
+		c.Assert(err, check.Equals, nil)
+		want, _ := levenshtein(a, b)
+		c.Check(p.Distance(alphabet.BytesToLetters(b)), check.Equals, want, check.Commentf("%s %s", a, b))
+	}
+}
+
+func (s *S) TestFind(c *check.C) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		a, b := randSeq(1+r.Intn(MaxLen), r), randSeq(r.Intn(200), r)
+		k := r.Intn(len(a) + 1)
+		p, err := NewPattern(alphabet.BytesToLetters(a), alphabet.DNA)
+		c.Assert(err, check.Equals, nil)
+		_, semi := levenshtein(a, b)
+		for _, f := range p.Find(alphabet.BytesToLetters(b), k) {
+			m := f.(*Match)
+			c.Check(m.Distance, check.Equals, semi[m.To])
+			c.Check(m.Distance <= k, check.Equals, true)
+			d, _ := levenshtein(a, b[m.From:m.To])
+			c.Check(d, check.Equals, m.Distance, check.Commentf("%s %s %v", a, b, m))
+		}
+	}
+}
+
+func (s *S) TestFindIn(c *check.C) {
+	p, err := NewPattern(alphabet.Letters("gattaca"), alphabet.DNA)
+	c.Assert(err, check.Equals, nil)
+	read := linear.NewSeq("read", alphabet.Letters("ccgattacattgatacagg"), alphabet.DNA)
+	want := p.Find(read.Seq, 1)
+	got := p.FindIn(read, 1)
+	c.Assert(len(got), check.Equals, 2)
+	c.Assert(len(got), check.Equals, len(want))
+	for i, f := range got {
+		c.Check(f.Location(), check.Equals, read)
+		c.Check(f.Name(), check.Equals, "read")
+		c.Check(f.Start(), check.Equals, want[i].Start())
+		c.Check(f.End(), check.Equals, want[i].End())
+		c.Check(want[i].Location(), check.Equals, nil)
+	}
+}
+
+func (s *S) TestPatternErrors(c *check.C) {
+	_, err := NewPattern(nil, alphabet.DNA)
+	c.Check(err, check.Equals, ErrEmptyPattern)
+	_, err = NewPattern(make(alphabet.Letters, MaxLen+1), alphabet.DNA)
+	c.Check(err, check.Equals, ErrPatternTooLong)
+	_, err = NewPattern(alphabet.Letters("acgt"), nil)
+	c.Check(err, check.Equals, ErrNoAlphabet)
+}
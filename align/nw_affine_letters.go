@@ -95,9 +95,9 @@ func pointerNWAffineLetters(rSeq, qSeq alphabet.Letters, i, j, l int, table [][3
 	}
 }
 
-func (a NWAffine) alignLetters(rSeq, qSeq alphabet.Letters, alpha alphabet.Alphabet) ([]feat.Pair, error) {
+func (a NWAffine) alignLetters(rSeq, qSeq alphabet.Letters, alpha alphabet.Alphabet, ws *Workspace) ([]feat.Pair, error) {
 	let := len(a.Matrix)
-	la := make([]int, 0, let*let)
+	la := ws.scores(let * let)
 	for _, row := range a.Matrix {
 		if len(row) != let {
 			return nil, ErrMatrixNotSquare
@@ -107,7 +107,7 @@ func (a NWAffine) alignLetters(rSeq, qSeq alphabet.Letters, alpha alphabet.Alpha
 
 	index := alpha.LetterIndex()
 	r, c := rSeq.Len()+1, qSeq.Len()+1
-	table := make([][3]int, r*c)
+	table := ws.affine(r * c)
 	table[0] = [3]int{
 		diag: 0,
 		up:   minInt,
@@ -0,0 +1,299 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pairhmm implements a pair hidden Markov model for calculating the likelihood of
+// sequencing reads given candidate haplotypes, in the manner of the GATK HaplotypeCaller.
+//
+// The model has match, insertion and deletion states. Match state emission probabilities are
+// derived from the Phred base qualities of the read, and transitions between states are given
+// by gap open and gap extension probabilities. Reads are aligned globally and haplotypes locally,
+// with a uniform prior over the haplotype start position.
+package pairhmm
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/seq/linear"
+
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrNoAlphabet      = errors.New("pairhmm: no alphabet")
+	ErrEmptySequence   = errors.New("pairhmm: empty sequence")
+	ErrBadProbability  = errors.New("pairhmm: transition probability out of range")
+	ErrMismatchedTypes = errors.New("pairhmm: mismatched molecule types")
+)
+
+const (
+	match = iota
+	insertion
+	deletion
+)
+
+// A PairHMM is a pair hidden Markov model. GapOpen is the probability of a transition
+// from the match state to each of the insertion and deletion states and GapExtend is the
+// probability of remaining in an insertion or deletion state.
+type PairHMM struct {
+	GapOpen   float64
+	GapExtend float64
+}
+
+// model holds the log transition probabilities of a PairHMM.
+type model struct {
+	mm, mg, gm, gg float64
+}
+
+func (h PairHMM) model() (model, error) {
+	if h.GapOpen <= 0 || 2*h.GapOpen >= 1 || h.GapExtend < 0 || h.GapExtend >= 1 {
+		return model{}, ErrBadProbability
+	}
+	return model{
+		mm: math.Log(1 - 2*h.GapOpen),
+		mg: math.Log(h.GapOpen),
+		gm: math.Log(1 - h.GapExtend),
+		gg: math.Log(h.GapExtend),
+	}, nil
+}
+
+// emissions returns a function returning the log probability of the match state emitting the
+// ith read letter and jth haplotype letter.
+func emissions(read *linear.QSeq, hap *linear.Seq) (func(i, j int) float64, error) {
+	if read.Alpha == nil || hap.Alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if read.Alpha.Moltype() != hap.Alpha.Moltype() {
+		return nil, ErrMismatchedTypes
+	}
+	if len(read.Seq) == 0 || len(hap.Seq) == 0 {
+		return nil, ErrEmptySequence
+	}
+	var (
+		rAmb = read.Alpha.Ambiguous() | ' '
+		hAmb = hap.Alpha.Ambiguous() | ' '
+		logE [256]float64
+		logM [256]float64
+	)
+	for q := range logE {
+		e := alphabet.Qphred(q).ProbE()
+		if math.IsNaN(e) || e > 0.75 {
+			e = 0.75
+		}
+		logM[q] = math.Log(1 - e)
+		logE[q] = math.Log(e / 3)
+	}
+	return func(i, j int) float64 {
+		r, h := read.Seq[i], hap.Seq[j]
+		rl, hl := r.L|' ', h|' '
+		if rl == hl || rl == rAmb || hl == hAmb {
+			return logM[r.Q]
+		}
+		return logE[r.Q]
+	}, nil
+}
+
+// logSum returns log(exp(a) + exp(b)).
+func logSum(a, b float64) float64 {
+	if math.IsInf(a, -1) {
+		return b
+	}
+	if math.IsInf(b, -1) {
+		return a
+	}
+	if a < b {
+		a, b = b, a
+	}
+	return a + math.Log1p(math.Exp(b-a))
+}
+
+// Forward returns the natural log of the probability of read given the haplotype hap,
+// calculated by the forward algorithm in log space.
+func (h PairHMM) Forward(read *linear.QSeq, hap *linear.Seq) (float64, error) {
+	m, err := h.model()
+	if err != nil {
+		return 0, err
+	}
+	emit, err := emissions(read, hap)
+	if err != nil {
+		return 0, err
+	}
+
+	r, c := len(read.Seq)+1, len(hap.Seq)+1
+	inf := math.Inf(-1)
+	prev, cur := make([][3]float64, c), make([][3]float64, c)
+	start := -math.Log(float64(len(hap.Seq)))
+	for j := range prev {
+		prev[j] = [3]float64{match: inf, insertion: inf, deletion: start}
+	}
+	for i := 1; i < r; i++ {
+		cur[0] = [3]float64{inf, inf, inf}
+		for j := 1; j < c; j++ {
+			d, u, l := prev[j-1], prev[j], cur[j-1]
+			cur[j] = [3]float64{
+				match: emit(i-1, j-1) + logSum(d[match]+m.mm,
+					logSum(d[insertion]+m.gm, d[deletion]+m.gm)),
+				insertion: logSum(u[match]+m.mg, u[insertion]+m.gg),
+				deletion:  logSum(l[match]+m.mg, l[deletion]+m.gg),
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	p := inf
+	for j := 1; j < c; j++ {
+		p = logSum(p, logSum(prev[j][match], prev[j][insertion]))
+	}
+	return p, nil
+}
+
+type feature struct {
+	start, end int
+	loc        feat.Feature
+}
+
+func (f feature) Name() string {
+	if f.loc != nil {
+		return f.loc.Name()
+	}
+	return ""
+}
+func (f feature) Description() string {
+	if f.loc != nil {
+		return f.loc.Description()
+	}
+	return ""
+}
+func (f feature) Location() feat.Feature { return f.loc }
+func (f feature) Start() int             { return f.start }
+func (f feature) End() int               { return f.end }
+func (f feature) Len() int               { return f.end - f.start }
+
+// A Segment is a segment of a Viterbi path through a PairHMM, describing the haplotype and
+// read positions emitted by a run of a single state.
+type Segment struct {
+	hap, read feature
+	logProb   float64
+}
+
+// Features returns the haplotype and read features of the segment.
+func (s *Segment) Features() [2]feat.Feature { return [2]feat.Feature{s.hap, s.read} }
+
+// LogProb returns the natural log probability contributed by the segment to the path.
+func (s *Segment) LogProb() float64 { return s.logProb }
+
+func (s *Segment) String() string {
+	switch {
+	case s.hap.start == s.hap.end:
+		return fmt.Sprintf("-/%s[%d,%d)=%.3g", s.read.Name(), s.read.start, s.read.end, s.logProb)
+	case s.read.start == s.read.end:
+		return fmt.Sprintf("%s[%d,%d)/-=%.3g", s.hap.Name(), s.hap.start, s.hap.end, s.logProb)
+	}
+	return fmt.Sprintf("%s[%d,%d)/%s[%d,%d)=%.3g",
+		s.hap.Name(), s.hap.start, s.hap.end,
+		s.read.Name(), s.read.start, s.read.end,
+		s.logProb)
+}
+
+// Viterbi returns the most probable path of read through the model given the haplotype hap
+// as a slice of *Segment, and the natural log probability of the path.
+func (h PairHMM) Viterbi(read *linear.QSeq, hap *linear.Seq) ([]feat.Pair, float64, error) {
+	m, err := h.model()
+	if err != nil {
+		return nil, 0, err
+	}
+	emit, err := emissions(read, hap)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r, c := len(read.Seq)+1, len(hap.Seq)+1
+	inf := math.Inf(-1)
+	table := make([][3]float64, r*c)
+	trace := make([][3]byte, r*c)
+	start := -math.Log(float64(len(hap.Seq)))
+	for j := 0; j < c; j++ {
+		table[j] = [3]float64{match: inf, insertion: inf, deletion: start}
+	}
+	best3 := func(v [3]float64, t [3]float64) (float64, byte) {
+		b, s := v[0]+t[0], byte(0)
+		for k := 1; k < 3; k++ {
+			if x := v[k] + t[k]; x > b {
+				b, s = x, byte(k)
+			}
+		}
+		return b, s
+	}
+	for i := 1; i < r; i++ {
+		table[i*c] = [3]float64{inf, inf, inf}
+		for j := 1; j < c; j++ {
+			p := i*c + j
+			var v float64
+			v, trace[p][match] = best3(table[p-c-1], [3]float64{m.mm, m.gm, m.gm})
+			table[p][match] = v + emit(i-1, j-1)
+			table[p][insertion], trace[p][insertion] = best3(table[p-c], [3]float64{m.mg, m.gg, inf})
+			table[p][deletion], trace[p][deletion] = best3(table[p-1], [3]float64{m.mg, inf, m.gg})
+		}
+	}
+
+	bestP, bestJ, state := inf, 0, match
+	for j := 1; j < c; j++ {
+		for _, s := range []int{match, insertion} {
+			if v := table[(r-1)*c+j][s]; v > bestP {
+				bestP, bestJ, state = v, j, s
+			}
+		}
+	}
+	if math.IsInf(bestP, -1) {
+		return nil, bestP, errors.New("pairhmm: no path")
+	}
+
+	var (
+		path       []feat.Pair
+		i, j       = r - 1, bestJ
+		endI, endJ = i, j
+		last       = state
+		segP       float64
+	)
+	for i > 0 {
+		p := i*c + j
+		if state != last {
+			path = append(path, &Segment{
+				hap:     feature{start: j, end: endJ, loc: hap},
+				read:    feature{start: i, end: endI, loc: read},
+				logProb: segP,
+			})
+			endI, endJ = i, j
+			segP = 0
+		}
+		next := int(trace[p][state])
+		var prev int
+		switch state {
+		case match:
+			prev = p - c - 1
+			i--
+			j--
+		case insertion:
+			prev = p - c
+			i--
+		case deletion:
+			prev = p - 1
+			j--
+		}
+		segP += table[p][state] - table[prev][next]
+		last, state = state, next
+	}
+	path = append(path, &Segment{
+		hap:     feature{start: j, end: endJ, loc: hap},
+		read:    feature{start: i, end: endI, loc: read},
+		logProb: segP + start,
+	})
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path, bestP, nil
+}
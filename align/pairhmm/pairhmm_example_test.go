@@ -0,0 +1,50 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pairhmm
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+)
+
+func qletters(s string, q alphabet.Qphred) []alphabet.QLetter {
+	ql := make([]alphabet.QLetter, len(s))
+	for i := range s {
+		ql[i] = alphabet.QLetter{L: alphabet.Letter(s[i]), Q: q}
+	}
+	return ql
+}
+
+func ExamplePairHMM() {
+	haps := []*linear.Seq{
+		linear.NewSeq("ref", alphabet.BytesToLetters([]byte("GATTACAGATTACCGATTACAGG")), alphabet.DNA),
+		linear.NewSeq("alt", alphabet.BytesToLetters([]byte("GATTACAGATTAGCCGATTACAGG")), alphabet.DNA),
+	}
+	read := linear.NewQSeq("read", qletters("CAGATTAGCCGATT", 30), alphabet.DNA, alphabet.Sanger)
+
+	h := PairHMM{GapOpen: 1e-4, GapExtend: 0.1}
+	for _, hap := range haps {
+		p, err := h.Forward(read, hap)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("%s: log P(read|hap) = %.2f\n", hap.Name(), p)
+
+		path, v, err := h.Viterbi(read, hap)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("%s: Viterbi log P = %.2f %v\n", hap.Name(), v, path)
+	}
+	// Output:
+	// ref: log P(read|hap) = -12.57
+	// ref: Viterbi log P = -12.57 [ref[5,12)/read[0,7)=-3.25 -/read[7,8)=-9.21 ref[12,18)/read[8,14)=-0.112]
+	// alt: log P(read|hap) = -3.30
+	// alt: Viterbi log P = -3.30 [alt[5,19)/read[0,14)=-3.3]
+}
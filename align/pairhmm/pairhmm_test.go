@@ -0,0 +1,87 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pairhmm
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	check "launchpad.net/gocheck"
+	"math"
+	"testing"
+)
+
+// Helpers
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestViterbiPath(c *check.C) {
+	h := PairHMM{GapOpen: 1e-3, GapExtend: 0.1}
+	for _, t := range []struct {
+		read, hap string
+	}{
+		{"ACGTACGT", "TTACGTACGTTT"},
+		{"ACGTTTACGT", "GGACGTACGTGG"},
+		{"ACGTACGT", "ACGACGT"},
+		{"AAAAGAAAA", "CCCAAAAAAAACCC"},
+	} {
+		read := linear.NewQSeq("read", qletters(t.read, 20), alphabet.DNA, alphabet.Sanger)
+		hap := linear.NewSeq("hap", alphabet.BytesToLetters([]byte(t.hap)), alphabet.DNA)
+
+		f, err := h.Forward(read, hap)
+		c.Assert(err, check.Equals, nil)
+		path, v, err := h.Viterbi(read, hap)
+		c.Assert(err, check.Equals, nil)
+
+		// The Viterbi path probability is bounded by the total probability.
+		c.Check(v <= f+1e-9, check.Equals, true, check.Commentf("read %q hap %q: %v > %v", t.read, t.hap, v, f))
+
+		// The path covers the read and the segment probabilities sum to the path probability.
+		var sum float64
+		rPos, hPos := 0, path[0].Features()[0].Start()
+		for _, fp := range path {
+			fs := fp.Features()
+			c.Check(fs[0].Start(), check.Equals, hPos)
+			c.Check(fs[1].Start(), check.Equals, rPos)
+			hPos, rPos = fs[0].End(), fs[1].End()
+			sum += fp.(*Segment).LogProb()
+		}
+		c.Check(rPos, check.Equals, len(t.read))
+		c.Check(math.Abs(sum-v) < 1e-9, check.Equals, true, check.Commentf("read %q hap %q: %v != %v", t.read, t.hap, sum, v))
+	}
+}
+
+func (s *S) TestForwardSingle(c *check.C) {
+	// A single base read against a single base haplotype can only be emitted by the match state.
+	h := PairHMM{GapOpen: 1e-3, GapExtend: 0.1}
+	hap := linear.NewSeq("hap", alphabet.BytesToLetters([]byte("A")), alphabet.DNA)
+	for _, t := range []struct {
+		read string
+		p    float64
+	}{
+		{"A", 0.9 * 0.9},
+		{"C", 0.9 * 0.1 / 3},
+		{"N", 0.9 * 0.9},
+	} {
+		read := linear.NewQSeq("read", qletters(t.read, 10), alphabet.DNA, alphabet.Sanger)
+		f, err := h.Forward(read, hap)
+		c.Assert(err, check.Equals, nil)
+		c.Check(math.Abs(math.Exp(f)-t.p) < 1e-12, check.Equals, true, check.Commentf("read %q: %v != %v", t.read, math.Exp(f), t.p))
+	}
+}
+
+func (s *S) TestErrors(c *check.C) {
+	read := linear.NewQSeq("read", qletters("ACGT", 20), alphabet.DNA, alphabet.Sanger)
+	hap := linear.NewSeq("hap", alphabet.BytesToLetters([]byte("ACGT")), alphabet.DNA)
+	_, err := PairHMM{GapOpen: 0.5, GapExtend: 0.1}.Forward(read, hap)
+	c.Check(err, check.Equals, ErrBadProbability)
+	_, err = PairHMM{GapOpen: 1e-3, GapExtend: 0.1}.Forward(read, linear.NewSeq("hap", nil, alphabet.DNA))
+	c.Check(err, check.Equals, ErrEmptySequence)
+	_, err = PairHMM{GapOpen: 1e-3, GapExtend: 0.1}.Forward(read, linear.NewSeq("hap", hap.Seq, alphabet.Protein))
+	c.Check(err, check.Equals, ErrMismatchedTypes)
+}
@@ -8,7 +8,6 @@
 package filter
 
 import (
-	"code.google.com/p/biogo/index/kmerindex"
 	"code.google.com/p/biogo/morass"
 	"code.google.com/p/biogo/seq/linear"
 
@@ -20,22 +19,47 @@ func MinWordsPerFilterHit(hitLength, wordLength, maxErrors int) int {
 	return hitLength + 1 - wordLength*(maxErrors+1)
 }
 
-// Type for passing filter parameters.
+// Ukonnen's Lemma for gapped q-grams of weight q and span s: U(n, q, s, 𝛜) := (n - s + 1) - q⌊𝛜n⌋
+// Each error falls on a seed position of at most q of the gapped q-grams of the match.
+func MinSpacedWordsPerFilterHit(hitLength, span, weight, maxErrors int) int {
+	return hitLength - span + 1 - weight*maxErrors
+}
+
+// Type for passing filter parameters. If Seed is not empty, it holds the spaced seed mask used to
+// build the kmerindex.Index of the filter, and WordSize is the weight of the seed.
 type Params struct {
 	WordSize   int
 	MinMatch   int
 	MaxError   int
 	TubeOffset int
+	Seed       string
+}
+
+// Index is the kmer index of a filter target. Both *kmerindex.Index and *kmerindex.Index64
+// satisfy Index.
+type Index interface {
+	// Seq returns the indexed sequence.
+	Seq() *linear.Seq
+	// K returns the word size of the index.
+	K() int
+	// Span returns the length of sequence covered by a word of the index.
+	Span() int
+	// PosAt returns the indexed sequence position held at p of the index's pos slice.
+	PosAt(p int) int
+	// ForEachRangeOf calls f with the position of each word of s from start to end and the
+	// bounds of the pos slice holding the positions of the word in the indexed sequence.
+	ForEachRangeOf(s *linear.Seq, start, end int, f func(position, from, to int)) error
 }
 
 // Filter implements a q-gram filter similar to that described in Rassmussen 2005.
 // This implementation is a translation of the C++ code written by Edgar and Myers.
 type Filter struct {
 	target         *linear.Seq
-	ki             *kmerindex.Index
+	ki             Index
 	tubes          []tubeState
 	morass         *morass.Morass
 	k              int
+	span           int
 	minMatch       int
 	maxError       int
 	maxKmerDist    int
@@ -46,11 +70,12 @@ type Filter struct {
 }
 
 // Return a new Filter using ki as the target, and filter parameters in params.
-func New(ki *kmerindex.Index, params *Params) (f *Filter) {
+func New(ki Index, params *Params) (f *Filter) {
 	f = &Filter{
 		ki:         ki,
 		target:     ki.Seq(),
 		k:          ki.K(),
+		span:       ki.Span(),
 		minMatch:   params.MinMatch,
 		maxError:   params.MaxError,
 		tubeOffset: params.TubeOffset,
@@ -67,14 +92,18 @@ func (f *Filter) Filter(query *linear.Seq, selfAlign, complement bool, morass *m
 	f.complement = complement
 	f.morass = morass
 	f.k = f.ki.K()
+	f.span = f.ki.Span()
 
 	// Ukonnen's Lemma
-	f.minKmersPerHit = MinWordsPerFilterHit(f.minMatch, f.k, f.maxError)
+	f.minKmersPerHit = MinSpacedWordsPerFilterHit(f.minMatch, f.span, f.k, f.maxError)
+	if f.minKmersPerHit <= 0 {
+		return errors.New("filter: MinMatch too short for word size and MaxError")
+	}
 
 	// Maximum distance between SeqQ positions of two k-mers in a match
 	// (More stringent bounds may be possible, but not a big problem
 	// if two adjacent matches get merged).
-	f.maxKmerDist = f.minMatch - f.k
+	f.maxKmerDist = f.minMatch - f.span
 
 	tubeWidth := f.tubeOffset + f.maxError
 
@@ -89,19 +118,14 @@ func (f *Filter) Filter(query *linear.Seq, selfAlign, complement bool, morass *m
 	ticker := tubeWidth
 
 	var err error
-	err = f.ki.ForEachKmerOf(query, 0, query.Len(), func(ki *kmerindex.Index, position, kmer int) {
-		from := 0
-		if kmer > 0 {
-			from = ki.FingerAt(kmer - 1)
-		}
-		to := ki.FingerAt(kmer)
+	err = f.ki.ForEachRangeOf(query, 0, query.Len(), func(position, from, to int) {
 		for i := from; i < to; i++ {
-			f.commonKmer(ki.PosAt(i), position)
+			f.commonKmer(f.ki.PosAt(i), position)
 		}
 
 		if ticker--; ticker == 0 {
 			if e := f.tubeEnd(position); e != nil {
-				panic(e) // Caught by fastkmerindex.ForEachKmerOf and returned
+				panic(e) // Caught by ForEachRangeOf and returned
 			}
 			ticker = f.tubeOffset
 		}
@@ -249,7 +273,7 @@ func (f *Filter) tubeEnd(q int) error {
 func (f *Filter) addHit(tubeIndex, QLo, QHi int) error {
 	fh := FilterHit{
 		QFrom:     QLo,
-		QTo:       QHi + f.k,
+		QTo:       QHi + f.span,
 		DiagIndex: f.target.Len() - tubeIndex*f.tubeOffset,
 	}
 
@@ -11,6 +11,8 @@ import (
 	"code.google.com/p/biogo/seq/linear"
 	"code.google.com/p/biogo/util"
 	check "launchpad.net/gocheck"
+	"math/rand"
+	"sort"
 	"testing"
 )
 
@@ -91,3 +93,111 @@ func (s *S) TestFilterAndMerge(c *check.C) {
 		{Next: nil, Top: 1024, Bottom: 938, Left: -3072, Right: -3005},
 	})
 }
+
+func (s *S) TestSpacedFilter(c *check.C) {
+	c.Check(MinSpacedWordsPerFilterHit(50, 6, 6, 4), check.Equals, MinWordsPerFilterHit(50, 6, 4))
+
+	rand.Seed(1)
+	l := [...]alphabet.Letter{'a', 'c', 'g', 't'}
+	a := linear.NewSeq("a", make(alphabet.Letters, 4000), alphabet.DNA)
+	for i := range a.Seq {
+		a.Seq[i] = l[rand.Intn(4)]
+	}
+	// A diverged repeat of a[1000:1400] with a substitution every eighth position,
+	// so that no contiguous 8-mer of the repeat is shared with the target.
+	b := linear.NewSeq("b", append(alphabet.Letters(nil), a.Seq[1000:1400]...), alphabet.DNA)
+	for i := 3; i < b.Len(); i += 8 {
+		b.Seq[i] = l[(alphabet.DNA.IndexOf(b.Seq[i])+1)%4]
+	}
+
+	filterHits := func(ki *kmerindex.Index, p *Params) []FilterHit {
+		f := New(ki, p)
+		sorter, err := morass.New(FilterHit{}, "", "", 2<<20, false)
+		c.Assert(err, check.Equals, nil)
+		defer sorter.CleanUp()
+		c.Assert(f.Filter(b, false, false, sorter), check.Equals, nil)
+		var r []FilterHit
+		for {
+			var h FilterHit
+			if sorter.Pull(&h) != nil {
+				break
+			}
+			r = append(r, h)
+		}
+		return r
+	}
+
+	contiguous, err := kmerindex.New(8, a)
+	c.Assert(err, check.Equals, nil)
+	contiguous.Build()
+	c.Check(filterHits(contiguous, &Params{WordSize: 8, MinMatch: 100, MaxError: 6, TubeOffset: 32}), check.HasLen, 0)
+
+	spaced, err := kmerindex.NewSpaced("1110111011101111", a)
+	c.Assert(err, check.Equals, nil)
+	spaced.Build()
+	p := &Params{WordSize: spaced.K(), MinMatch: 100, MaxError: 6, TubeOffset: 32, Seed: spaced.Seed()}
+	hits := filterHits(spaced, p)
+	c.Assert(len(hits) > 0, check.Equals, true)
+	var covered int
+	for _, h := range hits {
+		c.Check(h.QTo <= b.Len(), check.Equals, true)
+		covered += h.QTo - h.QFrom
+	}
+	c.Check(covered >= b.Len()/2, check.Equals, true, check.Commentf("hits: %v", hits))
+}
+
+type hitsByPosition []FilterHit
+
+func (h hitsByPosition) Len() int { return len(h) }
+func (h hitsByPosition) Less(i, j int) bool {
+	if h[i].QFrom != h[j].QFrom {
+		return h[i].QFrom < h[j].QFrom
+	}
+	if h[i].QTo != h[j].QTo {
+		return h[i].QTo < h[j].QTo
+	}
+	return h[i].DiagIndex < h[j].DiagIndex
+}
+func (h hitsByPosition) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (s *S) TestFilterIndex64(c *check.C) {
+	rand.Seed(1)
+	l := [...]alphabet.Letter{'a', 'c', 'g', 't'}
+	a := linear.NewSeq("a", make(alphabet.Letters, 4000), alphabet.DNA)
+	for i := range a.Seq {
+		a.Seq[i] = l[rand.Intn(4)]
+	}
+	b := linear.NewSeq("b", append(alphabet.Letters(nil), a.Seq[1000:1400]...), alphabet.DNA)
+	for i := 3; i < b.Len(); i += 40 {
+		b.Seq[i] = l[(alphabet.DNA.IndexOf(b.Seq[i])+1)%4]
+	}
+
+	filterHits := func(ki Index) []FilterHit {
+		f := New(ki, &Params{WordSize: 12, MinMatch: 100, MaxError: 3, TubeOffset: 32})
+		sorter, err := morass.New(FilterHit{}, "", "", 2<<20, false)
+		c.Assert(err, check.Equals, nil)
+		defer sorter.CleanUp()
+		c.Assert(f.Filter(b, false, false, sorter), check.Equals, nil)
+		var r []FilterHit
+		for {
+			var h FilterHit
+			if sorter.Pull(&h) != nil {
+				break
+			}
+			r = append(r, h)
+		}
+		sort.Sort(hitsByPosition(r))
+		return r
+	}
+
+	ki, err := kmerindex.New(12, a)
+	c.Assert(err, check.Equals, nil)
+	ki.Build()
+	ki64, err := kmerindex.New64(12, a)
+	c.Assert(err, check.Equals, nil)
+	ki64.Build()
+
+	want := filterHits(ki)
+	c.Assert(len(want) > 0, check.Equals, true)
+	c.Check(filterHits(ki64), check.DeepEquals, want)
+}
@@ -6,7 +6,6 @@ package filter
 
 import (
 	"code.google.com/p/biogo/alphabet"
-	"code.google.com/p/biogo/index/kmerindex"
 	"code.google.com/p/biogo/seq/linear"
 
 	"sort"
@@ -31,9 +30,9 @@ type Merger struct {
 	valueToCode                alphabet.Index
 }
 
-// Create a new Merger using the provided kmerindex, query sequence, filter parameters and maximum inter-segment gap length.
+// Create a new Merger using the provided index, query sequence, filter parameters and maximum inter-segment gap length.
 // If selfCompare is true only the upper diagonal of the comparison matrix is examined.
-func NewMerger(ki *kmerindex.Index, query *linear.Seq, filterParams *Params, maxIGap int, selfCompare bool) *Merger {
+func NewMerger(ki Index, query *linear.Seq, filterParams *Params, maxIGap int, selfCompare bool) *Merger {
 	tubeWidth := filterParams.TubeOffset + filterParams.MaxError
 	binWidth := tubeWidth - 1
 	leftPadding := diagonalPadding + binWidth
@@ -52,7 +51,7 @@ func NewMerger(ki *kmerindex.Index, query *linear.Seq, filterParams *Params, max
 		maxIGap:        maxIGap,
 		query:          query,
 		selfComparison: selfCompare,
-		bottomPadding:  ki.K() + 2,
+		bottomPadding:  ki.Span() + 2,
 		leftPadding:    leftPadding,
 		binWidth:       binWidth,
 		eoTerm:         eoTerm,
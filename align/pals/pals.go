@@ -16,6 +16,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"strings"
 	"unsafe"
 )
 
@@ -244,14 +245,34 @@ func (p *PALS) MemRequired(filterParams *filter.Params) uintptr {
 	return filter + sequence
 }
 
-// Build the kmerindex for filtering.
+// Build the kmerindex for filtering. If FilterParams.Seed is not empty, a spaced seed index is
+// built and FilterParams.WordSize is set to the weight of the seed. An error is returned without building
+// the index if a match of FilterParams.MinMatch letters with FilterParams.MaxError errors would not
+// be guaranteed to contain a word of the index.
 func (p *PALS) BuildIndex() error {
+	span, weight := p.FilterParams.WordSize, p.FilterParams.WordSize
+	if seed := p.FilterParams.Seed; seed != "" {
+		span, weight = len(seed), strings.Count(seed, "1")
+	}
+	if err := p.checkWords(span, weight); err != nil {
+		return err
+	}
+
 	p.notify("Indexing")
-	ki, err := kmerindex.New(p.FilterParams.WordSize, p.target)
+	var (
+		ki  *kmerindex.Index
+		err error
+	)
+	if p.FilterParams.Seed != "" {
+		ki, err = kmerindex.NewSpaced(p.FilterParams.Seed, p.target)
+	} else {
+		ki, err = kmerindex.New(p.FilterParams.WordSize, p.target)
+	}
 	if err != nil {
 		return err
 	} else {
 		ki.Build()
+		p.FilterParams.WordSize = ki.K()
 		p.notify("Indexed")
 	}
 	p.index = ki
@@ -260,6 +281,48 @@ func (p *PALS) BuildIndex() error {
 	return nil
 }
 
+// UseIndex sets the kmerindex used for filtering to ki, allowing an index that has been saved and
+// loaded with kmerindex.Save and kmerindex.Load or kmerindex.Open to be used in place of calling
+// BuildIndex. The index must have been built over the target sequence, and its words must be short
+// enough that a match of FilterParams.MinMatch letters with FilterParams.MaxError errors is
+// guaranteed to contain one. The word size and seed of the filter parameters are set from ki.
+func (p *PALS) UseIndex(ki *kmerindex.Index) error {
+	switch {
+	case !ki.Built():
+		return kmerindex.ErrNotBuilt
+	case ki.Seq().Len() != p.target.Len():
+		return kmerindex.ErrSeqMismatch
+	}
+	if err := p.checkWords(ki.Span(), ki.K()); err != nil {
+		return err
+	}
+	p.FilterParams.WordSize = ki.K()
+	if ki.Span() != ki.K() {
+		p.FilterParams.Seed = ki.Seed()
+	} else {
+		p.FilterParams.Seed = ""
+	}
+	p.index = ki
+	p.hitFilter = filter.New(p.index, p.FilterParams)
+
+	return nil
+}
+
+// checkWords returns an error if a match of FilterParams.MinMatch with FilterParams.MaxError errors
+// is not guaranteed to share a word with the target when words of the given weight span span
+// letters.
+func (p *PALS) checkWords(span, weight int) error {
+	if filter.MinSpacedWordsPerFilterHit(p.FilterParams.MinMatch, span, weight, p.FilterParams.MaxError) <= 0 {
+		return errors.New("pals: MinMatch too short for word size and MaxError")
+	}
+	return nil
+}
+
+// Index returns the kmerindex used for filtering, or nil if no index has been built or set.
+func (p *PALS) Index() *kmerindex.Index {
+	return p.index
+}
+
 // Share allows the receiver to use the index and parameters of m.
 func (p *PALS) Share(m *PALS) {
 	p.index = m.index
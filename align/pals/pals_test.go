@@ -8,6 +8,7 @@ import (
 	"code.google.com/p/biogo/align/pals/dp"
 	"code.google.com/p/biogo/align/pals/filter"
 	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/index/kmerindex"
 	"code.google.com/p/biogo/seq"
 	"code.google.com/p/biogo/seq/linear"
 	"code.google.com/p/biogo/util"
@@ -16,6 +17,7 @@ import (
 	"fmt"
 	check "launchpad.net/gocheck"
 	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -184,3 +186,51 @@ deBruijn5	pals	hit	1	904	0.0000	.	.	Target deBruijn5 1 904; maxe 0
 deBruijn8	pals	hit	1025	4095	0.0000	.	.	Target deBruijn8 1025 4095; maxe 0
 `)
 }
+
+func (s *S) TestUseIndex(c *check.C) {
+	t := linear.NewSeq("target", make(alphabet.Letters, 5000), alphabet.DNA)
+	for i := range t.Seq {
+		t.Seq[i] = alphabet.Letter(l[rand.Intn(Q)])
+	}
+	pa := New(t, t, true, nil, 1, 0, nil, nil)
+	pa.FilterParams = &filter.Params{WordSize: 6, MinMatch: 100, MaxError: 5, TubeOffset: 32}
+
+	for _, seed := range []string{"11111111", "1110111011101111"} {
+		ki, err := kmerindex.NewSpaced(seed, t)
+		c.Assert(err, check.Equals, nil)
+		c.Check(pa.UseIndex(ki), check.Equals, kmerindex.ErrNotBuilt)
+		ki.Build()
+		var buf bytes.Buffer
+		c.Assert(ki.Save(&buf), check.Equals, nil)
+		li, err := kmerindex.Load(&buf, t)
+		c.Assert(err, check.Equals, nil)
+
+		c.Check(pa.UseIndex(li), check.Equals, nil)
+		c.Check(pa.Index(), check.Equals, li)
+		c.Check(pa.FilterParams.WordSize, check.Equals, li.K())
+		if li.Span() == li.K() {
+			c.Check(pa.FilterParams.Seed, check.Equals, "")
+		} else {
+			c.Check(pa.FilterParams.Seed, check.Equals, seed)
+		}
+	}
+
+	other := linear.NewSeq("other", t.Seq[:4000], alphabet.DNA)
+	ki, err := kmerindex.New(8, other)
+	c.Assert(err, check.Equals, nil)
+	ki.Build()
+	c.Check(pa.UseIndex(ki), check.Equals, kmerindex.ErrSeqMismatch)
+
+	// A 30 letter match with 3 errors need not share
+	// a word with the target for a seed spanning 16.
+	short := New(t, t, true, nil, 1, 0, nil, nil)
+	short.FilterParams = &filter.Params{WordSize: 6, MinMatch: 30, MaxError: 3, TubeOffset: 32, Seed: "1110111011101111"}
+	c.Check(short.BuildIndex(), check.NotNil)
+	c.Check(short.Index(), check.IsNil)
+	ki, err = kmerindex.NewSpaced("1110111011101111", t)
+	c.Assert(err, check.Equals, nil)
+	ki.Build()
+	c.Check(short.UseIndex(ki), check.NotNil)
+	c.Check(short.Index(), check.IsNil)
+	c.Check(short.FilterParams.WordSize, check.Equals, 6)
+}
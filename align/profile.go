@@ -0,0 +1,410 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+	"code.google.com/p/biogo/seq/multi"
+
+	"errors"
+	"fmt"
+	"math"
+)
+
+// A ProfileSource is an alignment that can be used to construct a Profile. *multi.Multi,
+// *alignment.Seq and *alignment.QSeq are ProfileSources.
+type ProfileSource interface {
+	seq.Aligned
+	seq.Rower
+	Alphabet() alphabet.Alphabet
+}
+
+// A Profile is a position-specific profile of a multiple sequence alignment. Each column
+// of the profile holds the frequency of each letter of the alignment's alphabet, including
+// the gap letter, in the corresponding alignment column.
+type Profile struct {
+	alpha alphabet.Alphabet
+	seqs  []seq.Sequence
+	rows  []alphabet.QLetters
+	freqs [][]float64
+}
+
+// NewProfile returns a Profile constructed from the alignment a. Rows of a that are
+// themselves alignments are expanded into their constituent rows. An error is returned
+// if a has no alphabet, the alphabet is not gapped or the rows of a cannot be determined.
+func NewProfile(a ProfileSource) (*Profile, error) {
+	alpha := a.Alphabet()
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	p := &Profile{alpha: alpha, seqs: leaves(a)}
+	p.rows = make([]alphabet.QLetters, len(p.seqs))
+	for pos := a.Start(); pos < a.End(); pos++ {
+		c := a.ColumnQL(pos, true)
+		if len(c) != len(p.rows) {
+			return nil, errors.New("align: cannot determine profile rows")
+		}
+		for i, l := range c {
+			p.rows[i] = append(p.rows[i], l)
+		}
+	}
+	p.count()
+	return p, nil
+}
+
+// NewSequenceProfile returns a single row Profile constructed from the sequence s. An
+// error is returned if s has no alphabet or the alphabet is not gapped.
+func NewSequenceProfile(s seq.Sequence) (*Profile, error) {
+	alpha := s.Alphabet()
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	row := make(alphabet.QLetters, 0, s.Len())
+	for i := s.Start(); i < s.End(); i++ {
+		row = append(row, s.At(i))
+	}
+	p := &Profile{
+		alpha: alpha,
+		seqs:  []seq.Sequence{s},
+		rows:  []alphabet.QLetters{row},
+	}
+	p.count()
+	return p, nil
+}
+
+// leaves returns the sequences of r, expanding rows that are themselves alignments.
+func leaves(r seq.Rower) []seq.Sequence {
+	var s []seq.Sequence
+	for i := 0; i < r.Rows(); i++ {
+		row := r.Row(i)
+		if _, ok := row.(seq.Aligned); ok {
+			if n, ok := row.(seq.Rower); ok {
+				s = append(s, leaves(n)...)
+				continue
+			}
+		}
+		s = append(s, row)
+	}
+	return s
+}
+
+// count calculates the letter frequencies of each column of the profile. Letters not
+// in the profile's alphabet do not contribute to the frequencies.
+func (p *Profile) count() {
+	index := p.alpha.LetterIndex()
+	p.freqs = make([][]float64, p.Len())
+	w := 1 / float64(len(p.rows))
+	for col := range p.freqs {
+		f := make([]float64, p.alpha.Len())
+		for _, r := range p.rows {
+			if idx := index[r[col].L]; idx >= 0 {
+				f[idx] += w
+			}
+		}
+		p.freqs[col] = f
+	}
+}
+
+// Alphabet returns the alphabet of the profile.
+func (p *Profile) Alphabet() alphabet.Alphabet { return p.alpha }
+
+// Len returns the number of columns in the profile.
+func (p *Profile) Len() int {
+	if len(p.rows) == 0 {
+		return 0
+	}
+	return len(p.rows[0])
+}
+
+// Rows returns the number of sequences contributing to the profile.
+func (p *Profile) Rows() int { return len(p.rows) }
+
+// Freqs returns the letter frequencies of column col indexed by the profile alphabet's
+// letter indices. The gap frequency is at index 0.
+func (p *Profile) Freqs(col int) []float64 { return p.freqs[col] }
+
+// Subset returns a Profile holding the rows of p indicated by rows, in the order given.
+// Columns that hold only gaps in the selected rows are removed.
+func (p *Profile) Subset(rows ...int) *Profile {
+	s := &Profile{
+		alpha: p.alpha,
+		seqs:  make([]seq.Sequence, len(rows)),
+		rows:  make([]alphabet.QLetters, len(rows)),
+	}
+	for i, r := range rows {
+		s.seqs[i] = p.seqs[r]
+	}
+	gap := p.alpha.Gap()
+	for col := 0; col < p.Len(); col++ {
+		var keep bool
+		for _, r := range rows {
+			if p.rows[r][col].L != gap {
+				keep = true
+				break
+			}
+		}
+		if !keep {
+			continue
+		}
+		for i, r := range rows {
+			s.rows[i] = append(s.rows[i], p.rows[r][col])
+		}
+	}
+	s.count()
+	return s
+}
+
+// Multi returns the alignment represented by the profile as a *multi.Multi with the given
+// id and consensus function. Rows are returned as *linear.QSeq if the contributing
+// sequence was a *linear.QSeq and as *linear.Seq otherwise.
+func (p *Profile) Multi(id string, cons seq.ConsenseFunc) (*multi.Multi, error) {
+	rows := make([]seq.Sequence, len(p.rows))
+	for i, r := range p.rows {
+		switch s := p.seqs[i].(type) {
+		case *linear.QSeq:
+			c := *s
+			c.Seq = append(alphabet.QLetters(nil), r...)
+			c.Offset = 0
+			rows[i] = &c
+		default:
+			ann := s.CloneAnnotation()
+			ann.Offset = 0
+			l := make(alphabet.Letters, len(r))
+			for j, ql := range r {
+				l[j] = ql.L
+			}
+			rows[i] = &linear.Seq{Annotation: *ann, Seq: l}
+		}
+	}
+	return multi.NewMulti(id, rows, cons)
+}
+
+// ProfileAffine is a global aligner of Profiles using an affine gap penalty. The Matrix is a
+// square scoring matrix indexed by the profile alphabet's letter indices, with the first
+// column and first row specifying gap penalties, and GapOpen is added to the first column
+// of a run of gap columns.
+//
+// Two profile columns are scored by the expected matrix score of their letters, weighting
+// letters by their column frequency. Gap letters are scored against letters using the gap
+// penalties of the matrix, so an alignment of a single sequence profile to another single
+// sequence profile is scored in the same way as by NWAffine.
+type ProfileAffine Affine
+
+// Align aligns the profile q to the profile p, returning an alignment description of the
+// columns of p and q. An error is returned if the scoring matrix is not square or does not
+// cover the profile alphabet, or the profile alphabets do not match.
+func (a ProfileAffine) Align(p, q *Profile) ([]feat.Pair, error) {
+	la, err := flatten(a.Matrix)
+	if err != nil {
+		return nil, err
+	}
+	if p.alpha != q.alpha {
+		return nil, ErrMismatchedAlphabets
+	}
+	let := len(a.Matrix)
+	if let < p.alpha.Len() {
+		return nil, fmt.Errorf("align: matrix too small for alphabet: %d < %d", let, p.alpha.Len())
+	}
+
+	// w[i][b] is the expected score of letter b against column i of p.
+	w := make([][]float64, p.Len())
+	for i := range w {
+		w[i] = make([]float64, let)
+		for ai, f := range p.freqs[i] {
+			if f == 0 {
+				continue
+			}
+			for b := range w[i] {
+				w[i][b] += f * float64(la[ai*let+b])
+			}
+		}
+	}
+	// v[j] is the expected score of column j of q against a gap.
+	v := make([]float64, q.Len())
+	for j := range v {
+		for b, f := range q.freqs[j] {
+			v[j] += f * float64(la[b])
+		}
+	}
+	match := func(i, j int) float64 {
+		var s float64
+		for b, f := range q.freqs[j] {
+			s += f * w[i][b]
+		}
+		return s
+	}
+
+	var (
+		open  = float64(a.GapOpen)
+		inf   = math.Inf(-1)
+		r, c  = p.Len() + 1, q.Len() + 1
+		table = make([][3]float64, r*c)
+		trace = make([][3]byte, r*c)
+	)
+	table[0] = [3]float64{diag: 0, up: inf, left: inf}
+	for i := 1; i < r; i++ {
+		k := i * c
+		table[k] = [3]float64{diag: inf, up: table[k-c][up] + w[i-1][gap], left: inf}
+		trace[k][up] = up
+		if i == 1 {
+			table[k][up] = open + w[i-1][gap]
+			trace[k][up] = diag
+		}
+	}
+	for j := 1; j < c; j++ {
+		table[j] = [3]float64{diag: inf, up: inf, left: table[j-1][left] + v[j-1]}
+		trace[j][left] = left
+		if j == 1 {
+			table[j][left] = open + v[j-1]
+			trace[j][left] = diag
+		}
+	}
+
+	for i := 1; i < r; i++ {
+		for j := 1; j < c; j++ {
+			k := i*c + j
+
+			d := table[k-c-1]
+			best, dir := d[diag], byte(diag)
+			if d[up] > best {
+				best, dir = d[up], up
+			}
+			if d[left] > best {
+				best, dir = d[left], left
+			}
+			table[k][diag] = best + match(i-1, j-1)
+			trace[k][diag] = dir
+
+			u := table[k-c]
+			if s := u[diag] + open; s >= u[up] {
+				table[k][up], trace[k][up] = s, diag
+			} else {
+				table[k][up], trace[k][up] = u[up], up
+			}
+			table[k][up] += w[i-1][gap]
+
+			l := table[k-1]
+			if s := l[diag] + open; s >= l[left] {
+				table[k][left], trace[k][left] = s, diag
+			} else {
+				table[k][left], trace[k][left] = l[left], left
+			}
+			table[k][left] += v[j-1]
+		}
+	}
+
+	var (
+		aln   []feat.Pair
+		score float64
+		layer = diag
+		i, j  = r - 1, c - 1
+		maxI  = i
+		maxJ  = j
+		end   = table[i*c+j]
+	)
+	if end[up] > end[layer] {
+		layer = up
+	}
+	if end[left] > end[layer] {
+		layer = left
+	}
+	last := layer
+	for i > 0 || j > 0 {
+		k := i*c + j
+		move := layer
+		if last != move && (i != maxI || j != maxJ) {
+			aln = append(aln, &featPair{
+				a:     feature{start: i, end: maxI},
+				b:     feature{start: j, end: maxJ},
+				score: int(math.Floor(score + 0.5)),
+			})
+			maxI, maxJ = i, j
+			score = 0
+		}
+		var prev int
+		switch move {
+		case diag:
+			prev = k - c - 1
+			i--
+			j--
+		case up:
+			prev = k - c
+			i--
+		case left:
+			prev = k - 1
+			j--
+		}
+		layer = int(trace[k][move])
+		score += table[k][move] - table[prev][layer]
+		last = move
+	}
+	aln = append(aln, &featPair{
+		a:     feature{start: i, end: maxI},
+		b:     feature{start: j, end: maxJ},
+		score: int(math.Floor(score + 0.5)),
+	})
+
+	for i, j := 0, len(aln)-1; i < j; i, j = i+1, j-1 {
+		aln[i], aln[j] = aln[j], aln[i]
+	}
+
+	return aln, nil
+}
+
+// Merge aligns the profile q to the profile p and returns a Profile holding the rows of p
+// followed by the rows of q. The columns of both p and q are retained and gap columns are
+// inserted into each where required by the alignment.
+func (a ProfileAffine) Merge(p, q *Profile) (*Profile, error) {
+	aln, err := a.Align(p, q)
+	if err != nil {
+		return nil, err
+	}
+	return mergeProfiles(p, q, aln), nil
+}
+
+// mergeProfiles returns the Profile described by the alignment aln of q to p.
+func mergeProfiles(p, q *Profile, aln []feat.Pair) *Profile {
+	m := &Profile{
+		alpha: p.alpha,
+		seqs:  append(append([]seq.Sequence(nil), p.seqs...), q.seqs...),
+		rows:  make([]alphabet.QLetters, len(p.rows)+len(q.rows)),
+	}
+	gapCol := alphabet.QLetter{L: p.alpha.Gap()}
+	for _, fp := range aln {
+		f := fp.Features()
+		fa, fb := f[0], f[1]
+		n := fa.Len()
+		if fb.Len() > n {
+			n = fb.Len()
+		}
+		for k := 0; k < n; k++ {
+			for r := range p.rows {
+				l := gapCol
+				if fa.Len() != 0 {
+					l = p.rows[r][fa.Start()+k]
+				}
+				m.rows[r] = append(m.rows[r], l)
+			}
+			for r := range q.rows {
+				l := gapCol
+				if fb.Len() != 0 {
+					l = q.rows[r][fb.Start()+k]
+				}
+				m.rows[len(p.rows)+r] = append(m.rows[len(p.rows)+r], l)
+			}
+		}
+	}
+	m.count()
+	return m
+}
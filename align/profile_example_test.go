@@ -0,0 +1,81 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+	"code.google.com/p/biogo/seq/multi"
+
+	"fmt"
+)
+
+func ExampleProfileAffine_Merge() {
+	m, err := multi.NewMulti("m",
+		[]seq.Sequence{
+			linear.NewSeq("a", alphabet.BytesToLetters([]byte("ACGTTGCA-AC")), alphabet.DNAgapped),
+			linear.NewSeq("b", alphabet.BytesToLetters([]byte("ACGTAGCAGAC")), alphabet.DNAgapped),
+		},
+		seq.DefaultConsensus,
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	p, err := NewProfile(m)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	q, err := NewSequenceProfile(linear.NewSeq("c", alphabet.BytesToLetters([]byte("ACGTTCAGGAC")), alphabet.DNAgapped))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// w(gap) = -5
+	// w(match) = +4
+	// w(mismatch) = -5
+	//
+	// w(open) = -5
+	pa := ProfileAffine{
+		Matrix: Linear{
+			{0, -5, -5, -5, -5},
+			{-5, 4, -5, -5, -5},
+			{-5, -5, 4, -5, -5},
+			{-5, -5, -5, 4, -5},
+			{-5, -5, -5, -5, 4},
+		},
+		GapOpen: -5,
+	}
+
+	aln, err := pa.Align(p, q)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%s\n", aln)
+
+	mp, err := pa.Merge(p, q)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	mm, err := mp.Multi("merged", seq.DefaultConsensus)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%-s\n\n%-s\n", mm, mm.Consensus(false))
+
+	// Output:
+	// [[0,5)/[0,5)=16 [5,6)/-=-10 [6,8)/[5,7)=8 -/[7,8)=-10 [8,11)/[8,11)=8]
+	// ACGTTGCA--AC
+	// ACGTAGCA-GAC
+	// ACGTT-CAGGAC
+	//
+	// acgttgca-gac
+}
@@ -0,0 +1,278 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/feat"
+
+	"fmt"
+)
+
+// ProteinDNA is a codon-aware local aligner of protein sequences against nucleic acid sequences
+// in the manner of GeneWise and exonerate's protein2dna model. Codons of the nucleic acid sequence
+// are translated within the dynamic programming and scored against protein residues, allowing
+// frameshifts in the nucleic acid sequence at a fixed penalty.
+//
+// Matrix is a square protein scoring matrix indexed by the query alphabet, with the first row and
+// column specifying gap penalties for an inserted codon's translation and a deleted residue
+// respectively. GapOpen is added to the first gap of a run of inserted codons or deleted residues.
+// Frameshift is the score for skipping one or two nucleotides of the reference. If Code is nil
+// the standard genetic code is used.
+type ProteinDNA struct {
+	Matrix     Linear
+	GapOpen    int
+	Frameshift int
+	Code       *GeneticCode
+}
+
+// shift is the frameshift layer of the ProteinDNA dynamic programming table.
+const shift = left + 1
+
+var _ Aligner = ProteinDNA{}
+
+// A CodonPair is a feat.Pair describing the alignment of a segment of a nucleic acid reference
+// to a segment of a protein query.
+type CodonPair interface {
+	feat.Pair
+	Score() int
+
+	// Frame returns the reading frame, 0, 1 or 2, of the reference segment relative to the start
+	// of the reference sequence.
+	Frame() int
+
+	// Frameshift returns whether the segment describes a frameshift.
+	Frameshift() bool
+}
+
+type codonPair struct {
+	featPair
+	frameshift bool
+}
+
+func (cp *codonPair) Frame() int       { return cp.a.start % 3 }
+func (cp *codonPair) Frameshift() bool { return cp.frameshift }
+func (cp *codonPair) String() string {
+	if cp.frameshift {
+		return fmt.Sprintf("%s[%d,%d)/!=%d", cp.a.Name(), cp.a.start, cp.a.end, cp.score)
+	}
+	return cp.featPair.String()
+}
+
+// letters returns the letters held by s.
+func letters(s alphabet.Slice) (alphabet.Letters, error) {
+	switch s := s.(type) {
+	case alphabet.Letters:
+		return s, nil
+	case alphabet.QLetters:
+		l := make(alphabet.Letters, len(s))
+		for i, ql := range s {
+			l[i] = ql.L
+		}
+		return l, nil
+	default:
+		return nil, ErrTypeNotHandled
+	}
+}
+
+// Align aligns a protein query to a nucleic acid reference, returning an alignment description
+// of CodonPair values, or an error if the scoring matrix is not square, the reference is not a
+// nucleic acid sequence, the query is not a protein sequence, or the sequence data types are not
+// handled.
+func (a ProteinDNA) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	la, err := flatten(a.Matrix)
+	if err != nil {
+		return nil, err
+	}
+	rAlpha, qAlpha := reference.Alphabet(), query.Alphabet()
+	if rAlpha == nil || qAlpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if m := rAlpha.Moltype(); (m != feat.DNA && m != feat.RNA) || qAlpha.Moltype() != feat.Protein {
+		return nil, ErrMismatchedAlphabets
+	}
+	if qAlpha.IndexOf(qAlpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	rSeq, err := letters(reference.Slice())
+	if err != nil {
+		return nil, err
+	}
+	qSeq, err := letters(query.Slice())
+	if err != nil {
+		return nil, err
+	}
+
+	code := a.Code
+	if code == nil {
+		code = StandardCode
+	}
+	index := qAlpha.LetterIndex()
+	tIdx := make([]int, len(rSeq))
+	for i := 0; i+3 <= len(rSeq); i++ {
+		tIdx[i] = index[code.Translate(rSeq[i], rSeq[i+1], rSeq[i+2])]
+	}
+	qIdx := make([]int, len(qSeq))
+	for j, l := range qSeq {
+		qIdx[j] = index[l]
+	}
+
+	let := len(a.Matrix)
+	r, c := len(rSeq)+1, len(qSeq)+1
+	table := make([][4]int, r*c)
+
+	var (
+		maxS, maxI, maxJ = 0, 0, 0
+
+		scores [4]int
+	)
+	for i := 1; i < r; i++ {
+		for j := 0; j < c; j++ {
+			p := i*c + j
+
+			score := minInt
+			for k := 1; k <= 2 && k <= i; k++ {
+				prev := table[p-k*c]
+				scores = [4]int{diag: prev[diag], up: prev[up], left: prev[left], shift: minInt}
+				score = max2(score, maxOf(&scores)+a.Frameshift)
+			}
+			table[p][shift] = max2(score, 0)
+
+			if i >= 3 {
+				if tVal := tIdx[i-3]; tVal >= 0 {
+					prev := table[p-3*c]
+					table[p][up] = max2(max2(
+						max2(prev[diag], prev[shift])+a.GapOpen+la[tVal*let],
+						prev[up]+la[tVal*let],
+					), 0)
+					if j > 0 {
+						if qVal := qIdx[j-1]; qVal >= 0 {
+							scores = table[p-3*c-1]
+							table[p][diag] = max2(maxOf(&scores)+la[tVal*let+qVal], 0)
+							if table[p][diag] >= maxS { // greedy so make farthest down and right
+								maxS, maxI, maxJ = table[p][diag], i, j
+							}
+						}
+					}
+				}
+			}
+			if j > 0 {
+				if qVal := qIdx[j-1]; qVal >= 0 {
+					prev := table[p-1]
+					table[p][left] = max2(max2(
+						max2(prev[diag], prev[shift])+a.GapOpen+la[qVal],
+						prev[left]+la[qVal],
+					), 0)
+				}
+			}
+		}
+	}
+
+	var aln []feat.Pair
+	score, last, layer := 0, diag, diag
+	i, j := maxI, maxJ
+	for i > 0 {
+		p := i*c + j
+		v := table[p][layer]
+		if v == 0 {
+			break
+		}
+		var (
+			move      = layer
+			prev      int
+			prevLayer = -1
+			di, dj    int
+		)
+		switch layer {
+		case diag:
+			di, dj = 3, 1
+			prev = p - 3*c - 1
+			s := v - la[tIdx[i-3]*let+qIdx[j-1]]
+			for l, ps := range table[prev] {
+				if ps == s {
+					prevLayer = l
+					break
+				}
+			}
+		case up:
+			di = 3
+			prev = p - 3*c
+			g := la[tIdx[i-3]*let]
+			switch v {
+			case table[prev][up] + g:
+				prevLayer = up
+			case table[prev][diag] + a.GapOpen + g:
+				prevLayer = diag
+			case table[prev][shift] + a.GapOpen + g:
+				prevLayer = shift
+			}
+		case left:
+			dj = 1
+			prev = p - 1
+			g := la[qIdx[j-1]]
+			switch v {
+			case table[prev][left] + g:
+				prevLayer = left
+			case table[prev][diag] + a.GapOpen + g:
+				prevLayer = diag
+			case table[prev][shift] + a.GapOpen + g:
+				prevLayer = shift
+			}
+		case shift:
+		search:
+			for k := 1; k <= 2 && k <= i; k++ {
+				for l, ps := range table[p-k*c][:shift] {
+					if ps+a.Frameshift == v {
+						di, prev, prevLayer = k, p-k*c, l
+						break search
+					}
+				}
+			}
+		}
+		if prevLayer < 0 {
+			panic(fmt.Sprintf("align: protein/dna internal error: no path at row: %d col:%d layer:%s\n", i, j, "mulf"[layer:layer+1]))
+		}
+		if last != move && (i != maxI || j != maxJ) {
+			aln = append(aln, &codonPair{
+				featPair: featPair{
+					a:     feature{start: i, end: maxI},
+					b:     feature{start: j, end: maxJ},
+					score: score,
+				},
+				frameshift: last == shift,
+			})
+			maxI, maxJ = i, j
+			score = 0
+		}
+		score += v - table[prev][prevLayer]
+		i -= di
+		j -= dj
+		last, layer = move, prevLayer
+	}
+	aln = append(aln, &codonPair{
+		featPair: featPair{
+			a:     feature{start: i, end: maxI},
+			b:     feature{start: j, end: maxJ},
+			score: score,
+		},
+		frameshift: last == shift,
+	})
+
+	for i, j := 0, len(aln)-1; i < j; i, j = i+1, j-1 {
+		aln[i], aln[j] = aln[j], aln[i]
+	}
+
+	return aln, nil
+}
+
+func maxOf(a *[4]int) int {
+	m := minInt
+	for _, v := range a {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
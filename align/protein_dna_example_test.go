@@ -0,0 +1,52 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/align/matrix"
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+)
+
+func ExampleProteinDNA_Align() {
+	// The reference encodes MKTAYIAKQRQISFVKSHFSRQ with a single
+	// base deletion in the codon for the second lysine.
+	dna := &linear.Seq{Seq: alphabet.BytesToLetters([]byte(
+		"ccggcc" +
+			"ATGAAAACCGCTTATATTGCCAA" + "CAGCGTCAGATTTCCTTTGTGAAAAGCCATTTTAGCCGCCAG" +
+			"ggccgg",
+	))}
+	dna.Alpha = alphabet.DNA
+	prot := &linear.Seq{Seq: alphabet.BytesToLetters([]byte("MKTAYIAKQRQISFVKSHFSRQ"))}
+	prot.Alpha = alphabet.Protein
+
+	// Use BLOSUM62 with a gap extension score of -1.
+	m := make(Linear, len(matrix.BLOSUM62))
+	for i, row := range matrix.BLOSUM62 {
+		m[i] = append([]int(nil), row...)
+		m[i][0], m[0][i] = -1, -1
+	}
+	m[0][0] = 0
+	pd := ProteinDNA{
+		Matrix:     m,
+		GapOpen:    -10,
+		Frameshift: -15,
+	}
+
+	aln, err := pd.Align(dna, prot)
+	if err == nil {
+		for _, p := range aln {
+			cp := p.(CodonPair)
+			fmt.Printf("%v frame=%d frameshift=%t\n", cp, cp.Frame(), cp.Frameshift())
+		}
+	}
+	// Output:
+	// [6,27)/[0,7)=34 frame=0 frameshift=false
+	// [27,29)/!=-15 frame=0 frameshift=true
+	// -/[7,8)=-11 frame=2 frameshift=false
+	// [29,71)/[8,22)=70 frame=2 frameshift=false
+}
@@ -0,0 +1,297 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/feat"
+
+	"math"
+)
+
+// Quality-aware aligners scale the match and mismatch score of each pair of aligned letters
+// by the probability that both letters were correctly called, (1-E(r))(1-E(q)), where E is the
+// error probability of the letter's Phred score as returned by alphabet.Qphred.ProbE. A mismatch
+// between low quality letters is therefore penalised less than a mismatch between high quality
+// letters. Gap penalties are not scaled.
+//
+// Sequences with alphabet.Letters data carry no quality information and are aligned without
+// scaling, equivalently to the non-quality-aware aligner with the same parameters.
+
+var (
+	_ Aligner = SWQuality{}
+	_ Aligner = SWAffineQuality{}
+	_ Aligner = NWQuality{}
+	_ Aligner = NWAffineQuality{}
+	_ Aligner = FittedQuality{}
+	_ Aligner = FittedAffineQuality{}
+)
+
+// qualityScale holds a lookup for the probability that a letter with a given Phred score
+// is correct. Undefined qualities are not scaled.
+var qualityScale = func() [256]float64 {
+	var t [256]float64
+	for q := range t {
+		e := alphabet.Qphred(q).ProbE()
+		if math.IsNaN(e) {
+			t[q] = 1
+			continue
+		}
+		t[q] = 1 - e
+	}
+	return t
+}()
+
+// scaleQuality returns the score s scaled by the probability that letters with the qualities
+// qa and qb are both correct, rounded to the nearest integer.
+func scaleQuality(s int, qa, qb alphabet.Qphred) int {
+	return int(math.Floor(float64(s)*qualityScale[qa]*qualityScale[qb] + 0.5))
+}
+
+// SWQuality is the quality-aware linear gap penalty Smith-Waterman aligner type.
+type SWQuality Linear
+
+// Align aligns two sequences using the Smith-Waterman algorithm with quality-scaled substitution scores.
+// It returns an alignment description or an error if the scoring matrix is not square, or the
+// sequence data types or alphabets do not match.
+func (a SWQuality) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	return a.AlignWith(nil, reference, query)
+}
+
+// AlignWith aligns two sequences as for Align using the dynamic programming buffers held
+// by ws. If ws is nil, new buffers are allocated.
+func (a SWQuality) AlignWith(ws *Workspace, reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	alpha := reference.Alphabet()
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if alpha != query.Alphabet() {
+		return nil, ErrMismatchedAlphabets
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	switch rSeq := reference.Slice().(type) {
+	case alphabet.Letters:
+		qSeq, ok := query.Slice().(alphabet.Letters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return SW(a).alignLetters(rSeq, qSeq, alpha, ws)
+	case alphabet.QLetters:
+		qSeq, ok := query.Slice().(alphabet.QLetters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return a.alignQLetters(rSeq, qSeq, alpha, ws)
+	default:
+		return nil, ErrTypeNotHandled
+	}
+}
+
+// SWAffineQuality is the quality-aware affine gap penalty Smith-Waterman aligner type.
+type SWAffineQuality Affine
+
+// Align aligns two sequences using the Smith-Waterman algorithm with quality-scaled substitution scores.
+// It returns an alignment description or an error if the scoring matrix is not square, or the
+// sequence data types or alphabets do not match.
+func (a SWAffineQuality) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	return a.AlignWith(nil, reference, query)
+}
+
+// AlignWith aligns two sequences as for Align using the dynamic programming buffers held
+// by ws. If ws is nil, new buffers are allocated.
+func (a SWAffineQuality) AlignWith(ws *Workspace, reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	alpha := reference.Alphabet()
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if alpha != query.Alphabet() {
+		return nil, ErrMismatchedAlphabets
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	switch rSeq := reference.Slice().(type) {
+	case alphabet.Letters:
+		qSeq, ok := query.Slice().(alphabet.Letters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return SWAffine(a).alignLetters(rSeq, qSeq, alpha, ws)
+	case alphabet.QLetters:
+		qSeq, ok := query.Slice().(alphabet.QLetters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return a.alignQLetters(rSeq, qSeq, alpha, ws)
+	default:
+		return nil, ErrTypeNotHandled
+	}
+}
+
+// NWQuality is the quality-aware linear gap penalty Needleman-Wunsch aligner type.
+type NWQuality Linear
+
+// Align aligns two sequences using the Needleman-Wunsch algorithm with quality-scaled substitution scores.
+// It returns an alignment description or an error if the scoring matrix is not square, or the
+// sequence data types or alphabets do not match.
+func (a NWQuality) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	return a.AlignWith(nil, reference, query)
+}
+
+// AlignWith aligns two sequences as for Align using the dynamic programming buffers held
+// by ws. If ws is nil, new buffers are allocated.
+func (a NWQuality) AlignWith(ws *Workspace, reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	alpha := reference.Alphabet()
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if alpha != query.Alphabet() {
+		return nil, ErrMismatchedAlphabets
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	switch rSeq := reference.Slice().(type) {
+	case alphabet.Letters:
+		qSeq, ok := query.Slice().(alphabet.Letters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return NW(a).alignLetters(rSeq, qSeq, alpha, ws)
+	case alphabet.QLetters:
+		qSeq, ok := query.Slice().(alphabet.QLetters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return a.alignQLetters(rSeq, qSeq, alpha, ws)
+	default:
+		return nil, ErrTypeNotHandled
+	}
+}
+
+// NWAffineQuality is the quality-aware affine gap penalty Needleman-Wunsch aligner type.
+type NWAffineQuality Affine
+
+// Align aligns two sequences using the Needleman-Wunsch algorithm with quality-scaled substitution scores.
+// It returns an alignment description or an error if the scoring matrix is not square, or the
+// sequence data types or alphabets do not match.
+func (a NWAffineQuality) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	return a.AlignWith(nil, reference, query)
+}
+
+// AlignWith aligns two sequences as for Align using the dynamic programming buffers held
+// by ws. If ws is nil, new buffers are allocated.
+func (a NWAffineQuality) AlignWith(ws *Workspace, reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	alpha := reference.Alphabet()
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if alpha != query.Alphabet() {
+		return nil, ErrMismatchedAlphabets
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	switch rSeq := reference.Slice().(type) {
+	case alphabet.Letters:
+		qSeq, ok := query.Slice().(alphabet.Letters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return NWAffine(a).alignLetters(rSeq, qSeq, alpha, ws)
+	case alphabet.QLetters:
+		qSeq, ok := query.Slice().(alphabet.QLetters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return a.alignQLetters(rSeq, qSeq, alpha, ws)
+	default:
+		return nil, ErrTypeNotHandled
+	}
+}
+
+// FittedQuality is the quality-aware linear gap penalty fitted Needleman-Wunsch aligner type.
+type FittedQuality Linear
+
+// Align aligns two sequences using the Needleman-Wunsch algorithm with quality-scaled substitution scores.
+// It returns an alignment description or an error if the scoring matrix is not square, or the
+// sequence data types or alphabets do not match.
+func (a FittedQuality) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	return a.AlignWith(nil, reference, query)
+}
+
+// AlignWith aligns two sequences as for Align using the dynamic programming buffers held
+// by ws. If ws is nil, new buffers are allocated.
+func (a FittedQuality) AlignWith(ws *Workspace, reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	alpha := reference.Alphabet()
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if alpha != query.Alphabet() {
+		return nil, ErrMismatchedAlphabets
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	switch rSeq := reference.Slice().(type) {
+	case alphabet.Letters:
+		qSeq, ok := query.Slice().(alphabet.Letters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return Fitted(a).alignLetters(rSeq, qSeq, alpha, ws)
+	case alphabet.QLetters:
+		qSeq, ok := query.Slice().(alphabet.QLetters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return a.alignQLetters(rSeq, qSeq, alpha, ws)
+	default:
+		return nil, ErrTypeNotHandled
+	}
+}
+
+// FittedAffineQuality is the quality-aware affine gap penalty fitted Needleman-Wunsch aligner type.
+type FittedAffineQuality Affine
+
+// Align aligns two sequences using the Needleman-Wunsch algorithm with quality-scaled substitution scores.
+// It returns an alignment description or an error if the scoring matrix is not square, or the
+// sequence data types or alphabets do not match.
+func (a FittedAffineQuality) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	return a.AlignWith(nil, reference, query)
+}
+
+// AlignWith aligns two sequences as for Align using the dynamic programming buffers held
+// by ws. If ws is nil, new buffers are allocated.
+func (a FittedAffineQuality) AlignWith(ws *Workspace, reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	alpha := reference.Alphabet()
+	if alpha == nil {
+		return nil, ErrNoAlphabet
+	}
+	if alpha != query.Alphabet() {
+		return nil, ErrMismatchedAlphabets
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, ErrNotGappedAlphabet
+	}
+	switch rSeq := reference.Slice().(type) {
+	case alphabet.Letters:
+		qSeq, ok := query.Slice().(alphabet.Letters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return FittedAffine(a).alignLetters(rSeq, qSeq, alpha, ws)
+	case alphabet.QLetters:
+		qSeq, ok := query.Slice().(alphabet.QLetters)
+		if !ok {
+			return nil, ErrMismatchedTypes
+		}
+		return a.alignQLetters(rSeq, qSeq, alpha, ws)
+	default:
+		return nil, ErrTypeNotHandled
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+)
+
+func qletters(s string, q []alphabet.Qphred) []alphabet.QLetter {
+	ql := make([]alphabet.QLetter, len(s))
+	for i := range s {
+		ql[i] = alphabet.QLetter{L: alphabet.Letter(s[i]), Q: q[i]}
+	}
+	return ql
+}
+
+func ExampleSWQuality_Align() {
+	qsa := linear.NewQSeq("a", qletters("ACGTACGTACGT",
+		[]alphabet.Qphred{40, 40, 40, 40, 40, 40, 40, 40, 40, 40, 40, 40},
+	), alphabet.DNAgapped, alphabet.Sanger)
+	qsb := linear.NewQSeq("b", qletters("ACGTAGGTACGT",
+		[]alphabet.Qphred{40, 40, 40, 40, 40, 2, 40, 40, 40, 40, 40, 40},
+	), alphabet.DNAgapped, alphabet.Sanger)
+
+	// w(gap) = -5
+	// w(match) = +4
+	// w(mismatch) = -5
+	matrix := Linear{
+		{0, -5, -5, -5, -5},
+		{-5, 4, -5, -5, -5},
+		{-5, -5, 4, -5, -5},
+		{-5, -5, -5, 4, -5},
+		{-5, -5, -5, -5, 4},
+	}
+
+	for _, aligner := range []Aligner{SW(matrix), SWQuality(matrix)} {
+		aln, err := aligner.Align(qsa, qsb)
+		if err == nil {
+			fmt.Printf("%T: %v\n", aligner, aln)
+		}
+	}
+	// Output:
+	// align.SW: [[0,12)/[0,12)=39]
+	// align.SWQuality: [[0,12)/[0,12)=42]
+}
+
+func ExampleNWAffineQuality_Align() {
+	qsa := linear.NewQSeq("a", qletters("ATAGGAAG",
+		[]alphabet.Qphred{40, 40, 40, 40, 40, 40, 40, 40},
+	), alphabet.DNAgapped, alphabet.Sanger)
+	qsb := linear.NewQSeq("b", qletters("ATTGGCAATG",
+		[]alphabet.Qphred{40, 40, 3, 40, 40, 30, 40, 40, 40, 40},
+	), alphabet.DNAgapped, alphabet.Sanger)
+
+	//		   Query letter
+	//  	 -	 A	 C	 G	 T
+	// -	 0	-1	-1	-1	-1
+	// A	-1	 4	-4	-4	-4
+	// C	-1	-4	 4	-4	-4
+	// G	-1	-4	-4	 4	-4
+	// T	-1	-4	-4	-4	 4
+	//
+	// Gap open: -3
+	matrix := Affine{
+		Matrix: Linear{
+			{0, -1, -1, -1, -1},
+			{-1, 4, -4, -4, -4},
+			{-1, -4, 4, -4, -4},
+			{-1, -4, -4, 4, -4},
+			{-1, -4, -4, -4, 4},
+		},
+		GapOpen: -3,
+	}
+
+	for _, aligner := range []Aligner{NWAffine(matrix), NWAffineQuality(matrix)} {
+		aln, err := aligner.Align(qsa, qsb)
+		if err == nil {
+			fmt.Printf("%T: %v\n", aligner, aln)
+		}
+	}
+	// Output:
+	// align.NWAffine: [[0,5)/[0,5)=12 -/[5,6)=-4 [5,7)/[6,8)=8 -/[8,9)=-4 [7,8)/[9,10)=4]
+	// align.NWAffineQuality: [[0,5)/[0,5)=14 -/[5,6)=-4 [5,7)/[6,8)=8 -/[8,9)=-4 [7,8)/[9,10)=4]
+}
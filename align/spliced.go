@@ -0,0 +1,382 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/io/featio/bed"
+	"code.google.com/p/biogo/seq"
+
+	"bytes"
+	"fmt"
+)
+
+// A SpliceSignal describes an intron's donor and acceptor dinucleotides and the score
+// added to an intron bounded by them.
+type SpliceSignal struct {
+	Donor, Acceptor string
+	Bonus           int
+}
+
+// DefaultSpliceSignals are the canonical GT-AG and the minor GC-AG and AT-AC splice signals.
+var DefaultSpliceSignals = []SpliceSignal{
+	{Donor: "GT", Acceptor: "AG", Bonus: 20},
+	{Donor: "GC", Acceptor: "AG", Bonus: 10},
+	{Donor: "AT", Acceptor: "AC", Bonus: 5},
+}
+
+// minIntron is the smallest intron length allowed by Spliced.
+const minIntron = 4
+
+// Spliced is a spliced aligner for aligning transcript sequences such as cDNA or ESTs to
+// genomic reference sequence. The query is aligned in its entirety and the reference locally.
+// Introns are modelled as a separate state with a length-independent score; an intron is
+// scored Intron plus the Bonus of the SpliceSignal matching its terminal dinucleotides, if
+// any. Introns are only considered on the forward strand of the reference.
+//
+// Matrix is a square scoring matrix with the first column and first row specifying gap
+// penalties and GapOpen is added to the first position of a gap. MinIntron is the minimum
+// length of an intron; values less than 4 are treated as 4. If Signals is nil,
+// DefaultSpliceSignals is used.
+type Spliced struct {
+	Matrix    Linear
+	GapOpen   int
+	Intron    int
+	MinIntron int
+	Signals   []SpliceSignal
+}
+
+var _ Aligner = Spliced{}
+
+type intronPair struct {
+	featPair
+}
+
+func (ip *intronPair) String() string {
+	return fmt.Sprintf("%s[%d,%d)/~=%d", ip.a.Name(), ip.a.start, ip.a.end, ip.score)
+}
+
+// hasDinucleotide returns whether s[i:i+2] matches the dinucleotide d, ignoring case.
+func hasDinucleotide(s alphabet.Letters, i int, d string) bool {
+	if i < 0 || i+2 > len(s) || len(d) != 2 {
+		return false
+	}
+	return s[i]|' ' == alphabet.Letter(d[0])|' ' && s[i+1]|' ' == alphabet.Letter(d[1])|' '
+}
+
+// Align aligns a transcript query to a genomic reference. It returns an alignment description
+// that may be converted to a SplicedAlignment, or an error if the scoring matrix is not square,
+// or the sequence data types or alphabets do not match.
+func (a Spliced) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	la, err := flatten(a.Matrix)
+	if err != nil {
+		return nil, err
+	}
+	rIdx, qIdx, err := letterIndices(reference, query)
+	if err != nil {
+		return nil, err
+	}
+	rSeq, err := letters(reference.Slice())
+	if err != nil {
+		return nil, err
+	}
+	signals := a.Signals
+	if signals == nil {
+		signals = DefaultSpliceSignals
+	}
+	minLen := a.MinIntron
+	if minLen < minIntron {
+		minLen = minIntron
+	}
+
+	// Layers beyond left are intron layers, one for each splice signal
+	// and a final layer for non-canonical introns.
+	var (
+		let    = len(a.Matrix)
+		layers = left + 1 + len(signals) + 1
+		nonCan = layers - 1
+		r, c   = len(rIdx) + 1, len(qIdx) + 1
+		table  = make([]int, r*c*layers)
+	)
+	for i := range table {
+		table[i] = minInt
+	}
+
+	// donor and acceptor return the score contribution of the splice
+	// signal of intron layer l starting or ending at position i.
+	donor := func(l, i int) int {
+		if l == nonCan || hasDinucleotide(rSeq, i, signals[l-left-1].Donor) {
+			return 0
+		}
+		return minInt
+	}
+	acceptor := func(l, i int) int {
+		if l == nonCan {
+			return 0
+		}
+		if s := signals[l-left-1]; hasDinucleotide(rSeq, i-2, s.Acceptor) {
+			return s.Bonus
+		}
+		return minInt
+	}
+
+	for i := 0; i < r; i++ {
+		table[i*c*layers+diag] = 0
+		for j := 1; j < c; j++ {
+			p := (i*c + j) * layers
+			qVal := qIdx[j-1]
+			if qVal >= 0 {
+				table[p+left] = max2(
+					add(add(table[p-layers+diag], a.GapOpen), la[qVal]),
+					add(table[p-layers+left], la[qVal]),
+				)
+			}
+			if i == 0 {
+				continue
+			}
+			rVal := rIdx[i-1]
+			if rVal >= 0 {
+				u := p - c*layers
+				table[p+up] = max2(
+					add(add(table[u+diag], a.GapOpen), la[rVal*let]),
+					add(table[u+up], la[rVal*let]),
+				)
+			}
+			if rVal >= 0 && qVal >= 0 {
+				d := p - (c+1)*layers
+				score := max2(max2(table[d+diag], table[d+up]), table[d+left])
+				for l := left + 1; l < layers; l++ {
+					score = max2(score, add(table[d+l], acceptor(l, i-1)))
+				}
+				table[p+diag] = add(score, la[rVal*let+qVal])
+			}
+			for l := left + 1; l < layers; l++ {
+				score := table[p-c*layers+l]
+				if i >= minLen {
+					s := i - minLen
+					score = max2(score, add(add(table[(s*c+j)*layers+diag], a.Intron), donor(l, s)))
+				}
+				table[p+l] = score
+			}
+		}
+	}
+
+	maxS, maxI := minInt, 0
+	for i := 0; i < r; i++ {
+		p := (i*c + c - 1) * layers
+		if s := max2(table[p+diag], table[p+left]); s >= maxS {
+			maxS, maxI = s, i
+		}
+	}
+	if maxS == minInt {
+		return nil, fmt.Errorf("align: no spliced alignment found")
+	}
+
+	const intron = -1
+	var (
+		aln   []feat.Pair
+		score int
+		carry int
+		last  = diag
+		layer = diag
+		i, j  = maxI, c - 1
+		maxJ  = j
+	)
+	if table[(i*c+j)*layers+left] > table[(i*c+j)*layers+diag] {
+		layer, last = left, left
+	}
+	for j > 0 {
+		p := (i*c + j) * layers
+		v := table[p+layer]
+		var (
+			move      = layer
+			prev      = -1
+			prevLayer = -1
+			di, dj    int
+			contrib   int
+			bonus     int
+		)
+		switch layer {
+		case diag:
+			di, dj = 1, 1
+			prev = p - (c+1)*layers
+			contrib = la[rIdx[i-1]*let+qIdx[j-1]]
+			s := v - contrib
+			for l := diag; l <= left; l++ {
+				if table[prev+l] == s {
+					prevLayer = l
+					break
+				}
+			}
+			if prevLayer < 0 {
+				for l := left + 1; l < layers; l++ {
+					if add(table[prev+l], acceptor(l, i-1)) == s {
+						prevLayer, bonus = l, acceptor(l, i-1)
+						break
+					}
+				}
+			}
+		case up:
+			di = 1
+			prev = p - c*layers
+			g := la[rIdx[i-1]*let]
+			switch v {
+			case add(table[prev+up], g):
+				prevLayer = up
+			case add(add(table[prev+diag], a.GapOpen), g):
+				prevLayer = diag
+			}
+		case left:
+			dj = 1
+			prev = p - layers
+			g := la[qIdx[j-1]]
+			switch v {
+			case add(table[prev+left], g):
+				prevLayer = left
+			case add(add(table[prev+diag], a.GapOpen), g):
+				prevLayer = diag
+			}
+		default:
+			move = intron
+			if i > 0 && v == table[p-c*layers+layer] {
+				di, prev, prevLayer = 1, p-c*layers, layer
+			} else if s := i - minLen; s >= 0 {
+				di, prev, prevLayer = minLen, (s*c+j)*layers, diag
+			}
+		}
+		if prevLayer < 0 {
+			panic(fmt.Sprintf("align: spliced internal error: no path at row: %d col:%d layer:%d\n", i, j, layer))
+		}
+		if layer != diag {
+			contrib = v - table[prev+prevLayer]
+		}
+		if last != move && (i != maxI || j != maxJ) {
+			fp := featPair{
+				a:     feature{start: i, end: maxI},
+				b:     feature{start: j, end: maxJ},
+				score: score,
+			}
+			if last == intron {
+				aln = append(aln, &intronPair{fp})
+			} else {
+				aln = append(aln, &fp)
+			}
+			maxI, maxJ = i, j
+			score = 0
+		}
+		score += contrib + carry
+		carry = bonus
+		i -= di
+		j -= dj
+		last, layer = move, prevLayer
+	}
+	aln = append(aln, &featPair{
+		a:     feature{start: i, end: maxI},
+		b:     feature{start: j, end: maxJ},
+		score: score,
+	})
+
+	for i, j := 0, len(aln)-1; i < j; i, j = i+1, j-1 {
+		aln[i], aln[j] = aln[j], aln[i]
+	}
+
+	return aln, nil
+}
+
+// A SplicedAlignment is an alignment description returned by Spliced.Align.
+type SplicedAlignment []feat.Pair
+
+// IsIntron returns whether the ith feature pair of the alignment describes an intron.
+func (sa SplicedAlignment) IsIntron(i int) bool {
+	_, ok := sa[i].(*intronPair)
+	return ok
+}
+
+// Exons returns the reference start and end positions of the exon blocks of the alignment.
+func (sa SplicedAlignment) Exons() [][2]int {
+	var (
+		exons [][2]int
+		open  bool
+	)
+	for i, fp := range sa {
+		if sa.IsIntron(i) {
+			open = false
+			continue
+		}
+		f := fp.Features()[0]
+		if f.Len() == 0 && !open {
+			continue
+		}
+		if !open {
+			exons = append(exons, [2]int{f.Start(), f.End()})
+			open = true
+			continue
+		}
+		exons[len(exons)-1][1] = f.End()
+	}
+	return exons
+}
+
+// Bed12 returns a BED12 record describing the exon structure of the alignment on the plus
+// strand of chrom. The offset is added to all reference positions.
+func (sa SplicedAlignment) Bed12(chrom string, offset int) *bed.Bed12 {
+	exons := sa.Exons()
+	b := &bed.Bed12{
+		Chrom:      chrom,
+		FeatStrand: seq.Plus,
+		BlockCount: len(exons),
+	}
+	if len(exons) == 0 {
+		return b
+	}
+	b.ChromStart = exons[0][0] + offset
+	b.ChromEnd = exons[len(exons)-1][1] + offset
+	b.ThickStart, b.ThickEnd = b.ChromStart, b.ChromEnd
+	for _, e := range exons {
+		b.BlockSizes = append(b.BlockSizes, e[1]-e[0])
+		b.BlockStarts = append(b.BlockStarts, e[0]+offset-b.ChromStart)
+	}
+	return b
+}
+
+// Cigar returns a CIGAR string describing the alignment, with aligned positions reported as M,
+// query insertions as I, reference deletions as D and introns as N operations.
+func (sa SplicedAlignment) Cigar() string {
+	var (
+		buf  bytes.Buffer
+		op   byte
+		n    int
+		emit = func() {
+			if n > 0 {
+				fmt.Fprintf(&buf, "%d%c", n, op)
+			}
+		}
+	)
+	for i, fp := range sa {
+		f := fp.Features()
+		var (
+			o byte
+			l int
+		)
+		switch {
+		case sa.IsIntron(i):
+			o, l = 'N', f[0].Len()
+		case f[0].Len() == 0:
+			o, l = 'I', f[1].Len()
+		case f[1].Len() == 0:
+			o, l = 'D', f[0].Len()
+		default:
+			o, l = 'M', f[0].Len()
+		}
+		if o != op {
+			emit()
+			op, n = o, 0
+		}
+		n += l
+	}
+	emit()
+	return buf.String()
+}
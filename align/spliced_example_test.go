@@ -0,0 +1,51 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+)
+
+func ExampleSpliced_Align() {
+	genome := &linear.Seq{Seq: alphabet.BytesToLetters([]byte(
+		"ccttacgcatt" +
+			"ATGGCTAGCTTAGCA" + "gtaagtcatcgatcgattcgacttag" + "GCATCGGATCCAT" +
+			"tacgcgcgta",
+	))}
+	genome.Alpha = alphabet.DNAgapped
+	cdna := &linear.Seq{Seq: alphabet.BytesToLetters([]byte("ATGGCTAGCTTAGCAGCATCGGATCCAT"))}
+	cdna.Alpha = alphabet.DNAgapped
+
+	splicer := Spliced{
+		Matrix: Linear{
+			{0, -1, -1, -1, -1},
+			{-1, 2, -2, -2, -2},
+			{-1, -2, 2, -2, -2},
+			{-1, -2, -2, 2, -2},
+			{-1, -2, -2, -2, 2},
+		},
+		GapOpen:   -4,
+		Intron:    -30,
+		MinIntron: 20,
+	}
+
+	aln, err := splicer.Align(genome, cdna)
+	if err == nil {
+		sa := SplicedAlignment(aln)
+		fmt.Printf("%v\n", aln)
+		fmt.Printf("%v\n", sa.Exons())
+		fmt.Println(sa.Cigar())
+		b := sa.Bed12("chr1", 1000)
+		fmt.Println(b.ChromStart, b.ChromEnd, b.BlockCount, b.BlockSizes, b.BlockStarts)
+	}
+	// Output:
+	// [[11,26)/[0,15)=30 [26,52)/~=-10 [52,65)/[15,28)=26]
+	// [[11 26] [52 65]]
+	// 15M26N13M
+	// 1011 1065 2 [15 13] [0 41]
+}
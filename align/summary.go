@@ -0,0 +1,260 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/feat"
+
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// A Summary holds summary statistics for a pairwise alignment.
+type Summary struct {
+	// Length is the number of alignment columns.
+	Length int
+
+	// Identities is the number of aligned pairs of identical letters and Similarities
+	// is the number of aligned pairs with a positive score, including identities.
+	Identities   int
+	Similarities int
+
+	// Gaps is the number of gap columns and GapOpens is the number of runs of gap columns.
+	Gaps     int
+	GapOpens int
+
+	// Score is the alignment score calculated from the scoring scheme.
+	Score int
+
+	// RefCoverage and QueryCoverage are the fractions of the reference and query
+	// sequences spanned by the alignment.
+	RefCoverage   float64
+	QueryCoverage float64
+}
+
+// Identity returns the fraction of alignment columns holding identical letters.
+func (s Summary) Identity() float64 { return fraction(s.Identities, s.Length) }
+
+// Similarity returns the fraction of alignment columns holding positive scoring letter pairs.
+func (s Summary) Similarity() float64 { return fraction(s.Similarities, s.Length) }
+
+// GapFraction returns the fraction of alignment columns holding gaps.
+func (s Summary) GapFraction() float64 { return fraction(s.Gaps, s.Length) }
+
+func fraction(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// String returns an EMBOSS-style description of the summary.
+func (s Summary) String() string {
+	return fmt.Sprintf("Length: %d Identity: %d/%d (%.1f%%) Similarity: %d/%d (%.1f%%) Gaps: %d/%d (%.1f%%) Score: %d",
+		s.Length,
+		s.Identities, s.Length, 100*s.Identity(),
+		s.Similarities, s.Length, 100*s.Similarity(),
+		s.Gaps, s.Length, 100*s.GapFraction(),
+		s.Score,
+	)
+}
+
+// Summarise returns summary statistics for the alignment aln of query to reference, scored
+// using the receiver. An error is returned if the scoring matrix is not square, or the sequence
+// data types or alphabets do not match.
+func (m Linear) Summarise(reference, query AlphabetSlicer, aln []feat.Pair) (Summary, error) {
+	return Affine{Matrix: m}.Summarise(reference, query, aln)
+}
+
+// Summarise returns summary statistics for the alignment aln of query to reference, scored
+// using the receiver. An error is returned if the scoring matrix is not square, or the sequence
+// data types or alphabets do not match.
+func (a Affine) Summarise(reference, query AlphabetSlicer, aln []feat.Pair) (Summary, error) {
+	la, err := flatten(a.Matrix)
+	if err != nil {
+		return Summary{}, err
+	}
+	rIdx, qIdx, err := letterIndices(reference, query)
+	if err != nil {
+		return Summary{}, err
+	}
+	let := len(a.Matrix)
+
+	var s Summary
+	if len(aln) == 0 {
+		return s, nil
+	}
+	for _, fp := range aln {
+		f := fp.Features()
+		fr, fq := f[0], f[1]
+		switch {
+		case fr.Len() == 0:
+			s.Gaps += fq.Len()
+			s.GapOpens++
+			s.Score += a.GapOpen
+			for j := fq.Start(); j < fq.End(); j++ {
+				if qVal := qIdx[j]; qVal >= 0 {
+					s.Score += la[qVal]
+				}
+			}
+			s.Length += fq.Len()
+		case fq.Len() == 0:
+			s.Gaps += fr.Len()
+			s.GapOpens++
+			s.Score += a.GapOpen
+			for i := fr.Start(); i < fr.End(); i++ {
+				if rVal := rIdx[i]; rVal >= 0 {
+					s.Score += la[rVal*let]
+				}
+			}
+			s.Length += fr.Len()
+		default:
+			for k := 0; k < fr.Len(); k++ {
+				rVal, qVal := rIdx[fr.Start()+k], qIdx[fq.Start()+k]
+				if rVal < 0 || qVal < 0 {
+					continue
+				}
+				v := la[rVal*let+qVal]
+				s.Score += v
+				if rVal == qVal {
+					s.Identities++
+				}
+				if v > 0 {
+					s.Similarities++
+				}
+			}
+			s.Length += fr.Len()
+		}
+	}
+
+	first, last := aln[0].Features(), aln[len(aln)-1].Features()
+	s.RefCoverage = fraction(last[0].End()-first[0].Start(), len(rIdx))
+	s.QueryCoverage = fraction(last[1].End()-first[1].Start(), len(qIdx))
+
+	return s, nil
+}
+
+// A BlockFormatter writes pairwise alignments as blocks of aligned rows in the style of the
+// EMBOSS pair format. Each block holds the reference row, a match line and the query row, with
+// sequence coordinates given as one-based positions. In the match line '|' indicates identity,
+// ':' indicates a positive scoring pair under Matrix, '.' indicates other aligned pairs and ' '
+// indicates a gap. If Matrix is nil only identities are marked. Width is the number of alignment
+// columns in each block; if Width is less than one, 50 columns are used.
+type BlockFormatter struct {
+	Matrix Linear
+	Width  int
+}
+
+type namer interface {
+	Name() string
+}
+
+// Format writes the alignment aln of query to reference to w.
+func (bf BlockFormatter) Format(w io.Writer, reference, query AlphabetSlicer, aln []feat.Pair) error {
+	rIdx, qIdx, err := letterIndices(reference, query)
+	if err != nil {
+		return err
+	}
+	rSeq, err := letters(reference.Slice())
+	if err != nil {
+		return err
+	}
+	qSeq, err := letters(query.Slice())
+	if err != nil {
+		return err
+	}
+	var la []int
+	if bf.Matrix != nil {
+		la, err = flatten(bf.Matrix)
+		if err != nil {
+			return err
+		}
+	}
+	let := len(bf.Matrix)
+	width := bf.Width
+	if width < 1 {
+		width = 50
+	}
+
+	gap := reference.Alphabet().Gap()
+	var (
+		rRow, qRow, mRow []byte
+		rPos, qPos       []int // sequence positions following each column
+	)
+	for _, fp := range aln {
+		f := fp.Features()
+		fr, fq := f[0], f[1]
+		n := fr.Len()
+		if fq.Len() > n {
+			n = fq.Len()
+		}
+		for k := 0; k < n; k++ {
+			var (
+				rl, ql = gap, gap
+				m      = byte(' ')
+			)
+			if fr.Len() != 0 {
+				rl = rSeq[fr.Start()+k]
+				rPos = append(rPos, fr.Start()+k+1)
+			} else {
+				rPos = append(rPos, fr.Start())
+			}
+			if fq.Len() != 0 {
+				ql = qSeq[fq.Start()+k]
+				qPos = append(qPos, fq.Start()+k+1)
+			} else {
+				qPos = append(qPos, fq.Start())
+			}
+			if fr.Len() != 0 && fq.Len() != 0 {
+				rVal, qVal := rIdx[fr.Start()+k], qIdx[fq.Start()+k]
+				switch {
+				case rVal >= 0 && rVal == qVal:
+					m = '|'
+				case la != nil && rVal >= 0 && qVal >= 0 && la[rVal*let+qVal] > 0:
+					m = ':'
+				default:
+					m = '.'
+				}
+			}
+			rRow = append(rRow, byte(rl))
+			qRow = append(qRow, byte(ql))
+			mRow = append(mRow, m)
+		}
+	}
+
+	rName, qName := "reference", "query"
+	if n, ok := reference.(namer); ok && n.Name() != "" {
+		rName = n.Name()
+	}
+	if n, ok := query.(namer); ok && n.Name() != "" {
+		qName = n.Name()
+	}
+	nameWidth := len(rName)
+	if len(qName) > nameWidth {
+		nameWidth = len(qName)
+	}
+
+	bw := bufio.NewWriter(w)
+	for s := 0; s < len(rRow); s += width {
+		e := s + width
+		if e > len(rRow) {
+			e = len(rRow)
+		}
+		if s != 0 {
+			fmt.Fprintln(bw)
+		}
+		start := func(pos []int, i int) int {
+			if s == 0 {
+				return aln[0].Features()[i].Start() + 1
+			}
+			return pos[s-1] + 1
+		}
+		fmt.Fprintf(bw, "%-*s %8d %s %8d\n", nameWidth, rName, start(rPos, 0), rRow[s:e], rPos[e-1])
+		fmt.Fprintf(bw, "%-*s %8s %s\n", nameWidth, "", "", mRow[s:e])
+		fmt.Fprintf(bw, "%-*s %8d %s %8d\n", nameWidth, qName, start(qPos, 1), qRow[s:e], qPos[e-1])
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,67 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+	"os"
+)
+
+func ExampleAffine_Summarise() {
+	ref := linear.NewSeq("ref", alphabet.BytesToLetters([]byte("GACCTAGCATGACTAGCTAGGCTAGCAGTCA")), alphabet.DNAgapped)
+	qry := linear.NewSeq("qry", alphabet.BytesToLetters([]byte("GACCTAGCATGATTTCTAGCTTGGCTAGCA")), alphabet.DNAgapped)
+
+	// w(gap) = -1
+	// w(match) = +2
+	// w(mismatch) = -3
+	//
+	// w(open) = -4
+	a := Affine{
+		Matrix: Linear{
+			{0, -1, -1, -1, -1},
+			{-1, 2, -3, -3, -3},
+			{-1, -3, 2, -3, -3},
+			{-1, -3, -3, 2, -3},
+			{-1, -3, -3, -3, 2},
+		},
+		GapOpen: -4,
+	}
+
+	aln, err := SWAffine(a).Align(ref, qry)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%v\n", aln)
+
+	s, err := a.Summarise(ref, qry, aln)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(s)
+	fmt.Printf("Gap opens: %d Coverage: %.2f/%.2f\n\n", s.GapOpens, s.RefCoverage, s.QueryCoverage)
+
+	err = BlockFormatter{Matrix: a.Matrix, Width: 20}.Format(os.Stdout, ref, qry, aln)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	// Output:
+	// [[0,12)/[0,12)=24 -/[12,15)=-7 [12,27)/[15,30)=25]
+	// Length: 30 Identity: 26/30 (86.7%) Similarity: 26/30 (86.7%) Gaps: 3/30 (10.0%) Score: 42
+	// Gap opens: 1 Coverage: 0.87/1.00
+	//
+	// ref        1 GACCTAGCATGA---CTAGC       17
+	//              ||||||||||||   |||||
+	// qry        1 GACCTAGCATGATTTCTAGC       20
+	//
+	// ref       18 TAGGCTAGCA       27
+	//              |.||||||||
+	// qry       21 TTGGCTAGCA       30
+}
@@ -106,3 +106,89 @@ func ExampleSWAffine_Align() {
 	// ATAGGAA
 	// ATTGGCA
 }
+
+func ExampleSW_AlignAll() {
+	swsa := &linear.Seq{Seq: alphabet.BytesToLetters([]byte("GGACGTACGTTTTTTACGTACGGGG"))}
+	swsa.Alpha = alphabet.DNAgapped
+	swsb := &linear.Seq{Seq: alphabet.BytesToLetters([]byte("ACGTACG"))}
+	swsb.Alpha = alphabet.DNAgapped
+
+	// w(gap) = -2
+	// w(match) = +2
+	// w(mismatch) = -1
+	smith := SW{
+		{0, -2, -2, -2, -2},
+		{-2, 2, -1, -1, -1},
+		{-2, -1, 2, -1, -1},
+		{-2, -1, -1, 2, -1},
+		{-2, -1, -1, -1, 2},
+	}
+
+	alns, err := smith.AlignAll(swsa, swsb, 8, 0)
+	if err == nil {
+		for _, aln := range alns {
+			fmt.Printf("%v\n", aln)
+			fa := Format(swsa, swsb, aln, '-')
+			fmt.Printf("%s\n%s\n", fa[0], fa[1])
+		}
+	}
+	// Output:
+	// [[15,22)/[0,7)=14]
+	// ACGTACG
+	// ACGTACG
+	// [[2,9)/[0,7)=14]
+	// ACGTACG
+	// ACGTACG
+	// [[14,18)/[3,7)=8]
+	// TACG
+	// TACG
+	// [[6,10)/[0,4)=8]
+	// ACGT
+	// ACGT
+}
+
+func ExampleSWAffine_AlignAll() {
+	swsa := &linear.Seq{Seq: alphabet.BytesToLetters([]byte("GGACGTACGTTTTTTACGTTACGGGG"))}
+	swsa.Alpha = alphabet.DNAgapped
+	swsb := &linear.Seq{Seq: alphabet.BytesToLetters([]byte("ACGTACG"))}
+	swsb.Alpha = alphabet.DNAgapped
+
+	//		   Query letter
+	//  	 -	 A	 C	 G	 T
+	// -	 0	-1	-1	-1	-1
+	// A	-1	 2	-1	-1	-1
+	// C	-1	-1	 2	-1	-1
+	// G	-1	-1	-1	 2	-1
+	// T	-1	-1	-1	-1	 2
+	//
+	// Gap open: -2
+	smith := SWAffine{
+		Matrix: Linear{
+			{0, -1, -1, -1, -1},
+			{-1, 2, -1, -1, -1},
+			{-1, -1, 2, -1, -1},
+			{-1, -1, -1, 2, -1},
+			{-1, -1, -1, -1, 2},
+		},
+		GapOpen: -2,
+	}
+
+	alns, err := smith.AlignAll(swsa, swsb, 6, 3)
+	if err == nil {
+		for _, aln := range alns {
+			fmt.Printf("%v\n", aln)
+			fa := Format(swsa, swsb, aln, '-')
+			fmt.Printf("%s\n%s\n", fa[0], fa[1])
+		}
+	}
+	// Output:
+	// [[2,9)/[0,7)=14]
+	// ACGTACG
+	// ACGTACG
+	// [[15,18)/[0,3)=6 [18,19)/-=-3 [19,23)/[3,7)=8]
+	// ACGTTACG
+	// ACG-TACG
+	// [[14,18)/[3,7)=8]
+	// TACG
+	// TACG
+}
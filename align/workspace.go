@@ -0,0 +1,104 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/feat"
+)
+
+// A Workspace holds dynamic programming buffers that are reused between alignments by the
+// SW, NW and Fitted aligners and their affine and quality-aware variants. The zero value is
+// ready to use. A Workspace must not be used by more than one alignment at a time and the
+// buffers it holds grow to the size of the largest alignment performed with it.
+type Workspace struct {
+	la     []int
+	table  []int
+	atable [][3]int
+}
+
+// scores returns an empty slice with capacity for at least n scoring matrix elements.
+// If ws is nil a new slice is allocated.
+func (ws *Workspace) scores(n int) []int {
+	if ws == nil {
+		return make([]int, 0, n)
+	}
+	if cap(ws.la) < n {
+		ws.la = make([]int, 0, n)
+	}
+	return ws.la[:0]
+}
+
+// linear returns a zeroed linear gap dynamic programming table of n cells. If ws is nil
+// a new table is allocated.
+func (ws *Workspace) linear(n int) []int {
+	if ws == nil {
+		return make([]int, n)
+	}
+	if cap(ws.table) < n {
+		ws.table = make([]int, n)
+		return ws.table
+	}
+	t := ws.table[:n]
+	for i := range t {
+		t[i] = 0
+	}
+	return t
+}
+
+// affine returns a zeroed affine gap dynamic programming table of n cells. If ws is nil
+// a new table is allocated.
+func (ws *Workspace) affine(n int) [][3]int {
+	if ws == nil {
+		return make([][3]int, n)
+	}
+	if cap(ws.atable) < n {
+		ws.atable = make([][3]int, n)
+		return ws.atable
+	}
+	t := ws.atable[:n]
+	for i := range t {
+		t[i] = [3]int{}
+	}
+	return t
+}
+
+// A WorkspaceAligner is an Aligner that can perform alignments using a provided Workspace.
+type WorkspaceAligner interface {
+	Aligner
+	AlignWith(ws *Workspace, reference, query AlphabetSlicer) ([]feat.Pair, error)
+}
+
+var (
+	_ WorkspaceAligner = SW{}
+	_ WorkspaceAligner = SWAffine{}
+	_ WorkspaceAligner = NW{}
+	_ WorkspaceAligner = NWAffine{}
+	_ WorkspaceAligner = Fitted{}
+	_ WorkspaceAligner = FittedAffine{}
+	_ WorkspaceAligner = SWQuality{}
+	_ WorkspaceAligner = SWAffineQuality{}
+	_ WorkspaceAligner = NWQuality{}
+	_ WorkspaceAligner = NWAffineQuality{}
+	_ WorkspaceAligner = FittedQuality{}
+	_ WorkspaceAligner = FittedAffineQuality{}
+)
+
+// WithWorkspace returns an Aligner that performs alignments with a using the workspace ws.
+// If ws is nil a new Workspace is used. The returned Aligner must not be used concurrently.
+func WithWorkspace(a WorkspaceAligner, ws *Workspace) Aligner {
+	if ws == nil {
+		ws = &Workspace{}
+	}
+	return workspaceAligner{a: a, ws: ws}
+}
+
+type workspaceAligner struct {
+	a  WorkspaceAligner
+	ws *Workspace
+}
+
+func (w workspaceAligner) Align(reference, query AlphabetSlicer) ([]feat.Pair, error) {
+	return w.a.AlignWith(w.ws, reference, query)
+}
@@ -0,0 +1,365 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/seq/linear"
+
+	"errors"
+	"fmt"
+)
+
+// XDrop is a seed extension aligner in the manner of BLAST. Seed hits are extended in both
+// directions until the alignment score falls more than Drop below the best score seen.
+// The Affine Matrix is a square scoring matrix with the first column and first row
+// specifying gap penalties and GapOpen is added to the first position of a gap.
+type XDrop struct {
+	Affine
+	Drop int
+}
+
+// seedFor checks the sequences and seed for an extension and returns the flattened scoring
+// matrix and the alphabet letter index.
+func (a XDrop) seedFor(reference, query *linear.Seq, i, j, k int) ([]int, *[256]int, error) {
+	la, err := flatten(a.Matrix)
+	if err != nil {
+		return nil, nil, err
+	}
+	alpha := reference.Alpha
+	if alpha == nil {
+		return nil, nil, ErrNoAlphabet
+	}
+	if alpha != query.Alpha {
+		return nil, nil, ErrMismatchedAlphabets
+	}
+	if alpha.IndexOf(alpha.Gap()) != 0 {
+		return nil, nil, ErrNotGappedAlphabet
+	}
+	if k < 0 || i < 0 || j < 0 || i+k > len(reference.Seq) || j+k > len(query.Seq) {
+		return nil, nil, errors.New("align: seed out of range")
+	}
+	return la, alpha.LetterIndex(), nil
+}
+
+// scorer returns a function returning the score of aligning reference position x with query
+// position y, or minInt if either letter is not in the alphabet.
+func scorer(reference, query *linear.Seq, la []int, let int, index *[256]int) func(x, y int) int {
+	return func(x, y int) int {
+		rVal, qVal := index[reference.Seq[x]], index[query.Seq[y]]
+		if rVal < 0 || qVal < 0 {
+			return minInt
+		}
+		return la[rVal*let+qVal]
+	}
+}
+
+// Ungapped extends the seed of length k at reference position i and query position j without
+// gaps. It returns the extended block and its score. The seed letters are included in the block
+// regardless of their score. The reference and query sequence data are used without copying.
+func (a XDrop) Ungapped(reference, query *linear.Seq, i, j, k int) (feat.Pair, int, error) {
+	la, index, err := a.seedFor(reference, query, i, j, k)
+	if err != nil {
+		return nil, 0, err
+	}
+	score := scorer(reference, query, la, len(a.Matrix), index)
+
+	var s int
+	for o := 0; o < k; o++ {
+		s = add(s, score(i+o, j+o))
+	}
+	if s == minInt {
+		return nil, 0, errors.New("align: seed contains invalid letter")
+	}
+
+	// extend returns the length of the best extension in direction
+	// dir from the reference and query positions x and y.
+	extend := func(x, y, dir int) (n, best int) {
+		var cur int
+		for o := 1; ; o++ {
+			x, y = x+dir, y+dir
+			if x < 0 || y < 0 || x >= len(reference.Seq) || y >= len(query.Seq) {
+				break
+			}
+			v := score(x, y)
+			if v == minInt {
+				break
+			}
+			cur += v
+			if cur > best {
+				n, best = o, cur
+			}
+			if cur < best-a.Drop {
+				break
+			}
+		}
+		return n, best
+	}
+	ln, ls := extend(i, j, -1)
+	rn, rs := extend(i+k-1, j+k-1, 1)
+
+	s += ls + rs
+	return &featPair{
+		a:     feature{start: i - ln, end: i + k + rn},
+		b:     feature{start: j - ln, end: j + k + rn},
+		score: s,
+	}, s, nil
+}
+
+// Gapped extends the seed of length k at reference position i and query position j using
+// affine gapped X-drop dynamic programming. It returns the alignment description and its
+// total score. The seed letters are aligned without gaps. The reference and query sequence
+// data are used without copying.
+func (a XDrop) Gapped(reference, query *linear.Seq, i, j, k int) ([]feat.Pair, int, error) {
+	la, index, err := a.seedFor(reference, query, i, j, k)
+	if err != nil {
+		return nil, 0, err
+	}
+	let := len(a.Matrix)
+	score := scorer(reference, query, la, let, index)
+
+	var s int
+	for o := 0; o < k; o++ {
+		s = add(s, score(i+o, j+o))
+	}
+	if s == minInt {
+		return nil, 0, errors.New("align: seed contains invalid letter")
+	}
+
+	rGap := func(x int) int {
+		if v := index[reference.Seq[x]]; v >= 0 {
+			return la[v*let]
+		}
+		return minInt
+	}
+	qGap := func(y int) int {
+		if v := index[query.Seq[y]]; v >= 0 {
+			return la[v]
+		}
+		return minInt
+	}
+
+	left, ls := a.extend(i, j,
+		func(x, y int) int { return score(i-1-x, j-1-y) },
+		func(x int) int { return rGap(i - 1 - x) },
+		func(y int) int { return qGap(j - 1 - y) },
+	)
+	right, rs := a.extend(len(reference.Seq)-i-k, len(query.Seq)-j-k,
+		func(x, y int) int { return score(i+k+x, j+k+y) },
+		func(x int) int { return rGap(i + k + x) },
+		func(y int) int { return qGap(j + k + y) },
+	)
+
+	var aln []feat.Pair
+	for n := len(left) - 1; n >= 0; n-- {
+		fp := left[n]
+		fp.a.start, fp.a.end = i-fp.a.end, i-fp.a.start
+		fp.b.start, fp.b.end = j-fp.b.end, j-fp.b.start
+		aln = appendBlock(aln, fp)
+	}
+	aln = appendBlock(aln, &featPair{
+		a:     feature{start: i, end: i + k},
+		b:     feature{start: j, end: j + k},
+		score: s,
+	})
+	for _, fp := range right {
+		fp.a.start, fp.a.end = fp.a.start+i+k, fp.a.end+i+k
+		fp.b.start, fp.b.end = fp.b.start+j+k, fp.b.end+j+k
+		aln = appendBlock(aln, fp)
+	}
+
+	return aln, s + ls + rs, nil
+}
+
+// appendBlock appends fp to aln, merging it with the last element of aln if both describe
+// ungapped aligned blocks that abut.
+func appendBlock(aln []feat.Pair, fp *featPair) []feat.Pair {
+	if fp.a.start == fp.a.end && fp.b.start == fp.b.end {
+		return aln
+	}
+	if len(aln) != 0 {
+		last := aln[len(aln)-1].(*featPair)
+		if last.a.end == fp.a.start && last.b.end == fp.b.start &&
+			last.a.Len() == last.b.Len() && fp.a.Len() == fp.b.Len() {
+			last.a.end, last.b.end = fp.a.end, fp.b.end
+			last.score += fp.score
+			return aln
+		}
+	}
+	return append(aln, fp)
+}
+
+// xdropRow is a row of the X-drop dynamic programming table holding the cells in
+// columns [lo, lo+len(cells)).
+type xdropRow struct {
+	lo    int
+	cells [][3]int
+}
+
+func (r xdropRow) at(j int) [3]int {
+	if j < r.lo || j >= r.lo+len(r.cells) {
+		return [3]int{minInt, minInt, minInt}
+	}
+	return r.cells[j-r.lo]
+}
+
+// extend performs an anchored affine X-drop extension over up to rLen reference and qLen query
+// positions, returning the alignment description of the best scoring extension, in extension
+// coordinates, and its score. The match function returns the score of aligning the xth
+// reference position with the yth query position, and rGap and qGap return the gap penalties
+// for the xth reference and yth query positions.
+func (a XDrop) extend(rLen, qLen int, match func(x, y int) int, rGap, qGap func(int) int) ([]*featPair, int) {
+	var (
+		rows []xdropRow
+
+		best, bestI, bestJ int
+	)
+	drop := func(v int) bool { return v == minInt || v < best-a.Drop }
+
+	// The first row holds the origin and leading query gaps.
+	first := xdropRow{cells: [][3]int{{diag: 0, up: minInt, left: minInt}}}
+	for j := 1; j <= qLen; j++ {
+		prev := first.cells[j-1]
+		v := max2(add(add(prev[diag], a.GapOpen), qGap(j-1)), add(prev[left], qGap(j-1)))
+		if drop(v) {
+			break
+		}
+		first.cells = append(first.cells, [3]int{diag: minInt, up: minInt, left: v})
+	}
+	rows = append(rows, first)
+
+	for i := 1; i <= rLen; i++ {
+		prev := rows[i-1]
+		row := xdropRow{lo: -1}
+		for j := prev.lo; j <= qLen; j++ {
+			var cell [3]int
+			d, u := prev.at(j-1), prev.at(j)
+			if j > 0 {
+				cell[diag] = add(max(&d), match(i-1, j-1))
+			} else {
+				cell[diag] = minInt
+			}
+			cell[up] = max2(add(add(u[diag], a.GapOpen), rGap(i-1)), add(u[up], rGap(i-1)))
+			cell[left] = minInt
+			if row.lo >= 0 && j > 0 {
+				l := row.cells[len(row.cells)-1]
+				cell[left] = max2(add(add(l[diag], a.GapOpen), qGap(j-1)), add(l[left], qGap(j-1)))
+			}
+			for l := range cell {
+				if drop(cell[l]) {
+					cell[l] = minInt
+				}
+			}
+			if cell == [3]int{minInt, minInt, minInt} {
+				if row.lo < 0 {
+					continue
+				}
+				if j >= prev.lo+len(prev.cells) {
+					break
+				}
+			}
+			if row.lo < 0 {
+				row.lo = j
+			}
+			row.cells = append(row.cells, cell)
+			if cell[diag] > best {
+				best, bestI, bestJ = cell[diag], i, j
+			}
+		}
+		// Trim trailing dropped cells.
+		for len(row.cells) != 0 && row.cells[len(row.cells)-1] == [3]int{minInt, minInt, minInt} {
+			row.cells = row.cells[:len(row.cells)-1]
+		}
+		if len(row.cells) == 0 {
+			break
+		}
+		rows = append(rows, row)
+	}
+
+	if bestI == 0 && bestJ == 0 {
+		return nil, 0
+	}
+
+	var (
+		aln   []*featPair
+		score int
+		layer = diag
+		last  = diag
+		i, j  = bestI, bestJ
+		maxI  = i
+		maxJ  = j
+	)
+	for i > 0 || j > 0 {
+		v := rows[i].at(j)[layer]
+		var (
+			move      = layer
+			prev      [3]int
+			prevLayer = -1
+		)
+		switch layer {
+		case diag:
+			prev = rows[i-1].at(j - 1)
+			s := v - match(i-1, j-1)
+			for l, ps := range prev {
+				if ps == s {
+					prevLayer = l
+					break
+				}
+			}
+		case up:
+			prev = rows[i-1].at(j)
+			g := rGap(i - 1)
+			switch v {
+			case add(prev[up], g):
+				prevLayer = up
+			case add(add(prev[diag], a.GapOpen), g):
+				prevLayer = diag
+			}
+		case left:
+			prev = rows[i].at(j - 1)
+			g := qGap(j - 1)
+			switch v {
+			case add(prev[left], g):
+				prevLayer = left
+			case add(add(prev[diag], a.GapOpen), g):
+				prevLayer = diag
+			}
+		}
+		if prevLayer < 0 {
+			panic(fmt.Sprintf("align: xdrop internal error: no path at row: %d col:%d layer:%s\n", i, j, "mul"[layer:layer+1]))
+		}
+		if last != move && (i != maxI || j != maxJ) {
+			aln = append(aln, &featPair{
+				a:     feature{start: i, end: maxI},
+				b:     feature{start: j, end: maxJ},
+				score: score,
+			})
+			maxI, maxJ = i, j
+			score = 0
+		}
+		score += v - prev[prevLayer]
+		switch move {
+		case diag:
+			i--
+			j--
+		case up:
+			i--
+		case left:
+			j--
+		}
+		last, layer = move, prevLayer
+	}
+	aln = append(aln, &featPair{
+		a:     feature{start: i, end: maxI},
+		b:     feature{start: j, end: maxJ},
+		score: score,
+	})
+
+	for i, j := 0, len(aln)-1; i < j; i, j = i+1, j-1 {
+		aln[i], aln[j] = aln[j], aln[i]
+	}
+
+	return aln, best
+}
@@ -0,0 +1,59 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package align
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+)
+
+func ExampleXDrop_Gapped() {
+	ref := linear.NewSeq("ref", alphabet.BytesToLetters([]byte("TTTTTTTTGACCTAGCATGACTAGCTAGGCTAGCAGGGGGGGG")), alphabet.DNAgapped)
+	qry := linear.NewSeq("qry", alphabet.BytesToLetters([]byte("CCCCGACCTAGCATGATTTCTAGCTAGGCTAGCACCCC")), alphabet.DNAgapped)
+
+	// w(gap) = -1
+	// w(match) = +2
+	// w(mismatch) = -3
+	//
+	// w(open) = -4
+	xd := XDrop{
+		Affine: Affine{
+			Matrix: Linear{
+				{0, -1, -1, -1, -1},
+				{-1, 2, -3, -3, -3},
+				{-1, -3, 2, -3, -3},
+				{-1, -3, -3, 2, -3},
+				{-1, -3, -3, -3, 2},
+			},
+			GapOpen: -4,
+		},
+		Drop: 10,
+	}
+
+	// The seed is the 8-mer CTAGCATG at ref[11:19] and qry[7:15].
+	block, score, err := xd.Ungapped(ref, qry, 11, 7, 8)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%v %d\n", block, score)
+
+	aln, score, err := xd.Gapped(ref, qry, 11, 7, 8)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%v %d\n", aln, score)
+	fa := Format(ref, qry, aln, '-')
+	fmt.Printf("%s\n%s\n", fa[0], fa[1])
+
+	// Output:
+	// [8,20)/[4,16)=24 24
+	// [[8,20)/[4,16)=24 -/[16,19)=-7 [20,35)/[19,34)=30] 47
+	// GACCTAGCATGA---CTAGCTAGGCTAGCA
+	// GACCTAGCATGATTTCTAGCTAGGCTAGCA
+}
@@ -2,45 +2,86 @@ package bwt
 
 import (
 	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/index/succinct"
 	"code.google.com/p/biogo/seq/linear"
-	"github.com/robsyme/wavelettree"
 )
 
+// occurrences is an occurrence table of a BWT.
+type occurrences interface {
+	// Rank returns the number of occurrences of c
+	// in the BWT before position i.
+	Rank(c byte, i int) int
+}
+
+// symbolVectors is an occurrence table holding a rank bit vector for each
+// symbol present in the BWT.
+type symbolVectors [256]*succinct.BitVector
+
+func newSymbolVectors(bwt []byte) *symbolVectors {
+	var v symbolVectors
+	for i, c := range bwt {
+		if v[c] == nil {
+			v[c] = succinct.NewBitVector(len(bwt))
+		}
+		v[c].Set(i)
+	}
+	for _, bv := range v {
+		if bv != nil {
+			bv.Build()
+		}
+	}
+	return &v
+}
+
+func (v *symbolVectors) Rank(c byte, i int) int {
+	if v[c] == nil {
+		return 0
+	}
+	return v[c].Rank1(i)
+}
+
+// Index is a BWT index whose occurrence table holds a rank bit vector for each
+// symbol in the text.
 type Index struct {
 	alphabet alphabet.Alphabet
 	BWT      []byte
 	sa       []int
 	c        [256]int
-	wtree    *wavelettree.WaveletTree
+	occ      occurrences
 }
 
+// IndexFaster is a BWT index whose occurrence table is a wavelet matrix over the BWT.
 type IndexFaster struct {
-	alphabet alphabet.Alphabet
-	BWT      []byte
-	sa       []int
-	c        [256]uint
-	wtree    *wavelettree.WaveletTree
+	Index
 }
 
 func New(seq *linear.Seq) *Index {
-	data := append(seq.Seq, 0)
+	index := newIndex(seq)
+	index.occ = newSymbolVectors(index.BWT)
+	return index
+}
+
+func NewWithWaveletTree(seq *linear.Seq) *IndexFaster {
+	index := newIndex(seq)
+	index.occ = succinct.NewWaveletMatrix(index.BWT)
+	return &IndexFaster{*index}
+}
+
+// newIndex returns an Index of seq without an occurrence table.
+func newIndex(seq *linear.Seq) *Index {
+	data := make(alphabet.Letters, len(seq.Seq)+1)
+	copy(data, seq.Seq)
 	length := len(data)
 	suffixArray := generateSuffixArray(data)
-	tree := wavelettree.New(alphabet.LettersToBytes(data))
 	index := Index{
 		sa:       suffixArray,
 		alphabet: seq.Alphabet(),
-		wtree:    tree,
 	}
 
 	index.BWT = make([]byte, length)
 	for i := 0; i < length; i++ {
 		dataIndex := (suffixArray[i] - 1 + length) % length
-<<<<<<< HEAD
-		index.BWT[i] = data[dataIndex]
-=======
-		index.BWT[i] = byte(seq.Seq[dataIndex])
->>>>>>> 8c03a7ded2ff0b2901294810386cdd046be11ab4
+		index.BWT[i] = byte(data[dataIndex])
 	}
 
 	var mem alphabet.Letter
@@ -56,128 +97,44 @@ func New(seq *linear.Seq) *Index {
 	return &index
 }
 
-<<<<<<< HEAD
 func (index *Index) SeachForBytesFast(pattern []byte) []uint {
-	searchLetters := alphabet.BytesToLetters(pattern)
 	s := uint(1)
 	e := uint(len(index.BWT))
 
 	for i := len(pattern) - 1; i >= 0; i-- {
-		currChar := searchLetters[i]
-		s = uint(index.c[currChar]) + index.fastRank(s-1, currChar) + 1
-		e = uint(index.c[currChar]) + index.fastRank(e, currChar)
-		if e <= s {
+		currChar := pattern[i]
+		s = uint(index.c[currChar] + index.occ.Rank(currChar, int(s-1)) + 1)
+		e = uint(index.c[currChar] + index.occ.Rank(currChar, int(e)))
+		if e < s {
 			return []uint{}
 		}
 	}
 
 	results := make([]uint, e-s+1)
-	for i := uint(0); i < len(results); i++ {
-		results[i] = index.sa[s+i-1]
+	for i := uint(0); i < uint(len(results)); i++ {
+		results[i] = uint(index.sa[s+i-1])
 	}
 
 	return results
 }
 
-func (index *Index) SearchForBytesBasic(pattern []byte) []int {
-=======
-func NewWithWaveletTree(seq *linear.Seq) *IndexFaster {
-	length := seq.Len()
-	suffixArray := generateSuffixArray(seq.Seq)
-
-	index := IndexFaster{
-		sa:       suffixArray,
-		alphabet: seq.Alphabet(),
-	}
-
-	index.BWT = make([]byte, length)
-	for i := 0; i < length; i++ {
-		dataIndex := (suffixArray[i] - 1 + length) % length
-		index.BWT[i] = byte(seq.Seq[dataIndex])
-	}
-
-	var mem alphabet.Letter
-
-	for i, d := range suffixArray {
-		currentLetter := seq.Seq[d]
-		if mem != currentLetter {
-			index.c[currentLetter] = uint(i)
-		}
-		mem = currentLetter
-	}
-
-	index.wtree = wavelettree.New(index.BWT)
-
-	return &index
-}
-
 func (index *Index) SearchForBytes(pattern []byte) []int {
->>>>>>> 8c03a7ded2ff0b2901294810386cdd046be11ab4
-	searchLetters := alphabet.BytesToLetters(pattern)
 	s := 1
 	e := len(index.BWT)
 
-	for i := len(pattern) - 1; i >= 0; i-- {
-		currChar := searchLetters[i]
-		s = index.c[currChar] + index.rank(s-1, currChar) + 1
-		e = index.c[currChar] + index.rank(e, currChar)
-		if e <= s {
-			return []int{}
-		}
-	}
-
-	results := make([]int, e-s+1)
-	for i := 0; i < len(results); i++ {
-		results[i] = index.sa[s+i-1]
-	}
-
-	return results
-}
-
-// TODO: This is ridiculously slow.
-// Implement rank and count using wavelet trees and RRR for easy speedup.
-func (index *Index) rank(ranking int, c alphabet.Letter) int {
-	sum := 0
-	for i := 0; i < ranking; i++ {
-		if index.BWT[i] == byte(c) {
-			sum++
-		}
-	}
-	return sum
-}
-
-func (index *IndexFaster) SearchForBytes(pattern []byte) []int {
-	s := uint(1)
-	e := uint(len(index.BWT))
-
 	for i := len(pattern) - 1; i >= 0; i-- {
 		currChar := pattern[i]
-		s = index.c[currChar] + index.wtree.Rank(s-1, currChar) + 1
-		e = index.c[currChar] + index.wtree.Rank(e, currChar)
-		if e <= s {
+		s = index.c[currChar] + index.occ.Rank(currChar, s-1) + 1
+		e = index.c[currChar] + index.occ.Rank(currChar, e)
+		if e < s {
 			return []int{}
 		}
 	}
 
 	results := make([]int, e-s+1)
-	for i := uint(0); i < uint(len(results)); i++ {
+	for i := 0; i < len(results); i++ {
 		results[i] = index.sa[s+i-1]
 	}
 
 	return results
 }
-
-func (index *Index) rankFaster(ranking int, c alphabet.Letter) int {
-	sum := 0
-	for i := 0; i < ranking; i++ {
-		if index.BWT[i] == byte(c) {
-			sum++
-		}
-	}
-	return sum
-}
-
-func (index *Index) fastRank(ranking uint, c alphabet.Letter) uint {
-	// TODO Use wavelet trees to get fast counts.
-	return index.wtree.Rank(uint(ranking), byte(c))
-}
@@ -3,7 +3,9 @@ package bwt
 import (
 	"code.google.com/p/biogo/alphabet"
 	"code.google.com/p/biogo/seq/linear"
+	"fmt"
 	. "github.com/smartystreets/goconvey/convey"
+	"math/rand"
 	"testing"
 )
 
@@ -17,7 +19,7 @@ func TestIndexSearch(t *testing.T) {
 	Convey("Given a new index of the string 'TAGCTACTGATGCGTAGCTATGCTAGC'", t, func() {
 		index := New(d)
 		Convey("When searching for 'TGA'", func() {
-			results := index.SearchForBytesBasic([]byte("TAG"))
+			results := index.SearchForBytes([]byte("TAG"))
 			Convey("There should be three hits", func() {
 				So(len(results), ShouldEqual, 3)
 			})
@@ -30,14 +32,14 @@ func TestIndexSearch(t *testing.T) {
 		})
 
 		Convey("When searching for a pattern that wraps around the string", func() {
-			results := index.SearchForBytesBasic([]byte("AGCTAGCTACT"))
+			results := index.SearchForBytes([]byte("AGCTAGCTACT"))
 			Convey("There should be no results returned", func() {
 				So(len(results), ShouldBeZeroValue)
 			})
 		})
 
 		Convey("When searching for 'GTAG'", func() {
-			results := index.SearchForBytesBasic([]byte("TAGC"))
+			results := index.SearchForBytes([]byte("TAGC"))
 			Convey("There should be at least one hit", func() {
 				So(len(results), ShouldBeGreaterThan, 0)
 			})
@@ -48,10 +50,111 @@ func TestIndexSearch(t *testing.T) {
 		})
 
 		Convey("When searching for nonsense", func() {
-			results := index.SearchForBytesBasic([]byte("foobar"))
+			results := index.SearchForBytes([]byte("foobar"))
 			Convey("There should be no results", func() {
 				So(len(results), ShouldBeZeroValue)
 			})
 		})
 	})
+
+	Convey("Given an Index and an IndexFaster of the string 'TAGCTACTGATGCGTAGCTATGCTAGC'", t, func() {
+		for _, index := range []*Index{New(d), &NewWithWaveletTree(d).Index} {
+			Convey(fmt.Sprintf("When searching a %T for patterns that occur exactly once", index.occ), func() {
+				for _, t := range []struct {
+					pattern string
+					pos     int
+				}{
+					{"GATG", 8},
+					{"CGTA", 12},
+					{"TAGCTAC", 0},
+				} {
+					So(index.SearchForBytes([]byte(t.pattern)), ShouldResemble, []int{t.pos})
+					So(index.SeachForBytesFast([]byte(t.pattern)), ShouldResemble, []uint{uint(t.pos)})
+				}
+			})
+		}
+	})
+}
+
+// scanOccurrences is an occurrence table that counts by scanning the BWT.
+type scanOccurrences []byte
+
+func (s scanOccurrences) Rank(c byte, i int) int {
+	var n int
+	for _, b := range s[:i] {
+		if b == c {
+			n++
+		}
+	}
+	return n
+}
+
+func randomSequence(n int) *linear.Seq {
+	text := make([]byte, n)
+	for i := range text {
+		text[i] = "ACGT"[rand.Intn(4)]
+	}
+	return linear.NewSeq("random", alphabet.BytesToLetters(text), alphabet.DNA)
+}
+
+func TestOccurrenceTables(t *testing.T) {
+	rand.Seed(1)
+	s := randomSequence(2000)
+	Convey("Given an Index and an IndexFaster of a random sequence", t, func() {
+		index, faster := New(s), NewWithWaveletTree(s)
+		scan := scanOccurrences(index.BWT)
+		Convey("The occurrence tables should agree with a scan of the BWT", func() {
+			ok := true
+			for i := 0; i <= len(index.BWT); i++ {
+				for _, c := range []byte{0, 'A', 'C', 'G', 'T', 'N'} {
+					want := scan.Rank(c, i)
+					if index.occ.Rank(c, i) != want || faster.occ.Rank(c, i) != want {
+						ok = false
+					}
+				}
+			}
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Searches should return the same hits", func() {
+			for i := 0; i < 20; i++ {
+				p := alphabet.LettersToBytes(s.Seq[i*50 : i*50+4+i%5])
+				want := index.SearchForBytes(p)
+				So(len(want), ShouldBeGreaterThan, 0)
+				So(faster.SearchForBytes(p), ShouldResemble, want)
+				fast := index.SeachForBytesFast(p)
+				So(len(fast), ShouldEqual, len(want))
+				for j := range fast {
+					So(int(fast[j]), ShouldEqual, want[j])
+				}
+			}
+		})
+	})
+}
+
+var benchSeq = func() *linear.Seq {
+	rand.Seed(1)
+	return randomSequence(1e5)
+}()
+
+func benchmarkSearch(b *testing.B, index *Index) {
+	patterns := make([][]byte, 100)
+	for i := range patterns {
+		start := rand.Intn(benchSeq.Len() - 20)
+		patterns[i] = alphabet.LettersToBytes(benchSeq.Seq[start : start+20])
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.SearchForBytes(patterns[i%len(patterns)])
+	}
+}
+
+func BenchmarkSearchScan(b *testing.B) {
+	index := New(benchSeq)
+	index.occ = scanOccurrences(index.BWT)
+	benchmarkSearch(b, index)
+}
+func BenchmarkSearchIndex(b *testing.B) { benchmarkSearch(b, New(benchSeq)) }
+func BenchmarkSearchIndexFaster(b *testing.B) {
+	benchmarkSearch(b, &NewWithWaveletTree(benchSeq).Index)
 }
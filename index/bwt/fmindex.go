@@ -0,0 +1,294 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bwt
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/index/succinct"
+	"code.google.com/p/biogo/seq/linear"
+
+	"errors"
+	"fmt"
+	"sort"
+)
+
+const (
+	// DefaultSampleRate is the default suffix array sampling rate used by NewFMIndex.
+	DefaultSampleRate = 32
+
+	// checkpoint is the interval between occurrence count checkpoints.
+	checkpoint = 64
+
+	// separator is the symbol code used to separate sequences in an FMIndex.
+	separator = 0
+
+	// invalid marks letters that are not valid in the index alphabet.
+	invalid = 0xff
+)
+
+// An FMIndex is an FM-index over a set of sequences. The sequences are concatenated, each
+// followed by a separator, so matches never span more than one sequence. The suffix array is
+// sampled at every sample rate text positions and at the start of each sequence, and rank
+// queries on the BWT are answered using occurrence count checkpoints.
+type FMIndex struct {
+	alpha alphabet.Alphabet
+	code  [256]byte
+	sigma int
+	rate  int
+
+	bwt []byte
+	c   []int
+	occ []uint32
+
+	sampled *succinct.BitVector
+	samples []uint32
+
+	names  []string
+	starts []int
+
+	unmap func() error
+}
+
+// A Hit is the location of a match in an FMIndex.
+type Hit struct {
+	// Seq is the index of the sequence holding the match in
+	// the set of sequences used to build the FMIndex.
+	Seq int
+
+	// Name is the name of the sequence holding the match.
+	Name string
+
+	// Offset is the position of the match in the sequence.
+	Offset int
+}
+
+func (h Hit) String() string { return fmt.Sprintf("%s:%d", h.Name, h.Offset) }
+
+// NewFMIndex returns an FMIndex of the provided sequences, with the suffix array sampled
+// every rate text positions. If rate is less than one, DefaultSampleRate is used. All the
+// sequences must share an alphabet and contain only valid letters.
+func NewFMIndex(seqs []*linear.Seq, rate int) (*FMIndex, error) {
+	if len(seqs) == 0 {
+		return nil, errors.New("bwt: no sequences")
+	}
+	if rate < 1 {
+		rate = DefaultSampleRate
+	}
+	alpha := seqs[0].Alpha
+	if alpha == nil {
+		return nil, errors.New("bwt: no alphabet")
+	}
+	f := &FMIndex{
+		alpha:  alpha,
+		sigma:  alpha.Len() + 1,
+		rate:   rate,
+		names:  make([]string, len(seqs)),
+		starts: make([]int, len(seqs)),
+	}
+	if f.sigma >= invalid {
+		return nil, errors.New("bwt: alphabet too large")
+	}
+	f.setCodes()
+
+	n := 0
+	for _, s := range seqs {
+		n += len(s.Seq) + 1
+	}
+	if n >= 1<<32-1 {
+		return nil, errors.New("bwt: sequences too long for index")
+	}
+	text := make(alphabet.Letters, 0, n)
+	for i, s := range seqs {
+		if s.Alpha != alpha {
+			return nil, fmt.Errorf("bwt: alphabet mismatch for %q", s.Name())
+		}
+		f.names[i] = s.Name()
+		f.starts[i] = len(text)
+		for j, l := range s.Seq {
+			c := f.code[l]
+			if c == invalid {
+				return nil, fmt.Errorf("bwt: invalid letter %q at %s:%d", l, s.Name(), j)
+			}
+			text = append(text, alphabet.Letter(c))
+		}
+		text = append(text, separator)
+	}
+
+	sa := generateSuffixArray(text)
+
+	f.bwt = make([]byte, n)
+	f.sampled = succinct.NewBitVector(n)
+	var samples int
+	for i, p := range sa {
+		if p == 0 {
+			f.bwt[i] = byte(text[n-1])
+		} else {
+			f.bwt[i] = byte(text[p-1])
+		}
+		if p%rate == 0 || f.bwt[i] == separator {
+			f.sampled.Set(i)
+			samples++
+		}
+	}
+	f.sampled.Build()
+	f.samples = make([]uint32, 0, samples)
+	for i, p := range sa {
+		if f.sampled.Get(i) {
+			f.samples = append(f.samples, uint32(p))
+		}
+	}
+
+	f.occ = make([]uint32, (n/checkpoint+1)*f.sigma)
+	counts := make([]uint32, f.sigma)
+	for i, b := range f.bwt {
+		if i%checkpoint == 0 {
+			copy(f.occ[(i/checkpoint)*f.sigma:], counts)
+		}
+		counts[b]++
+	}
+	if n%checkpoint == 0 {
+		copy(f.occ[(n/checkpoint)*f.sigma:], counts)
+	}
+	f.c = make([]int, f.sigma+1)
+	for i, v := range counts {
+		f.c[i+1] = f.c[i] + int(v)
+	}
+
+	return f, nil
+}
+
+// setCodes initialises the letter to symbol code mapping from the index alphabet.
+func (f *FMIndex) setCodes() {
+	for i := range f.code {
+		f.code[i] = invalid
+	}
+	for _, l := range f.alpha.Letters() {
+		f.code[l] = byte(f.alpha.IndexOf(alphabet.Letter(l)) + 1)
+	}
+}
+
+// Alphabet returns the alphabet of the indexed sequences.
+func (f *FMIndex) Alphabet() alphabet.Alphabet { return f.alpha }
+
+// Len returns the length of the indexed text, including sequence separators.
+func (f *FMIndex) Len() int { return len(f.bwt) }
+
+// Sequences returns the number of sequences in the index.
+func (f *FMIndex) Sequences() int { return len(f.names) }
+
+// Name returns the name of the ith indexed sequence.
+func (f *FMIndex) Name(i int) string { return f.names[i] }
+
+// SampleRate returns the suffix array sampling rate of the index.
+func (f *FMIndex) SampleRate() int { return f.rate }
+
+// Code returns the symbol code used in the index for the letter l and whether l is a valid
+// letter in the index alphabet.
+func (f *FMIndex) Code(l alphabet.Letter) (byte, bool) {
+	c := f.code[l]
+	return c, c != invalid
+}
+
+// Occ returns the number of occurrences of the symbol code c in the BWT before row i.
+func (f *FMIndex) Occ(c byte, i int) int {
+	k := i / checkpoint
+	n := int(f.occ[k*f.sigma+int(c)])
+	for _, b := range f.bwt[k*checkpoint : i] {
+		if b == c {
+			n++
+		}
+	}
+	return n
+}
+
+// Extend returns the suffix array interval of the rows prefixed by the symbol code c followed
+// by the prefix of the rows in the half-open interval [lo, hi).
+func (f *FMIndex) Extend(lo, hi int, c byte) (int, int) {
+	return f.c[c] + f.Occ(c, lo), f.c[c] + f.Occ(c, hi)
+}
+
+// Interval returns the half-open suffix array interval of the rows prefixed by pattern.
+// The interval is empty if pattern does not occur in the index or contains letters not
+// valid in the index alphabet.
+func (f *FMIndex) Interval(pattern alphabet.Letters) (lo, hi int) {
+	lo, hi = 0, len(f.bwt)
+	for i := len(pattern) - 1; i >= 0 && lo < hi; i-- {
+		c := f.code[pattern[i]]
+		if c == invalid {
+			return 0, 0
+		}
+		lo, hi = f.Extend(lo, hi, c)
+	}
+	return lo, hi
+}
+
+// Count returns the number of occurrences of pattern in the index.
+func (f *FMIndex) Count(pattern alphabet.Letters) int {
+	lo, hi := f.Interval(pattern)
+	return hi - lo
+}
+
+// Position returns the text position of the suffix at the given row of the suffix array.
+func (f *FMIndex) Position(row int) int {
+	var steps int
+	for !f.sampled.Get(row) {
+		c := f.bwt[row]
+		row = f.c[c] + f.Occ(c, row)
+		steps++
+	}
+	return int(f.samples[f.sampled.Rank1(row)]) + steps
+}
+
+// Resolve returns the index of the sequence holding the text position pos and the offset of
+// pos within that sequence.
+func (f *FMIndex) Resolve(pos int) (seq, offset int) {
+	seq = sort.SearchInts(f.starts, pos+1) - 1
+	return seq, pos - f.starts[seq]
+}
+
+// Locate returns the locations of all occurrences of pattern in the index, sorted by sequence
+// and offset.
+func (f *FMIndex) Locate(pattern alphabet.Letters) []Hit {
+	if len(pattern) == 0 {
+		return nil
+	}
+	lo, hi := f.Interval(pattern)
+	return f.hits(lo, hi)
+}
+
+// hits returns the sorted locations of the rows in the half-open interval [lo, hi).
+func (f *FMIndex) hits(lo, hi int) []Hit {
+	if lo >= hi {
+		return nil
+	}
+	h := make(hits, 0, hi-lo)
+	for row := lo; row < hi; row++ {
+		s, off := f.Resolve(f.Position(row))
+		h = append(h, Hit{Seq: s, Name: f.names[s], Offset: off})
+	}
+	sort.Sort(h)
+	return h
+}
+
+type hits []Hit
+
+func (h hits) Len() int { return len(h) }
+func (h hits) Less(i, j int) bool {
+	return h[i].Seq < h[j].Seq || (h[i].Seq == h[j].Seq && h[i].Offset < h[j].Offset)
+}
+func (h hits) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+// Close releases any memory mapping held by an FMIndex returned by Open. The index must not be
+// used after Close is called.
+func (f *FMIndex) Close() error {
+	if f.unmap == nil {
+		return nil
+	}
+	err := f.unmap()
+	f.unmap = nil
+	f.bwt, f.occ, f.samples = nil, nil, nil
+	f.sampled = nil
+	return err
+}
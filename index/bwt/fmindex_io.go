@@ -0,0 +1,351 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bwt
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/index/succinct"
+	"code.google.com/p/biogo/internal/mapped"
+
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// FMIndex serialisation format.
+//
+// All values are little-endian. The header is followed by the index arrays, each aligned
+// to an 8 byte boundary from the start of the data so that they may be used in place when
+// the data is memory mapped:
+//
+//	magic     [8]byte  "bgfmidx\x00"
+//	version   uint32
+//	rate      uint32
+//	sigma     uint32
+//	letters   uint32 length followed by the alphabet letters
+//	n         uint64 length of the BWT
+//	nseq      uint64
+//	names     nseq × (uint32 length followed by the name)
+//	starts    nseq × uint64
+//	c         (sigma+1) × uint64
+//	bwt       n × byte
+//	occ       (n/64+1)×sigma × uint32
+//	sampled   ⌈n/64⌉ × uint64
+//	samples   (number of set bits in sampled) × uint32
+//
+// The rank directory of sampled is not saved; it is rebuilt when the index is loaded.
+const (
+	fmMagic   = "bgfmidx\x00"
+	fmVersion = 2
+)
+
+var (
+	ErrNotFMIndex     = errors.New("bwt: not an FM-index")
+	ErrVersion        = errors.New("bwt: unsupported FM-index version")
+	ErrAlphabet       = errors.New("bwt: alphabet does not match FM-index")
+	ErrTruncatedIndex = errors.New("bwt: truncated FM-index")
+	ErrCorruptIndex   = errors.New("bwt: corrupt FM-index")
+)
+
+// fmWriter writes little-endian values, tracking the number of bytes written and the
+// first error encountered.
+type fmWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+	buf [8]byte
+}
+
+func (w *fmWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	var n int
+	n, w.err = w.w.Write(b)
+	w.n += int64(n)
+}
+func (w *fmWriter) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(w.buf[:4], v)
+	w.write(w.buf[:4])
+}
+func (w *fmWriter) uint64(v uint64) {
+	binary.LittleEndian.PutUint64(w.buf[:8], v)
+	w.write(w.buf[:8])
+}
+func (w *fmWriter) string(s string) {
+	w.uint32(uint32(len(s)))
+	w.write([]byte(s))
+}
+func (w *fmWriter) align() {
+	var pad [8]byte
+	w.write(pad[:(8-w.n%8)%8])
+}
+
+// Save writes the index to w.
+func (f *FMIndex) Save(w io.Writer) error {
+	fw := &fmWriter{w: bufio.NewWriter(w)}
+	fw.write([]byte(fmMagic))
+	fw.uint32(fmVersion)
+	fw.uint32(uint32(f.rate))
+	fw.uint32(uint32(f.sigma))
+	fw.string(f.alpha.Letters())
+	fw.uint64(uint64(len(f.bwt)))
+	fw.uint64(uint64(len(f.names)))
+	for _, n := range f.names {
+		fw.string(n)
+	}
+	for _, s := range f.starts {
+		fw.uint64(uint64(s))
+	}
+	for _, c := range f.c {
+		fw.uint64(uint64(c))
+	}
+
+	fw.align()
+	fw.write(f.bwt)
+	fw.align()
+	for _, v := range f.occ {
+		fw.uint32(v)
+	}
+	fw.align()
+	for _, v := range f.sampled.Words() {
+		fw.uint64(v)
+	}
+	fw.align()
+	for _, v := range f.samples {
+		fw.uint32(v)
+	}
+	if fw.err != nil {
+		return fw.err
+	}
+	return fw.w.Flush()
+}
+
+// Load reads an index written by Save from r. The alphabet alpha must match the alphabet of the
+// saved index.
+func Load(r io.Reader, alpha alphabet.Alphabet) (*FMIndex, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeFMIndex(data, alpha)
+}
+
+// Open returns the index saved in the file at path. Where the platform supports it the file
+// is memory mapped and the index arrays refer directly to the mapped data; the returned index
+// should be closed with Close when it is no longer needed. The alphabet alpha must match the
+// alphabet of the saved index.
+func Open(path string, alpha alphabet.Alphabet) (*FMIndex, error) {
+	data, unmap, err := mapped.Map(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := decodeFMIndex(data, alpha)
+	if err != nil {
+		if unmap != nil {
+			unmap()
+		}
+		return nil, err
+	}
+	f.unmap = unmap
+	return f, nil
+}
+
+// fmReader reads little-endian values from a byte slice, recording truncation.
+type fmReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *fmReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data)-r.off < n {
+		r.err = ErrTruncatedIndex
+		return nil
+	}
+	b := r.data[r.off : r.off+n : r.off+n]
+	r.off += n
+	return b
+}
+func (r *fmReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+func (r *fmReader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+func (r *fmReader) string() string {
+	return string(r.next(int(r.uint32())))
+}
+func (r *fmReader) align() {
+	r.next((8 - r.off%8) % 8)
+}
+
+// uint32s returns a slice of n uint32 values, referring to the underlying data where possible.
+func (r *fmReader) uint32s(n int) []uint32 {
+	b := r.next(4 * n)
+	if b == nil {
+		return nil
+	}
+	return mapped.Uint32s(b)
+}
+
+// uint64s returns a slice of n uint64 values, referring to the underlying data where possible.
+func (r *fmReader) uint64s(n int) []uint64 {
+	b := r.next(8 * n)
+	if b == nil {
+		return nil
+	}
+	return mapped.Uint64s(b)
+}
+
+func decodeFMIndex(data []byte, alpha alphabet.Alphabet) (*FMIndex, error) {
+	if alpha == nil {
+		return nil, errors.New("bwt: no alphabet")
+	}
+	r := &fmReader{data: data}
+	if string(r.next(len(fmMagic))) != fmMagic {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, ErrNotFMIndex
+	}
+	if v := r.uint32(); v != fmVersion {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, ErrVersion
+	}
+	f := &FMIndex{
+		alpha: alpha,
+		rate:  int(r.uint32()),
+		sigma: int(r.uint32()),
+	}
+	letters := r.string()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if letters != alpha.Letters() || f.sigma != alpha.Len()+1 {
+		return nil, ErrAlphabet
+	}
+	f.setCodes()
+
+	n, nseq := r.uint64(), r.uint64()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if n >= 1<<32 || nseq > n {
+		return nil, fmt.Errorf("bwt: invalid FM-index dimensions: %d sequences in %d", nseq, n)
+	}
+	f.names = make([]string, nseq)
+	for i := range f.names {
+		f.names[i] = r.string()
+	}
+	f.starts = make([]int, nseq)
+	for i := range f.starts {
+		f.starts[i] = int(r.uint64())
+	}
+	f.c = make([]int, f.sigma+1)
+	for i := range f.c {
+		f.c[i] = int(r.uint64())
+	}
+
+	r.align()
+	f.bwt = r.next(int(n))
+	r.align()
+	f.occ = r.uint32s((int(n)/checkpoint + 1) * f.sigma)
+	r.align()
+	words := r.uint64s((int(n) + 63) / 64)
+	r.align()
+	if r.err != nil {
+		return nil, r.err
+	}
+	var err error
+	f.sampled, err = succinct.NewBitVectorOf(words, int(n))
+	if err != nil {
+		return nil, ErrCorruptIndex
+	}
+	f.samples = r.uint32s(f.sampled.Ones())
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if !f.valid() {
+		return nil, ErrCorruptIndex
+	}
+	return f, nil
+}
+
+// valid returns whether the values of a decoded index are consistent, so that queries on the
+// index cannot index out of range.
+func (f *FMIndex) valid() bool {
+	n := len(f.bwt)
+	if len(f.starts) == 0 || f.starts[0] != 0 {
+		return false
+	}
+	for i, s := range f.starts {
+		if s < 0 || s >= n || (i > 0 && s <= f.starts[i-1]) {
+			return false
+		}
+	}
+	if f.c[0] != 0 || f.c[f.sigma] != n {
+		return false
+	}
+	for i := 1; i <= f.sigma; i++ {
+		if f.c[i] < f.c[i-1] {
+			return false
+		}
+	}
+
+	// Each occurrence checkpoint must hold the symbol
+	// counts of the BWT before the checkpoint row.
+	counts := make([]uint32, f.sigma)
+	checkOcc := func(row int) bool {
+		for sym, v := range f.occ[(row/checkpoint)*f.sigma:][:f.sigma] {
+			if v != counts[sym] {
+				return false
+			}
+		}
+		return true
+	}
+	for i, b := range f.bwt {
+		// Rows preceded by a separator must be sampled so
+		// that locating a row terminates.
+		if int(b) >= f.sigma || (b == separator && !f.sampled.Get(i)) {
+			return false
+		}
+		if i%checkpoint == 0 && !checkOcc(i) {
+			return false
+		}
+		counts[b]++
+	}
+	if n%checkpoint == 0 && !checkOcc(n) {
+		return false
+	}
+	for sym, v := range counts {
+		if int(v) != f.c[sym+1]-f.c[sym] {
+			return false
+		}
+	}
+	for _, p := range f.samples {
+		if int(p) >= n {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,182 @@
+package bwt
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func fmSequences() []*linear.Seq {
+	return []*linear.Seq{
+		linear.NewSeq("chr1", alphabet.BytesToLetters([]byte("TAGCTACTGATGCGTAGCTATGCTAGC")), alphabet.DNA),
+		linear.NewSeq("chr2", alphabet.BytesToLetters([]byte("GGGTAGCAAA")), alphabet.DNA),
+		linear.NewSeq("chr3", alphabet.BytesToLetters([]byte("tagc")), alphabet.DNA),
+	}
+}
+
+func naiveHits(seqs []*linear.Seq, pattern string) []Hit {
+	var h []Hit
+	p := bytes.ToLower([]byte(pattern))
+	for i, s := range seqs {
+		b := bytes.ToLower(alphabet.LettersToBytes(s.Seq))
+		for j := 0; j+len(p) <= len(b); j++ {
+			if bytes.Equal(b[j:j+len(p)], p) {
+				h = append(h, Hit{Seq: i, Name: s.Name(), Offset: j})
+			}
+		}
+	}
+	return h
+}
+
+func TestFMIndexLocate(t *testing.T) {
+	seqs := fmSequences()
+	Convey("Given an FM-index of three sequences", t, func() {
+		f, err := NewFMIndex(seqs, 4)
+		So(err, ShouldBeNil)
+		So(f.Sequences(), ShouldEqual, 3)
+		So(f.Len(), ShouldEqual, 27+10+4+3)
+
+		Convey("When searching for 'TAGC'", func() {
+			hits := f.Locate(alphabet.Letters("TAGC"))
+			Convey("The hits should be in each sequence", func() {
+				So(hits, ShouldResemble, []Hit{
+					{Seq: 0, Name: "chr1", Offset: 0},
+					{Seq: 0, Name: "chr1", Offset: 14},
+					{Seq: 0, Name: "chr1", Offset: 23},
+					{Seq: 1, Name: "chr2", Offset: 3},
+					{Seq: 2, Name: "chr3", Offset: 0},
+				})
+			})
+		})
+
+		Convey("When searching for a pattern spanning two sequences", func() {
+			Convey("There should be no results returned", func() {
+				So(f.Count(alphabet.Letters("AGCGGG")), ShouldEqual, 0)
+				So(len(f.Locate(alphabet.Letters("AGCGGG"))), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When searching for nonsense", func() {
+			Convey("There should be no results returned", func() {
+				So(len(f.Locate(alphabet.Letters("foobar"))), ShouldEqual, 0)
+			})
+		})
+
+		Convey("When searching for every substring", func() {
+			ok := true
+			for _, s := range seqs {
+				for i := 0; i < s.Len(); i++ {
+					for j := i + 1; j <= s.Len() && j <= i+6; j++ {
+						p := string(alphabet.LettersToBytes(s.Seq[i:j]))
+						got, want := f.Locate(alphabet.Letters(p)), naiveHits(seqs, p)
+						if len(got) != len(want) {
+							ok = false
+							continue
+						}
+						for k := range got {
+							if got[k] != want[k] {
+								ok = false
+							}
+						}
+					}
+				}
+			}
+			Convey("The hits should match a naive search", func() {
+				So(ok, ShouldBeTrue)
+			})
+		})
+	})
+}
+
+func TestFMIndexSerialisation(t *testing.T) {
+	rand.Seed(1)
+	s := randomSequence(1000)
+	seqs := []*linear.Seq{
+		linear.NewSeq("a", s.Seq[:600], alphabet.DNA),
+		linear.NewSeq("b", s.Seq[600:], alphabet.DNA),
+	}
+	Convey("Given a saved FM-index", t, func() {
+		f, err := NewFMIndex(seqs, 0)
+		So(err, ShouldBeNil)
+		var buf bytes.Buffer
+		So(f.Save(&buf), ShouldBeNil)
+
+		dir, err := ioutil.TempDir("", "bwt")
+		So(err, ShouldBeNil)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "index.fm")
+		So(ioutil.WriteFile(path, buf.Bytes(), 0644), ShouldBeNil)
+
+		Convey("When it is loaded and opened", func() {
+			l, err := Load(bytes.NewReader(buf.Bytes()), alphabet.DNA)
+			So(err, ShouldBeNil)
+			o, err := Open(path, alphabet.DNA)
+			So(err, ShouldBeNil)
+			defer o.Close()
+
+			Convey("Searches should give the same results as the original", func() {
+				for _, p := range []string{"acg", "ttta", "gattaca", "cgcgc", string(alphabet.LettersToBytes(s.Seq[595:605]))} {
+					want := f.Locate(alphabet.Letters(p))
+					So(l.Locate(alphabet.Letters(p)), ShouldResemble, want)
+					So(o.Locate(alphabet.Letters(p)), ShouldResemble, want)
+				}
+			})
+		})
+
+		Convey("When it is loaded with the wrong alphabet", func() {
+			_, err := Load(bytes.NewReader(buf.Bytes()), alphabet.Protein)
+			So(err, ShouldEqual, ErrAlphabet)
+		})
+
+		Convey("When truncated data is loaded", func() {
+			_, err := Load(bytes.NewReader(buf.Bytes()[:buf.Len()-1]), alphabet.DNA)
+			So(err, ShouldEqual, ErrTruncatedIndex)
+		})
+
+		Convey("When corrupt data is loaded", func() {
+			for _, corrupt := range []func(*FMIndex){
+				func(f *FMIndex) { f.bwt[10] = byte(f.sigma) },
+				func(f *FMIndex) { f.c[2] = len(f.bwt) + 5 },
+				func(f *FMIndex) { f.c[1], f.c[2] = f.c[2], f.c[1] },
+				func(f *FMIndex) { f.starts[1] = 0 },
+				func(f *FMIndex) { f.occ[f.sigma+1] = uint32(len(f.bwt)) },
+				func(f *FMIndex) { f.starts[0] = 1 },
+				func(f *FMIndex) {
+					// Set the last checkpoint to the symbol totals.
+					last := f.occ[len(f.occ)-f.sigma:]
+					for sym := range last {
+						last[sym] = uint32(f.c[sym+1] - f.c[sym])
+					}
+				},
+				func(f *FMIndex) { f.samples[3] = uint32(len(f.bwt)) },
+				func(f *FMIndex) {
+					for i, b := range f.bwt {
+						if b == separator {
+							f.sampled.Unset(i)
+							break
+						}
+					}
+				},
+				func(f *FMIndex) {
+					w := f.sampled.Words()
+					w[len(w)-1] |= 1 << 63
+				},
+			} {
+				c, err := NewFMIndex(seqs, 0)
+				So(err, ShouldBeNil)
+				corrupt(c)
+				var buf bytes.Buffer
+				So(c.Save(&buf), ShouldBeNil)
+				_, err = Load(&buf, alphabet.DNA)
+				So(err, ShouldEqual, ErrCorruptIndex)
+			}
+		})
+	})
+}
@@ -0,0 +1,222 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bwt
+
+import (
+	"code.google.com/p/biogo/alphabet"
+
+	"sort"
+)
+
+// A Match is an approximate occurrence of a pattern in an Index or IndexFaster.
+type Match struct {
+	// Pos is the start position of the match in the indexed text.
+	Pos int
+
+	// Edits is the number of mismatches, insertions and deletions
+	// in the match.
+	Edits int
+}
+
+// An InexactHit is an approximate occurrence of a pattern in an FMIndex.
+type InexactHit struct {
+	Hit
+	Edits int
+}
+
+// backwardIndex is the set of operations required to perform backward search.
+type backwardIndex interface {
+	// rows returns the number of rows in the suffix array.
+	rows() int
+
+	// extend returns the half-open suffix array interval of the rows prefixed
+	// by c followed by the prefix of the rows in [lo, hi).
+	extend(lo, hi int, c byte) (int, int)
+
+	// symbols returns the symbols that may be used to extend an interval.
+	symbols() []byte
+
+	// code returns the symbol for the pattern byte b.
+	code(b byte) byte
+
+	// position returns the text position of the suffix at a row.
+	position(row int) int
+}
+
+type indexSearcher struct{ *Index }
+
+func (s indexSearcher) rows() int { return len(s.BWT) }
+func (s indexSearcher) extend(lo, hi int, c byte) (int, int) {
+	return s.c[c] + s.occ.Rank(c, lo), s.c[c] + s.occ.Rank(c, hi)
+}
+func (s indexSearcher) symbols() []byte {
+	var sym []byte
+	for i, v := range s.c {
+		if i != 0 && v != 0 {
+			sym = append(sym, byte(i))
+		}
+	}
+	return sym
+}
+func (s indexSearcher) code(b byte) byte     { return b }
+func (s indexSearcher) position(row int) int { return s.sa[row] }
+
+type fmSearcher struct{ *FMIndex }
+
+func (s fmSearcher) rows() int                            { return len(s.bwt) }
+func (s fmSearcher) extend(lo, hi int, c byte) (int, int) { return s.FMIndex.extend(lo, hi, c) }
+func (s fmSearcher) symbols() []byte {
+	sym := make([]byte, s.sigma-1)
+	for i := range sym {
+		sym[i] = byte(i + 1)
+	}
+	return sym
+}
+func (s fmSearcher) code(b byte) byte     { return s.FMIndex.code[b] }
+func (s fmSearcher) position(row int) int { return s.Position(row) }
+
+// SearchInexact returns the start positions of matches of pattern in the index with at most k
+// mismatches, and if indels is true, insertions and deletions within the pattern. Each position
+// is reported once with the smallest number of edits found for it, and matches are sorted by
+// position.
+func (index *Index) SearchInexact(pattern []byte, k int, indels bool) []Match {
+	return searchInexact(indexSearcher{index}, pattern, k, indels)
+}
+
+// LocateInexact returns the locations of matches of pattern in the index with at most k
+// mismatches, and if indels is true, insertions and deletions within the pattern. Each location
+// is reported once with the smallest number of edits found for it, and hits are sorted by
+// sequence and offset.
+// Letters in pattern that are not valid in the index alphabet always count as mismatches.
+func (f *FMIndex) LocateInexact(pattern alphabet.Letters, k int, indels bool) []InexactHit {
+	m := searchInexact(fmSearcher{f}, alphabet.LettersToBytes(pattern), k, indels)
+	if m == nil {
+		return nil
+	}
+	h := make([]InexactHit, len(m))
+	for i, v := range m {
+		s, off := f.Resolve(v.Pos)
+		h[i] = InexactHit{Hit: Hit{Seq: s, Name: f.names[s], Offset: off}, Edits: v.Edits}
+	}
+	return h
+}
+
+// interval is a suffix array interval reached with a number of edits.
+type interval struct {
+	lo, hi int
+	edits  int
+}
+
+// inexact holds the state of a backtracking inexact backward search.
+type inexact struct {
+	idx     backwardIndex
+	pattern []byte
+	sym     []byte
+	d       []int
+	k       int
+	indels  bool
+	found   []interval
+}
+
+// searchInexact performs a backtracking backward search for pattern in idx, allowing up to k
+// differences, following Li and Durbin's BWA algorithm. Branches are pruned using a lower
+// bound on the number of differences in each prefix of the pattern.
+func searchInexact(idx backwardIndex, pattern []byte, k int, indels bool) []Match {
+	if len(pattern) == 0 || k < 0 {
+		return nil
+	}
+	code := make([]byte, len(pattern))
+	for i, b := range pattern {
+		code[i] = idx.code(b)
+	}
+	s := inexact{
+		idx:     idx,
+		pattern: code,
+		sym:     idx.symbols(),
+		d:       lowerBounds(idx, code),
+		k:       k,
+		indels:  indels,
+	}
+	s.search(len(code)-1, k, 0, idx.rows())
+	if len(s.found) == 0 {
+		return nil
+	}
+
+	best := make(map[int]int)
+	for _, iv := range s.found {
+		for row := iv.lo; row < iv.hi; row++ {
+			p := idx.position(row)
+			if e, ok := best[p]; !ok || iv.edits < e {
+				best[p] = iv.edits
+			}
+		}
+	}
+	m := make(matches, 0, len(best))
+	for p, e := range best {
+		m = append(m, Match{Pos: p, Edits: e})
+	}
+	sort.Sort(m)
+	return m
+}
+
+// lowerBounds returns the BWA D array for pattern; the ith element is a lower bound on the number
+// of differences between pattern[:i+1] and any substring of the indexed text. Each element is the
+// number of non-overlapping segments of pattern[:i+1], found greedily from position i, that do not
+// occur in the index.
+func lowerBounds(idx backwardIndex, pattern []byte) []int {
+	d := make([]int, len(pattern))
+	n := idx.rows()
+	for i := range pattern {
+		lo, hi := 0, n
+		for j := i; j >= 0; j-- {
+			lo, hi = idx.extend(lo, hi, pattern[j])
+			if lo >= hi {
+				d[i]++
+				lo, hi = 0, n
+			}
+		}
+	}
+	return d
+}
+
+// search extends the interval [lo, hi) matching pattern[i+1:] with z remaining differences.
+func (s *inexact) search(i, z, lo, hi int) {
+	if i >= 0 && z < s.d[i] {
+		return
+	}
+	if i < 0 {
+		s.found = append(s.found, interval{lo: lo, hi: hi, edits: s.k - z})
+		return
+	}
+	// Indels are not permitted at the ends of the pattern.
+	indel := s.indels && z > 0 && (lo != 0 || hi != s.idx.rows())
+	if indel && i > 0 {
+		// Insertion in the pattern relative to the text.
+		s.search(i-1, z-1, lo, hi)
+	}
+	for _, c := range s.sym {
+		l, h := s.idx.extend(lo, hi, c)
+		if l >= h {
+			continue
+		}
+		if indel {
+			// Deletion from the pattern relative to the text.
+			s.search(i, z-1, l, h)
+		}
+		if c == s.pattern[i] {
+			s.search(i-1, z, l, h)
+		} else if z > 0 {
+			s.search(i-1, z-1, l, h)
+		}
+	}
+}
+
+type matches []Match
+
+func (m matches) Len() int { return len(m) }
+func (m matches) Less(i, j int) bool {
+	return m[i].Pos < m[j].Pos || (m[i].Pos == m[j].Pos && m[i].Edits < m[j].Edits)
+}
+func (m matches) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
@@ -0,0 +1,133 @@
+package bwt
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"math/rand"
+	"testing"
+)
+
+func hammingMatches(text, pattern []byte, k int) []Match {
+	var m []Match
+	for p := 0; p+len(pattern) <= len(text); p++ {
+		var d int
+		for i := range pattern {
+			if text[p+i] != pattern[i] {
+				d++
+			}
+		}
+		if d <= k {
+			m = append(m, Match{Pos: p, Edits: d})
+		}
+	}
+	return m
+}
+
+// editDistance returns the smallest edit distance between pattern and any substring
+// of text starting at p.
+func editDistance(text, pattern []byte, p int) int {
+	prev := make([]int, len(pattern)+1)
+	for i := range prev {
+		prev[i] = i
+	}
+	best := prev[len(pattern)]
+	cur := make([]int, len(pattern)+1)
+	for j := p; j < len(text); j++ {
+		cur[0] = j - p + 1
+		for i := 1; i <= len(pattern); i++ {
+			v := prev[i-1]
+			if text[j] != pattern[i-1] {
+				v++
+			}
+			if prev[i]+1 < v {
+				v = prev[i] + 1
+			}
+			if cur[i-1]+1 < v {
+				v = cur[i-1] + 1
+			}
+			cur[i] = v
+		}
+		if cur[len(pattern)] < best {
+			best = cur[len(pattern)]
+		}
+		prev, cur = cur, prev
+	}
+	return best
+}
+
+func TestSearchInexact(t *testing.T) {
+	rand.Seed(1)
+	s := randomSequence(300)
+	text := alphabet.LettersToBytes(s.Seq)
+	fm, err := NewFMIndex([]*linear.Seq{s}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	searchers := []struct {
+		name   string
+		search func(pattern []byte, k int, indels bool) []Match
+	}{
+		{"Index", New(s).SearchInexact},
+		{"IndexFaster", NewWithWaveletTree(s).SearchInexact},
+		{"FMIndex", func(pattern []byte, k int, indels bool) []Match {
+			var m []Match
+			for _, h := range fm.LocateInexact(alphabet.BytesToLetters(pattern), k, indels) {
+				m = append(m, Match{Pos: h.Offset, Edits: h.Edits})
+			}
+			return m
+		}},
+	}
+
+	for _, idx := range searchers {
+		Convey("Given an "+idx.name+" of a random sequence", t, func() {
+			Convey("When searching with mismatches only", func() {
+				ok := true
+				for i := 0; i < 20; i++ {
+					p := append([]byte(nil), text[i*13:i*13+12]...)
+					p[rand.Intn(len(p))] = "ACGT"[rand.Intn(4)]
+					for k := 0; k <= 2; k++ {
+						got, want := idx.search(p, k, false), hammingMatches(text, p, k)
+						if len(got) != len(want) {
+							ok = false
+							continue
+						}
+						for j := range got {
+							if got[j] != want[j] {
+								ok = false
+							}
+						}
+					}
+				}
+				Convey("The matches should agree with a naive search", func() {
+					So(ok, ShouldBeTrue)
+				})
+			})
+
+			Convey("When searching with indels", func() {
+				p := append(append([]byte(nil), text[100:110]...), text[111:120]...)
+				got := idx.search(p, 1, true)
+				found, sound := false, true
+				for _, m := range got {
+					if m.Pos == 100 && m.Edits == 1 {
+						found = true
+					}
+					if editDistance(text, p, m.Pos) > m.Edits {
+						sound = false
+					}
+				}
+				Convey("The planted deletion should be found", func() {
+					So(found, ShouldBeTrue)
+				})
+				Convey("Each match should be within its reported edit distance", func() {
+					So(sound, ShouldBeTrue)
+				})
+			})
+
+			Convey("When searching for an empty pattern", func() {
+				So(len(idx.search(nil, 2, true)), ShouldEqual, 0)
+			})
+		})
+	}
+}
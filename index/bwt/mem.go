@@ -0,0 +1,229 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bwt
+
+import (
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+	"sort"
+)
+
+// A MEM is a maximal exact match between a query sequence and a sequence in an FMIndex.
+// A MEM satisfies the feat.Pair interface, with the indexed sequence feature first and the
+// query feature second.
+type MEM struct {
+	// Ref is the location of the match in the indexed sequences.
+	Ref Hit
+
+	// Query is the query sequence and QueryStart is the position
+	// of the match in the query.
+	Query      *linear.Seq
+	QueryStart int
+
+	// Length is the length of the match.
+	Length int
+}
+
+// Features returns the indexed sequence and query features of the match.
+func (m *MEM) Features() [2]feat.Feature {
+	return [2]feat.Feature{
+		segment{name: m.Ref.Name, start: m.Ref.Offset, end: m.Ref.Offset + m.Length},
+		segment{name: m.Query.Name(), start: m.QueryStart, end: m.QueryStart + m.Length, loc: m.Query},
+	}
+}
+
+// Diagonal returns the offset of the match in the query minus the offset in the indexed sequence.
+func (m *MEM) Diagonal() int { return m.QueryStart - m.Ref.Offset }
+
+func (m *MEM) String() string {
+	return fmt.Sprintf("%s[%d,%d)/%s[%d,%d)",
+		m.Ref.Name, m.Ref.Offset, m.Ref.Offset+m.Length,
+		m.Query.Name(), m.QueryStart, m.QueryStart+m.Length,
+	)
+}
+
+// segment is a feature describing a match segment.
+type segment struct {
+	name       string
+	start, end int
+	loc        feat.Feature
+}
+
+func (s segment) Name() string {
+	if s.loc != nil {
+		return s.loc.Name()
+	}
+	return s.name
+}
+func (s segment) Description() string {
+	if s.loc != nil {
+		return s.loc.Description()
+	}
+	return ""
+}
+func (s segment) Location() feat.Feature { return s.loc }
+func (s segment) Start() int             { return s.start }
+func (s segment) End() int               { return s.end }
+func (s segment) Len() int               { return s.end - s.start }
+
+// MEMs returns the maximal exact matches of at least min letters between query and the indexed
+// sequences. A maximal exact match cannot be extended in either direction without introducing a
+// mismatch. Matches are returned as *MEM sorted by query position, indexed sequence and offset.
+//
+// For each end position in the query, matches are extended to the left only while some of their
+// occurrences cannot be extended to the right, and suffix array rows are only visited for
+// intervals holding at least one left and right maximal occurrence.
+func (f *FMIndex) MEMs(query *linear.Seq, min int) []feat.Pair {
+	if min < 1 {
+		min = 1
+	}
+	q := f.codes(query)
+	m := len(q)
+	n := len(f.bwt)
+	var mems []feat.Pair
+	for e := m; e >= min; e-- {
+		// [lo, hi) is the interval of q[s:e] and [xlo, xhi) is the interval of q[s:e+1],
+		// the occurrences of q[s:e] that can be extended to the right.
+		lo, hi := 0, n
+		xlo, xhi := 0, 0
+		if e < m {
+			xlo, xhi = f.extend(0, n, q[e])
+		}
+		for s := e - 1; s >= 0; s-- {
+			lo, hi = f.extend(lo, hi, q[s])
+			if lo >= hi {
+				break
+			}
+			if xlo < xhi {
+				xlo, xhi = f.extend(xlo, xhi, q[s])
+			}
+			if xlo >= xhi {
+				xlo, xhi = lo, lo
+			}
+			if hi-lo == xhi-xlo {
+				// Every occurrence of q[s:e] is followed by q[e], so no
+				// occurrence of q[s:e] or its left extensions is right maximal.
+				break
+			}
+			if e-s < min {
+				continue
+			}
+
+			// The number of occurrences that are left and right maximal is the
+			// number that are not followed by q[e] less the number of those that
+			// are preceded by q[s-1].
+			maximal := (hi - lo) - (xhi - xlo)
+			if s > 0 {
+				llo, lhi := f.extend(lo, hi, q[s-1])
+				lxlo, lxhi := f.extend(xlo, xhi, q[s-1])
+				maximal -= (lhi - llo) - (lxhi - lxlo)
+			}
+			for _, r := range [2][2]int{{lo, xlo}, {xhi, hi}} {
+				for row := r[0]; row < r[1] && maximal > 0; row++ {
+					if s > 0 && f.bwt[row] == q[s-1] {
+						continue
+					}
+					maximal--
+					seq, off := f.Resolve(f.Position(row))
+					mems = append(mems, &MEM{
+						Ref:        Hit{Seq: seq, Name: f.names[seq], Offset: off},
+						Query:      query,
+						QueryStart: s,
+						Length:     e - s,
+					})
+				}
+			}
+		}
+	}
+	sort.Sort(memsByPosition(mems))
+	return mems
+}
+
+// SMEMs returns the super-maximal exact matches of at least min letters between query and the
+// indexed sequences. A super-maximal exact match is a maximal exact match that is not contained
+// in the query interval of any other maximal exact match. Each occurrence of each super-maximal
+// exact match in the indexed sequences is returned as a *MEM, sorted by query position, indexed
+// sequence and offset.
+func (f *FMIndex) SMEMs(query *linear.Seq, min int) []feat.Pair {
+	if min < 1 {
+		min = 1
+	}
+	q := f.codes(query)
+	m := len(q)
+	n := len(f.bwt)
+
+	// start[e] is the start of the longest match ending at e, and
+	// interval[e] is the suffix array interval of that match.
+	start := make([]int, m+1)
+	interval := make([][2]int, m+1)
+	for e := m; e > 0; e-- {
+		lo, hi := 0, n
+		s := e
+		for s > 0 {
+			l, h := f.extend(lo, hi, q[s-1])
+			if l >= h {
+				break
+			}
+			lo, hi = l, h
+			s--
+		}
+		start[e], interval[e] = s, [2]int{lo, hi}
+	}
+
+	var mems []feat.Pair
+	for e := m; e > 0; e-- {
+		s := start[e]
+		if e-s < min || (e < m && start[e+1] <= s) {
+			continue
+		}
+		for row := interval[e][0]; row < interval[e][1]; row++ {
+			seq, off := f.Resolve(f.Position(row))
+			mems = append(mems, &MEM{
+				Ref:        Hit{Seq: seq, Name: f.names[seq], Offset: off},
+				Query:      query,
+				QueryStart: s,
+				Length:     e - s,
+			})
+		}
+	}
+	sort.Sort(memsByPosition(mems))
+	return mems
+}
+
+// codes returns the symbol codes of the letters of s.
+func (f *FMIndex) codes(s *linear.Seq) []byte {
+	q := make([]byte, len(s.Seq))
+	for i, l := range s.Seq {
+		q[i] = f.code[l]
+	}
+	return q
+}
+
+// extend is Extend with handling of invalid symbol codes.
+func (f *FMIndex) extend(lo, hi int, c byte) (int, int) {
+	if int(c) >= f.sigma || c == separator {
+		return 0, 0
+	}
+	return f.Extend(lo, hi, c)
+}
+
+type memsByPosition []feat.Pair
+
+func (m memsByPosition) Len() int { return len(m) }
+func (m memsByPosition) Less(i, j int) bool {
+	a, b := m[i].(*MEM), m[j].(*MEM)
+	switch {
+	case a.QueryStart != b.QueryStart:
+		return a.QueryStart < b.QueryStart
+	case a.Ref.Seq != b.Ref.Seq:
+		return a.Ref.Seq < b.Ref.Seq
+	case a.Ref.Offset != b.Ref.Offset:
+		return a.Ref.Offset < b.Ref.Offset
+	}
+	return a.Length < b.Length
+}
+func (m memsByPosition) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
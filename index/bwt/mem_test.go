@@ -0,0 +1,160 @@
+package bwt
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/seq/linear"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type naiveMEM struct {
+	seq, off, qs, l int
+}
+
+// naiveMEMs returns the maximal exact matches of at least min letters between q and refs.
+func naiveMEMs(refs []string, q string, min int) []naiveMEM {
+	var m []naiveMEM
+	for si, r := range refs {
+		for p := range r {
+			for s := range q {
+				if r[p] != q[s] || (p > 0 && s > 0 && r[p-1] == q[s-1]) {
+					continue
+				}
+				l := 0
+				for p+l < len(r) && s+l < len(q) && r[p+l] == q[s+l] {
+					l++
+				}
+				if l >= min {
+					m = append(m, naiveMEM{si, p, s, l})
+				}
+			}
+		}
+	}
+	return m
+}
+
+func memsOf(p []feat.Pair) []naiveMEM {
+	m := make([]naiveMEM, len(p))
+	for i, f := range p {
+		v := f.(*MEM)
+		m[i] = naiveMEM{v.Ref.Seq, v.Ref.Offset, v.QueryStart, v.Length}
+	}
+	return m
+}
+
+type byNaive []naiveMEM
+
+func (m byNaive) Len() int { return len(m) }
+func (m byNaive) Less(i, j int) bool {
+	a, b := m[i], m[j]
+	switch {
+	case a.qs != b.qs:
+		return a.qs < b.qs
+	case a.seq != b.seq:
+		return a.seq < b.seq
+	case a.off != b.off:
+		return a.off < b.off
+	}
+	return a.l < b.l
+}
+func (m byNaive) Swap(i, j int) { m[i], m[j] = m[j], m[i] }
+
+func TestMEMs(t *testing.T) {
+	rand.Seed(1)
+	var (
+		seqs []*linear.Seq
+		refs []string
+	)
+	for _, n := range []int{200, 150} {
+		s := randomSequence(n)
+		seqs = append(seqs, s)
+		refs = append(refs, string(alphabet.LettersToBytes(s.Seq)))
+	}
+	q := refs[0][20:60] + string(alphabet.LettersToBytes(randomSequence(10).Seq)) + refs[1][30:70] + refs[0][21:45]
+	query := linear.NewSeq("q", alphabet.BytesToLetters([]byte(q)), alphabet.DNA)
+
+	Convey("Given an FM-index of two random sequences", t, func() {
+		f, err := NewFMIndex(seqs, 8)
+		So(err, ShouldBeNil)
+
+		for _, min := range []int{4, 12} {
+			Convey(fmt.Sprintf("When finding MEMs of at least %d letters", min), func() {
+				want := naiveMEMs(refs, q, min)
+				sort.Sort(byNaive(want))
+				Convey("The MEMs should agree with a naive search", func() {
+					So(memsOf(f.MEMs(query, min)), ShouldResemble, want)
+				})
+			})
+
+			Convey(fmt.Sprintf("When finding SMEMs of at least %d letters", min), func() {
+				all := naiveMEMs(refs, q, 1)
+				var want []naiveMEM
+				seen := make(map[[2]int]bool)
+				for _, a := range all {
+					if a.l < min || seen[[2]int{a.qs, a.l}] {
+						continue
+					}
+					contained := false
+					for _, b := range all {
+						if b.qs <= a.qs && a.qs+a.l <= b.qs+b.l && b.l > a.l {
+							contained = true
+							break
+						}
+					}
+					if contained {
+						continue
+					}
+					seen[[2]int{a.qs, a.l}] = true
+					sub := q[a.qs : a.qs+a.l]
+					for si, r := range refs {
+						for p := 0; p+len(sub) <= len(r); p++ {
+							if strings.HasPrefix(r[p:], sub) {
+								want = append(want, naiveMEM{si, p, a.qs, a.l})
+							}
+						}
+					}
+				}
+				sort.Sort(byNaive(want))
+				Convey("The SMEMs should agree with a naive search", func() {
+					So(memsOf(f.SMEMs(query, min)), ShouldResemble, want)
+				})
+			})
+		}
+
+		Convey("The anchors should describe the matched segments", func() {
+			for _, p := range f.SMEMs(query, 20) {
+				fs := p.Features()
+				So(fs[0].Len(), ShouldEqual, fs[1].Len())
+				So(fs[1].Location(), ShouldEqual, query)
+				So(q[fs[1].Start():fs[1].End()], ShouldEqual, refs[p.(*MEM).Ref.Seq][fs[0].Start():fs[0].End()])
+			}
+		})
+	})
+}
+
+func BenchmarkMEMs(b *testing.B) {
+	rand.Seed(1)
+	ref := randomSequence(1e6)
+	f, err := NewFMIndex([]*linear.Seq{ref}, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	// A 10kb read with a 5% substitution rate.
+	q := append([]byte(nil), alphabet.LettersToBytes(ref.Seq[5e5:5e5+1e4])...)
+	for i := range q {
+		if rand.Float64() < 0.05 {
+			q[i] = "ACGT"[rand.Intn(4)]
+		}
+	}
+	query := linear.NewSeq("read", alphabet.BytesToLetters(q), alphabet.DNA)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f.MEMs(query, 12)
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmercount
+
+import (
+	"code.google.com/p/biogo/index/kmerindex"
+
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// Dump format.
+//
+// A dump is a little-endian binary stream holding a header followed by records sorted by k-mer:
+//
+//	magic    [8]byte "bgkmcnt\x00"
+//	version  uint32
+//	k        uint32
+//	records  (uint64 k-mer, uint64 count) until EOF
+const (
+	dumpMagic   = "bgkmcnt\x00"
+	dumpVersion = 1
+)
+
+var (
+	ErrNotDump      = errors.New("kmercount: not a k-mer count dump")
+	ErrVersion      = errors.New("kmercount: unsupported dump version")
+	ErrKMismatch    = errors.New("kmercount: k-mer length mismatch")
+	ErrTruncated    = errors.New("kmercount: truncated dump")
+	ErrUnsortedDump = errors.New("kmercount: dump not sorted")
+)
+
+// A DumpWriter writes sorted k-mer counts in dump format.
+type DumpWriter struct {
+	w    *bufio.Writer
+	buf  [16]byte
+	last kmerindex.Kmer64
+	n    int
+}
+
+// NewDumpWriter returns a DumpWriter that writes a dump of k-mers of length k to w.
+func NewDumpWriter(w io.Writer, k int) (*DumpWriter, error) {
+	dw := &DumpWriter{w: bufio.NewWriter(w)}
+	copy(dw.buf[:], dumpMagic)
+	if _, err := dw.w.Write(dw.buf[:8]); err != nil {
+		return nil, err
+	}
+	binary.LittleEndian.PutUint32(dw.buf[:4], dumpVersion)
+	binary.LittleEndian.PutUint32(dw.buf[4:8], uint32(k))
+	if _, err := dw.w.Write(dw.buf[:8]); err != nil {
+		return nil, err
+	}
+	return dw, nil
+}
+
+// Write writes a k-mer count. K-mers must be written in increasing order.
+func (dw *DumpWriter) Write(kc KmerCount) error {
+	if dw.n > 0 && kc.Kmer <= dw.last {
+		return ErrUnsortedDump
+	}
+	binary.LittleEndian.PutUint64(dw.buf[:8], uint64(kc.Kmer))
+	binary.LittleEndian.PutUint64(dw.buf[8:], kc.Count)
+	_, err := dw.w.Write(dw.buf[:])
+	dw.last = kc.Kmer
+	dw.n++
+	return err
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+func (dw *DumpWriter) Flush() error { return dw.w.Flush() }
+
+// Dump writes the k-mer counts held by the Counter to w in dump format.
+func (c *Counter) Dump(w io.Writer) error {
+	dw, err := NewDumpWriter(w, c.k)
+	if err != nil {
+		return err
+	}
+	for _, kc := range c.Sorted() {
+		if err = dw.Write(kc); err != nil {
+			return err
+		}
+	}
+	return dw.Flush()
+}
+
+// A DumpReader reads k-mer counts from a dump.
+type DumpReader struct {
+	r   *bufio.Reader
+	k   int
+	buf [16]byte
+}
+
+// NewDumpReader returns a DumpReader reading from r.
+func NewDumpReader(r io.Reader) (*DumpReader, error) {
+	dr := &DumpReader{r: bufio.NewReader(r)}
+	if _, err := io.ReadFull(dr.r, dr.buf[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrNotDump
+		}
+		return nil, err
+	}
+	if string(dr.buf[:8]) != dumpMagic {
+		return nil, ErrNotDump
+	}
+	if binary.LittleEndian.Uint32(dr.buf[8:12]) != dumpVersion {
+		return nil, ErrVersion
+	}
+	dr.k = int(binary.LittleEndian.Uint32(dr.buf[12:]))
+	return dr, nil
+}
+
+// K returns the k-mer length of the dump.
+func (dr *DumpReader) K() int { return dr.k }
+
+// Read returns the next k-mer count in the dump. At the end of the dump Read returns io.EOF.
+func (dr *DumpReader) Read() (KmerCount, error) {
+	_, err := io.ReadFull(dr.r, dr.buf[:])
+	switch err {
+	case nil:
+	case io.ErrUnexpectedEOF:
+		return KmerCount{}, ErrTruncated
+	default:
+		return KmerCount{}, err
+	}
+	return KmerCount{
+		Kmer:  kmerindex.Kmer64(binary.LittleEndian.Uint64(dr.buf[:8])),
+		Count: binary.LittleEndian.Uint64(dr.buf[8:]),
+	}, nil
+}
+
+// Load adds the k-mer counts in the dump read from r to the Counter.
+func (c *Counter) Load(r io.Reader) error {
+	dr, err := NewDumpReader(r)
+	if err != nil {
+		return err
+	}
+	if dr.k != c.k {
+		return ErrKMismatch
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for {
+		kc, err := dr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sh := c.shardFor(kc.Kmer)
+		sh.Lock()
+		n := sh.counts[kc.Kmer]
+		sh.counts[kc.Kmer] = n + kc.Count
+		sh.Unlock()
+		if n == 0 {
+			atomic.AddInt64(&c.distinct, 1)
+		}
+	}
+}
+
+// mergeItem is a dump reader and its current k-mer count.
+type mergeItem struct {
+	kc KmerCount
+	dr *DumpReader
+}
+
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].kc.Kmer < h[j].kc.Kmer }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// A Merger merges sorted dumps, summing the counts of k-mers present in more than one dump.
+type Merger struct {
+	k    int
+	h    mergeHeap
+	next KmerCount
+	have bool
+}
+
+// NewMerger returns a Merger reading from the provided dumps. All dumps must hold k-mers of the
+// same length.
+func NewMerger(dumps ...io.Reader) (*Merger, error) {
+	m := &Merger{h: make(mergeHeap, 0, len(dumps))}
+	for i, r := range dumps {
+		dr, err := NewDumpReader(r)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			m.k = dr.k
+		} else if dr.k != m.k {
+			return nil, ErrKMismatch
+		}
+		kc, err := dr.Read()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.h = append(m.h, mergeItem{kc: kc, dr: dr})
+	}
+	heap.Init(&m.h)
+	return m, nil
+}
+
+// K returns the k-mer length of the merged dumps.
+func (m *Merger) K() int { return m.k }
+
+// Read returns the next merged k-mer count in increasing k-mer order. At the end of the dumps Read
+// returns io.EOF.
+func (m *Merger) Read() (KmerCount, error) {
+	for len(m.h) > 0 {
+		it := m.h[0]
+		var (
+			kc  KmerCount
+			out bool
+		)
+		if m.have && it.kc.Kmer == m.next.Kmer {
+			m.next.Count += it.kc.Count
+		} else {
+			kc, out = m.next, m.have
+			m.next, m.have = it.kc, true
+		}
+
+		nkc, err := it.dr.Read()
+		switch err {
+		case nil:
+			if nkc.Kmer <= it.kc.Kmer {
+				return KmerCount{}, ErrUnsortedDump
+			}
+			m.h[0].kc = nkc
+			heap.Fix(&m.h, 0)
+		case io.EOF:
+			heap.Pop(&m.h)
+		default:
+			return KmerCount{}, err
+		}
+
+		if out {
+			return kc, nil
+		}
+	}
+	if m.have {
+		m.have = false
+		return m.next, nil
+	}
+	return KmerCount{}, io.EOF
+}
+
+// MergeDumps merges the sorted dumps read from dumps and writes the merged dump to w.
+func MergeDumps(w io.Writer, dumps ...io.Reader) error {
+	m, err := NewMerger(dumps...)
+	if err != nil {
+		return err
+	}
+	dw, err := NewDumpWriter(w, m.K())
+	if err != nil {
+		return err
+	}
+	for {
+		kc, err := m.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err = dw.Write(kc); err != nil {
+			return err
+		}
+	}
+	return dw.Flush()
+}
+
+// HistogramOf returns the k-mer spectrum of the merged dumps read from dumps. The max parameter is
+// interpreted as for Counter.Histogram.
+func HistogramOf(max int, dumps ...io.Reader) ([]int, error) {
+	m, err := NewMerger(dumps...)
+	if err != nil {
+		return nil, err
+	}
+	h := histogram{max: max}
+	for {
+		kc, err := m.Read()
+		if err == io.EOF {
+			return h.h, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.add(kc.Count)
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package kmercount provides counting of canonical k-mers in streams of sequences, in the manner
+// of Jellyfish.
+//
+// The canonical form of a k-mer is the lesser of the k-mer and its reverse complement, so a k-mer
+// and its reverse complement are counted together. Counts are held in a sharded hash table that is
+// safe for concurrent insertion. When the number of distinct k-mers held exceeds a limit, the table
+// can be spilled to sorted on-disk dumps that are later merged.
+package kmercount
+
+import (
+	"code.google.com/p/biogo/index/kmerindex"
+	"code.google.com/p/biogo/io/seqio"
+	"code.google.com/p/biogo/seq"
+
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	ErrKTooLarge   = errors.New("kmercount: k too large")
+	ErrKTooSmall   = errors.New("kmercount: k too small")
+	ErrBadAlphabet = errors.New("kmercount: alphabet size != 4")
+)
+
+// A Counter counts canonical k-mers in a sharded hash table. Add and CountReader may be called
+// concurrently.
+type Counter struct {
+	k    int
+	mask kmerindex.Kmer64
+
+	// Limit is the maximum number of distinct k-mers held by the Counter
+	// before Spill is called by CountReader. If Limit is zero or Spill
+	// is nil the Counter is unbounded.
+	Limit int
+
+	// Spill is called by CountReader when the number of distinct k-mers
+	// held exceeds Limit. No k-mers are added during the call. Spill will
+	// usually Dump the Counter to temporary storage and then Reset it.
+	Spill func(*Counter) error
+
+	mu       sync.RWMutex
+	shards   []shard
+	distinct int64
+}
+
+type shard struct {
+	sync.Mutex
+	counts map[kmerindex.Kmer64]uint64
+}
+
+// NewCounter returns a Counter for k-mers of length k with the given number of shards. If shards
+// is less than one, four times GOMAXPROCS shards are used.
+func NewCounter(k, shards int) (*Counter, error) {
+	switch {
+	case k > kmerindex.MaxKmerLen64:
+		return nil, ErrKTooLarge
+	case k < kmerindex.MinKmerLen:
+		return nil, ErrKTooSmall
+	}
+	if shards < 1 {
+		shards = 4 * runtime.GOMAXPROCS(0)
+	}
+	c := &Counter{
+		k:      k,
+		mask:   kmerindex.Kmer64(1)<<(2*uint(k)) - 1,
+		shards: make([]shard, shards),
+	}
+	for i := range c.shards {
+		c.shards[i].counts = make(map[kmerindex.Kmer64]uint64)
+	}
+	return c, nil
+}
+
+// K returns the k-mer length of the Counter.
+func (c *Counter) K() int { return c.k }
+
+// shardFor returns the shard holding kmer.
+func (c *Counter) shardFor(kmer kmerindex.Kmer64) *shard {
+	// Mix the k-mer bits so that shards are evenly loaded.
+	h := uint64(kmer) * 0x9e3779b97f4a7c15
+	return &c.shards[(h>>32)%uint64(len(c.shards))]
+}
+
+// Add adds the canonical k-mers of s to the Counter. K-mers containing letters that are not valid
+// in the alphabet of s are skipped.
+func (c *Counter) Add(s seq.Sequence) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.add(s)
+}
+
+func (c *Counter) add(s seq.Sequence) error {
+	alpha := s.Alphabet()
+	if alpha == nil || alpha.Len() != 4 {
+		return ErrBadAlphabet
+	}
+	kmerindex.ForEachCanonical64(kmerindex.Letters(s), c.k, alpha.LetterIndex(), func(_ int, kmer kmerindex.Kmer64) {
+		sh := c.shardFor(kmer)
+		sh.Lock()
+		n := sh.counts[kmer]
+		sh.counts[kmer] = n + 1
+		sh.Unlock()
+		if n == 0 {
+			atomic.AddInt64(&c.distinct, 1)
+		}
+	})
+	return nil
+}
+
+// CountReader adds the canonical k-mers of all the sequences read from r to the Counter using the
+// given number of threads. If threads is less than one or greater than GOMAXPROCS, GOMAXPROCS
+// threads are used. CountReader returns after r returns io.EOF or any error is encountered.
+func (c *Counter) CountReader(r seqio.Reader, threads int) error {
+	if available := runtime.GOMAXPROCS(0); threads > available || threads < 1 {
+		threads = available
+	}
+
+	var (
+		seqs = make(chan seq.Sequence, 2*threads)
+		errs = make(chan error, threads+1)
+		done = make(chan struct{})
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for s := range seqs {
+				if err := c.Add(s); err != nil {
+					errs <- err
+					return
+				}
+				if err := c.spill(); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	var err error
+read:
+	for {
+		var s seq.Sequence
+		s, err = r.Read()
+		if err != nil {
+			break
+		}
+		select {
+		case seqs <- s:
+		case err = <-errs:
+			break read
+		}
+	}
+	close(seqs)
+	<-done
+	if err == io.EOF {
+		err = nil
+	}
+	if err == nil {
+		select {
+		case err = <-errs:
+		default:
+		}
+	}
+	return err
+}
+
+// spill calls the Spill function if the Counter holds more than Limit distinct k-mers.
+func (c *Counter) spill() error {
+	if c.Limit <= 0 || c.Spill == nil || atomic.LoadInt64(&c.distinct) <= int64(c.Limit) {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.distinct <= int64(c.Limit) {
+		return nil
+	}
+	return c.Spill(c)
+}
+
+// Len returns the number of distinct canonical k-mers held by the Counter.
+func (c *Counter) Len() int { return int(atomic.LoadInt64(&c.distinct)) }
+
+// Count returns the number of occurrences of the canonical form of kmer.
+func (c *Counter) Count(kmer kmerindex.Kmer64) int {
+	kmer = kmerindex.Canonical64(c.k, kmer&c.mask)
+	sh := c.shardFor(kmer)
+	sh.Lock()
+	defer sh.Unlock()
+	return int(sh.counts[kmer])
+}
+
+// Reset removes all k-mers from the Counter. It is intended to be called from a Spill function
+// after the Counter has been dumped.
+func (c *Counter) Reset() {
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.Lock()
+		sh.counts = make(map[kmerindex.Kmer64]uint64)
+		sh.Unlock()
+	}
+	atomic.StoreInt64(&c.distinct, 0)
+}
+
+// A KmerCount is a canonical k-mer and its count.
+type KmerCount struct {
+	Kmer  kmerindex.Kmer64
+	Count uint64
+}
+
+type kmerCounts []KmerCount
+
+func (k kmerCounts) Len() int           { return len(k) }
+func (k kmerCounts) Less(i, j int) bool { return k[i].Kmer < k[j].Kmer }
+func (k kmerCounts) Swap(i, j int)      { k[i], k[j] = k[j], k[i] }
+
+// Sorted returns the canonical k-mers held by the Counter and their counts, sorted by k-mer.
+func (c *Counter) Sorted() []KmerCount {
+	kc := make(kmerCounts, 0, c.Len())
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.Lock()
+		for k, n := range sh.counts {
+			kc = append(kc, KmerCount{Kmer: k, Count: n})
+		}
+		sh.Unlock()
+	}
+	sort.Sort(kc)
+	return kc
+}
+
+// Histogram returns the k-mer spectrum of the Counter. The ith element of the returned slice is
+// the number of distinct canonical k-mers occurring i times, with counts greater than max added to
+// the last element. If max is less than one, the length of the histogram is set by the highest count.
+func (c *Counter) Histogram(max int) []int {
+	var h histogram
+	h.max = max
+	for i := range c.shards {
+		sh := &c.shards[i]
+		sh.Lock()
+		for _, n := range sh.counts {
+			h.add(n)
+		}
+		sh.Unlock()
+	}
+	return h.h
+}
+
+type histogram struct {
+	max int
+	h   []int
+}
+
+func (h *histogram) add(n uint64) {
+	i := int(n)
+	if h.max > 0 && (n > uint64(h.max) || i < 0) {
+		i = h.max
+	}
+	if i >= len(h.h) {
+		t := make([]int, i+1)
+		copy(t, h.h)
+		h.h = t
+	}
+	h.h[i]++
+}
+
+// WriteHistogram writes the histogram h to w in the two column format used by Jellyfish, omitting
+// counts with no k-mers.
+func WriteHistogram(w io.Writer, h []int) error {
+	for i, n := range h {
+		if n == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%d %d\n", i, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenomeSize returns an estimate of genome size from the k-mer spectrum h, and the coverage peak of
+// the spectrum. The estimate ignores k-mers with counts below the first minimum of the spectrum,
+// which are assumed to arise from sequencing errors, and divides the total number of remaining
+// k-mers by the coverage peak. If no peak is found, GenomeSize returns zero values.
+func GenomeSize(h []int) (size float64, peak int) {
+	valley := 1
+	for valley+1 < len(h) && h[valley+1] < h[valley] {
+		valley++
+	}
+	for i := valley + 1; i < len(h); i++ {
+		if h[i] > h[valley] && (peak == 0 || h[i] > h[peak]) {
+			peak = i
+		}
+	}
+	if peak == 0 {
+		return 0, 0
+	}
+	var total int
+	for i := valley; i < len(h); i++ {
+		total += i * h[i]
+	}
+	return float64(total) / float64(peak), peak
+}
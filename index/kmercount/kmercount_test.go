@@ -0,0 +1,225 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmercount
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/index/kmerindex"
+	"code.google.com/p/biogo/io/seqio/fasta"
+	"code.google.com/p/biogo/seq/linear"
+
+	"bytes"
+	"fmt"
+	"io"
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct {
+	seqs []*linear.Seq
+}
+
+var _ = check.Suite(&S{})
+
+func (s *S) SetUpSuite(c *check.C) {
+	rand.Seed(1)
+	s.seqs = make([]*linear.Seq, 20)
+	for i := range s.seqs {
+		l := make(alphabet.Letters, 200+rand.Intn(300))
+		for j := range l {
+			l[j] = [...]alphabet.Letter{'A', 'C', 'G', 'T', 'a', 'c', 'g', 't', 'N'}[rand.Intn(9)]
+		}
+		s.seqs[i] = linear.NewSeq(fmt.Sprintf("seq%d", i), l, alphabet.DNA)
+	}
+}
+
+// naive returns the canonical k-mer counts of seqs.
+func naive(c *check.C, k int, seqs []*linear.Seq) map[kmerindex.Kmer64]uint64 {
+	lookUp := alphabet.DNA.LetterIndex()
+	counts := make(map[kmerindex.Kmer64]uint64)
+	for _, sq := range seqs {
+	kmers:
+		for i := 0; i+k <= sq.Len(); i++ {
+			text := string(alphabet.LettersToBytes(sq.Seq[i : i+k]))
+			if strings.ContainsAny(text, "Nn") {
+				continue kmers
+			}
+			kmer, err := kmerindex.KmerOf64(k, lookUp, text)
+			c.Assert(err, check.Equals, nil)
+			counts[kmerindex.Canonical64(k, kmer)]++
+		}
+	}
+	return counts
+}
+
+func (s *S) TestNewCounter(c *check.C) {
+	_, err := NewCounter(kmerindex.MinKmerLen-1, 1)
+	c.Check(err, check.Equals, ErrKTooSmall)
+	_, err = NewCounter(kmerindex.MaxKmerLen64+1, 1)
+	c.Check(err, check.Equals, ErrKTooLarge)
+	cnt, err := NewCounter(5, 0)
+	c.Assert(err, check.Equals, nil)
+	c.Check(len(cnt.shards) > 0, check.Equals, true)
+	c.Check(cnt.Add(linear.NewSeq("", alphabet.BytesToLetters([]byte("ACDEF")), alphabet.Protein)), check.Equals, ErrBadAlphabet)
+}
+
+func (s *S) TestAdd(c *check.C) {
+	for _, k := range []int{4, 5, 11, 21, 31, 32} {
+		cnt, err := NewCounter(k, 7)
+		c.Assert(err, check.Equals, nil)
+		for _, sq := range s.seqs {
+			c.Assert(cnt.Add(sq), check.Equals, nil)
+		}
+		want := naive(c, k, s.seqs)
+		c.Check(cnt.Len(), check.Equals, len(want))
+		for kmer, n := range want {
+			c.Check(cnt.Count(kmer), check.Equals, int(n))
+			c.Check(cnt.Count(kmerindex.ComplementOf64(k, kmer)), check.Equals, int(n))
+		}
+		sorted := cnt.Sorted()
+		c.Check(len(sorted), check.Equals, len(want))
+		for i, kc := range sorted {
+			if i > 0 {
+				c.Check(kc.Kmer > sorted[i-1].Kmer, check.Equals, true)
+			}
+			c.Check(kc.Count, check.Equals, want[kc.Kmer])
+		}
+	}
+}
+
+func (s *S) fasta() *bytes.Buffer {
+	var buf bytes.Buffer
+	for _, sq := range s.seqs {
+		fmt.Fprintf(&buf, "%60a\n", sq)
+	}
+	return &buf
+}
+
+func (s *S) TestCountReader(c *check.C) {
+	const k = 9
+	want := naive(c, k, s.seqs)
+	for _, threads := range []int{1, 2, 4} {
+		cnt, err := NewCounter(k, 0)
+		c.Assert(err, check.Equals, nil)
+		r := fasta.NewReader(s.fasta(), linear.NewSeq("", nil, alphabet.DNA))
+		c.Assert(cnt.CountReader(r, threads), check.Equals, nil)
+		c.Check(cnt.Len(), check.Equals, len(want))
+		for kmer, n := range want {
+			c.Check(cnt.Count(kmer), check.Equals, int(n))
+		}
+	}
+}
+
+func (s *S) TestSpillMerge(c *check.C) {
+	const k = 7
+	full, err := NewCounter(k, 0)
+	c.Assert(err, check.Equals, nil)
+	for _, sq := range s.seqs {
+		c.Assert(full.Add(sq), check.Equals, nil)
+	}
+
+	var dumps []*bytes.Buffer
+	cnt, err := NewCounter(k, 0)
+	c.Assert(err, check.Equals, nil)
+	cnt.Limit = 1000
+	cnt.Spill = func(cnt *Counter) error {
+		var buf bytes.Buffer
+		if err := cnt.Dump(&buf); err != nil {
+			return err
+		}
+		dumps = append(dumps, &buf)
+		cnt.Reset()
+		return nil
+	}
+	r := fasta.NewReader(s.fasta(), linear.NewSeq("", nil, alphabet.DNA))
+	c.Assert(cnt.CountReader(r, 4), check.Equals, nil)
+	c.Assert(cnt.Spill(cnt), check.Equals, nil)
+	c.Check(len(dumps) > 1, check.Equals, true)
+
+	readers := func() []io.Reader {
+		rs := make([]io.Reader, len(dumps))
+		for i, d := range dumps {
+			rs[i] = bytes.NewReader(d.Bytes())
+		}
+		return rs
+	}
+
+	h, err := HistogramOf(20, readers()...)
+	c.Assert(err, check.Equals, nil)
+	c.Check(h, check.DeepEquals, full.Histogram(20))
+
+	var merged bytes.Buffer
+	c.Assert(MergeDumps(&merged, readers()...), check.Equals, nil)
+	dr, err := NewDumpReader(&merged)
+	c.Assert(err, check.Equals, nil)
+	c.Check(dr.K(), check.Equals, k)
+	var got []KmerCount
+	for {
+		kc, err := dr.Read()
+		if err == io.EOF {
+			break
+		}
+		c.Assert(err, check.Equals, nil)
+		got = append(got, kc)
+	}
+	c.Check(got, check.DeepEquals, full.Sorted())
+
+	loaded, err := NewCounter(k, 3)
+	c.Assert(err, check.Equals, nil)
+	for _, d := range readers() {
+		c.Assert(loaded.Load(d), check.Equals, nil)
+	}
+	c.Check(loaded.Sorted(), check.DeepEquals, full.Sorted())
+}
+
+func (s *S) TestDumpErrors(c *check.C) {
+	_, err := NewDumpReader(strings.NewReader("not a dump"))
+	c.Check(err, check.Equals, ErrNotDump)
+
+	var a, b bytes.Buffer
+	for k, buf := range map[int]*bytes.Buffer{4: &a, 5: &b} {
+		dw, err := NewDumpWriter(buf, k)
+		c.Assert(err, check.Equals, nil)
+		c.Check(dw.Write(KmerCount{Kmer: 2, Count: 1}), check.Equals, nil)
+		c.Check(dw.Write(KmerCount{Kmer: 1, Count: 1}), check.Equals, ErrUnsortedDump)
+		c.Assert(dw.Flush(), check.Equals, nil)
+	}
+	_, err = NewMerger(bytes.NewReader(a.Bytes()), bytes.NewReader(b.Bytes()))
+	c.Check(err, check.Equals, ErrKMismatch)
+
+	cnt, err := NewCounter(5, 1)
+	c.Assert(err, check.Equals, nil)
+	c.Check(cnt.Load(bytes.NewReader(a.Bytes())), check.Equals, ErrKMismatch)
+
+	dr, err := NewDumpReader(bytes.NewReader(b.Bytes()[:b.Len()-3]))
+	c.Assert(err, check.Equals, nil)
+	_, err = dr.Read()
+	c.Check(err, check.Equals, ErrTruncated)
+}
+
+func (s *S) TestGenomeSize(c *check.C) {
+	// Error k-mers at count 1 and a coverage peak at 10.
+	h := []int{0, 500, 40, 5, 2, 10, 30, 60, 80, 95, 100, 90, 70, 40, 20, 5}
+	size, peak := GenomeSize(h)
+	c.Check(peak, check.Equals, 10)
+	var total int
+	for i, n := range h[4:] {
+		total += (i + 4) * n
+	}
+	c.Check(size, check.Equals, float64(total)/10)
+
+	size, peak = GenomeSize([]int{0, 10, 5, 1})
+	c.Check(size, check.Equals, 0.)
+	c.Check(peak, check.Equals, 0)
+
+	var buf bytes.Buffer
+	c.Assert(WriteHistogram(&buf, []int{0, 3, 0, 1}), check.Equals, nil)
+	c.Check(buf.String(), check.Equals, "1 3\n3 1\n")
+}
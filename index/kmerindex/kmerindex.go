@@ -24,6 +24,8 @@ var (
 	ErrBadKmer        = errors.New("kmerindex: kmer out of range")
 	ErrBadKmerTextLen = errors.New("kmerindex: kmertext length != k")
 	ErrBadKmerText    = errors.New("kmerindex: kmertext contains illegal character")
+	ErrBadSeed        = errors.New("kmerindex: invalid seed mask")
+	ErrNotBuilt       = errors.New("kmerindex: index not built: call Build()")
 )
 
 var Debug = false // Set Debug to true to prevent recovering from panics in ForEachKmer f Eval function.
@@ -40,6 +42,15 @@ type Index struct {
 	k       int
 	kMask   Kmer
 	indexed bool
+
+	// span is the length of sequence covered by a Kmer and
+	// care holds the offsets of the positions of a spaced
+	// seed that contribute to a Kmer. For contiguous Kmers
+	// span is k and care is nil.
+	span int
+	care []int
+
+	unmap func() error
 }
 
 // Create a new Kmer Index with a word size k based on sequence
@@ -62,6 +73,53 @@ func New(k int, s *linear.Seq) (*Index, error) {
 		seq:     s,
 		lookUp:  s.Alpha.LetterIndex(),
 		indexed: false,
+		span:    k,
+	}
+	ki.buildKmerTable()
+
+	return ki, nil
+}
+
+// Create a new Kmer Index based on sequence using the spaced seed described by seed. The seed is a
+// mask of '1' and '0' characters, for example "111010010100110111", where a '1' indicates a
+// position that contributes to the Kmer and a '0' a position that is ignored. The seed must begin
+// and end with a '1', and the word size of the Index is the number of '1' positions in the seed.
+func NewSpaced(seed string, s *linear.Seq) (*Index, error) {
+	var care []int
+	for i, c := range seed {
+		switch c {
+		case '1':
+			care = append(care, i)
+		case '0':
+		default:
+			return nil, ErrBadSeed
+		}
+	}
+	k := len(care)
+	switch {
+	case len(seed) == 0 || seed[0] != '1' || seed[len(seed)-1] != '1':
+		return nil, ErrBadSeed
+	case k > MaxKmerLen:
+		return nil, ErrKTooLarge
+	case k < MinKmerLen:
+		return nil, ErrKTooSmall
+	case len(seed)+1 > s.Len():
+		return nil, ErrShortSeq
+	case s.Alpha.Len() != 4:
+		return nil, ErrBadAlphabet
+	}
+
+	ki := &Index{
+		finger:  make([]Kmer, util.Pow4(k)+1), // Need a Tn+1 finger position so that Tn can be recognised
+		k:       k,
+		kMask:   Kmer(util.Pow4(k) - 1),
+		seq:     s,
+		lookUp:  s.Alpha.LetterIndex(),
+		indexed: false,
+		span:    len(seed),
+	}
+	if k != len(seed) {
+		ki.care = care
 	}
 	ki.buildKmerTable()
 
@@ -76,8 +134,12 @@ func (ki *Index) buildKmerTable() {
 	ki.ForEachKmerOf(ki.seq, 0, ki.seq.Len(), incrementFinger)
 }
 
-// Build the Kmer position table destructively replacing Kmer frequencies
+// Build the Kmer position table destructively replacing Kmer frequencies. Build has no effect on an
+// Index that has already been built, including an Index returned by Load or Open.
 func (ki *Index) Build() {
+	if ki.indexed {
+		return
+	}
 	var sum Kmer
 	for i, v := range ki.finger {
 		ki.finger[i], sum = sum, sum+v
@@ -87,7 +149,7 @@ func (ki *Index) Build() {
 		index.pos[index.finger[kmer]] = position
 		index.finger[kmer]++
 	}
-	ki.pos = make([]int, ki.seq.Len()-ki.k+1)
+	ki.pos = make([]int, ki.seq.Len()-ki.span+1)
 	ki.ForEachKmerOf(ki.seq, 0, ki.seq.Len(), locatePositions)
 
 	ki.indexed = true
@@ -99,7 +161,7 @@ func (ki *Index) KmerPositionsString(kmertext string) (positions []int, err erro
 	case len(kmertext) != ki.k:
 		return nil, ErrBadKmerTextLen
 	case !ki.indexed:
-		return nil, errors.New("kmerindex: index not built: call Build()")
+		return nil, ErrNotBuilt
 	}
 
 	var kmer Kmer
@@ -133,6 +195,11 @@ func (ki *Index) KmerPositions(kmer Kmer) (positions []int, err error) {
 	return
 }
 
+// Return whether Build has been called on the Index.
+func (ki *Index) Built() bool {
+	return ki.indexed
+}
+
 // Return a map containing absolute Kmer frequencies and true if called before Build().
 // If called after Build returns a nil map and false.
 func (ki *Index) KmerFrequencies() (map[Kmer]int, bool) {
@@ -210,6 +277,8 @@ func (ki *Index) StringKmerIndex() (map[string][]int, bool) {
 type Eval func(index *Index, j, kmer int)
 
 // Applies the f Eval func to all kmers in s from start to end. Returns any panic raised by f as an error.
+// If the Index was created with a spaced seed, the kmers are formed from the seed positions of each
+// seed span-length window of s.
 func (ki *Index) ForEachKmerOf(s *linear.Seq, start, end int, f Eval) (err error) {
 	if !Debug {
 		defer func() {
@@ -223,6 +292,11 @@ func (ki *Index) ForEachKmerOf(s *linear.Seq, start, end int, f Eval) (err error
 		}()
 	}
 
+	if ki.care != nil {
+		ki.forEachSpacedKmerOf(s, start, end, f)
+		return
+	}
+
 	kmer := Kmer(0)
 	high := 0
 	var currentBase int
@@ -257,11 +331,69 @@ func (ki *Index) ForEachKmerOf(s *linear.Seq, start, end int, f Eval) (err error
 	return
 }
 
-// Return the Kmer length of the Index.
+// forEachSpacedKmerOf applies the f Eval func to all spaced seed kmers in s from start to end.
+func (ki *Index) forEachSpacedKmerOf(s *linear.Seq, start, end int, f Eval) {
+	// Letters at positions not in the seed are ignored, so
+	// a kmer is well defined if all its seed positions are.
+positions:
+	for position := start; position+ki.span <= end; position++ {
+		var kmer Kmer
+		for _, o := range ki.care {
+			currentBase := ki.lookUp[s.Seq[position+o]]
+			if currentBase < 0 {
+				continue positions
+			}
+			kmer = (kmer << 2) | Kmer(currentBase)
+		}
+		f(ki, position, int(kmer))
+	}
+}
+
+// Applies f to all kmers in s from start to end, passing the position of the kmer in s and the bounds
+// of the pos slice holding the positions of the kmer in the indexed sequence. Returns any panic raised
+// by f as an error. Not valid before Build() - will return an error.
+func (ki *Index) ForEachRangeOf(s *linear.Seq, start, end int, f func(position, from, to int)) error {
+	if !ki.indexed {
+		return ErrNotBuilt
+	}
+	return ki.ForEachKmerOf(s, start, end, func(index *Index, position, kmer int) {
+		from := 0
+		if kmer > 0 { // special case: An has no predecessor
+			from = int(index.finger[kmer-1])
+		}
+		f(position, from, int(index.finger[kmer]))
+	})
+}
+
+// Return the Kmer length of the Index. For a spaced seed Index this is the seed weight.
 func (ki *Index) K() int {
 	return ki.k
 }
 
+// Return the length of sequence covered by a Kmer of the Index. For a spaced seed Index this is
+// the seed length, otherwise it is the Kmer length.
+func (ki *Index) Span() int {
+	return ki.span
+}
+
+// Return the seed mask of the Index. For an Index of contiguous Kmers this is k '1' characters.
+func (ki *Index) Seed() string {
+	seed := make([]byte, ki.span)
+	if ki.care == nil {
+		for i := range seed {
+			seed[i] = '1'
+		}
+		return string(seed)
+	}
+	for i := range seed {
+		seed[i] = '0'
+	}
+	for _, o := range ki.care {
+		seed[o] = '1'
+	}
+	return string(seed)
+}
+
 // Returns a pointer to the indexed seq.Seq.
 func (ki *Index) Seq() *linear.Seq {
 	return ki.seq
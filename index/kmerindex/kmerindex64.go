@@ -0,0 +1,518 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmerindex
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+	"code.google.com/p/biogo/util"
+
+	"fmt"
+	"sort"
+)
+
+// 2-bit per base packed 64 bit word
+type Kmer64 uint64
+
+// Constraints on Kmer64 length.
+var (
+	MaxKmerLen64 = 32
+
+	// FingerLen64 is the maximum length of the Kmer64 prefix used to bucket
+	// positions in the finger table of an Index64.
+	FingerLen64 = 10
+)
+
+// Kmer index type for k up to 32. An Index64 has the same pos layout as an Index, but since a finger
+// table over all 4^k Kmers is not feasible for large k, the finger is over the prefixes of length
+// min(k, FingerLen64) and positions within each prefix bucket are sorted by Kmer64. FingerAt is
+// therefore indexed by prefix rather than by Kmer64; KmerRange and ForEachRangeOf give the bounds
+// of the pos slice for a complete Kmer64, and ForEachRangeOf allows an Index64 to be used as the
+// index of a PALS filter.Filter.
+//
+// Index64 is a separate type rather than Index parameterised by word width since the language has
+// no type parameters, and an interface over the word type would put a dynamic call in the inner
+// loop of kmer rolling; the finger layout also differs for large k.
+type Index64 struct {
+	finger  []int
+	pos     []int
+	seq     *linear.Seq
+	lookUp  alphabet.Index
+	k       int
+	kMask   Kmer64
+	shift   uint
+	indexed bool
+}
+
+// Create a new Kmer64 Index with a word size k based on sequence
+func New64(k int, s *linear.Seq) (*Index64, error) {
+	switch {
+	case k > MaxKmerLen64:
+		return nil, ErrKTooLarge
+	case k < MinKmerLen:
+		return nil, ErrKTooSmall
+	case k+1 > s.Len():
+		return nil, ErrShortSeq
+	case s.Alpha.Len() != 4:
+		return nil, ErrBadAlphabet
+	}
+
+	p := k
+	if p > FingerLen64 {
+		p = FingerLen64
+	}
+	ki := &Index64{
+		finger:  make([]int, util.Pow4(p)+1), // Need a Tn+1 finger position so that Tn can be recognised
+		k:       k,
+		kMask:   Kmer64(1)<<(2*uint(k)) - 1,
+		shift:   2 * uint(k-p),
+		seq:     s,
+		lookUp:  s.Alpha.LetterIndex(),
+		indexed: false,
+	}
+	ki.buildKmerTable()
+
+	return ki, nil
+}
+
+// Build the table of Kmer64 prefix frequencies - called by New64
+func (ki *Index64) buildKmerTable() {
+	incrementFinger := func(index *Index64, _ int, kmer Kmer64) {
+		index.finger[kmer>>index.shift]++
+	}
+	ki.ForEachKmerOf(ki.seq, 0, ki.seq.Len(), incrementFinger)
+}
+
+// Build the Kmer64 position table destructively replacing Kmer64 prefix frequencies. Build has no
+// effect on an Index64 that has already been built.
+func (ki *Index64) Build() {
+	if ki.indexed {
+		return
+	}
+	var sum int
+	for i, v := range ki.finger {
+		ki.finger[i], sum = sum, sum+v
+	}
+
+	kmers := make([]Kmer64, ki.seq.Len()-ki.k+1)
+	locatePositions := func(index *Index64, position int, kmer Kmer64) {
+		b := kmer >> index.shift
+		index.pos[index.finger[b]] = position
+		kmers[index.finger[b]] = kmer
+		index.finger[b]++
+	}
+	ki.pos = make([]int, ki.seq.Len()-ki.k+1)
+	ki.ForEachKmerOf(ki.seq, 0, ki.seq.Len(), locatePositions)
+	ki.pos = ki.pos[:ki.finger[len(ki.finger)-1]]
+
+	if ki.shift != 0 {
+		from := 0
+		for _, to := range ki.finger {
+			if to-from > 1 {
+				sort.Stable(bucket{kmers: kmers[from:to], pos: ki.pos[from:to]})
+			}
+			from = to
+		}
+	}
+
+	ki.indexed = true
+}
+
+// bucket sorts the positions of a finger bucket by Kmer64.
+type bucket struct {
+	kmers []Kmer64
+	pos   []int
+}
+
+func (b bucket) Len() int           { return len(b.pos) }
+func (b bucket) Less(i, j int) bool { return b.kmers[i] < b.kmers[j] }
+func (b bucket) Swap(i, j int) {
+	b.kmers[i], b.kmers[j] = b.kmers[j], b.kmers[i]
+	b.pos[i], b.pos[j] = b.pos[j], b.pos[i]
+}
+
+// kmerAt returns the Kmer64 at position p of the indexed sequence.
+func (ki *Index64) kmerAt(p int) (kmer Kmer64) {
+	for _, l := range ki.seq.Seq[p : p+ki.k] {
+		kmer = (kmer << 2) | Kmer64(ki.lookUp[l])
+	}
+	return kmer
+}
+
+// Return the bounds of the pos slice holding positions for the Kmer64 kmer
+func (ki *Index64) KmerRange(kmer Kmer64) (from, to int, err error) {
+	switch {
+	case kmer > ki.kMask:
+		return 0, 0, ErrBadKmer
+	case !ki.indexed:
+		return 0, 0, ErrNotBuilt
+	}
+
+	b := kmer >> ki.shift
+	if b > 0 { // special case: An has no predecessor
+		from = ki.finger[b-1]
+	}
+	to = ki.finger[b]
+	if ki.shift == 0 || from == to {
+		return from, to, nil
+	}
+
+	n := to - from
+	i := sort.Search(n, func(i int) bool { return ki.kmerAt(ki.pos[from+i]) >= kmer })
+	j := i + sort.Search(n-i, func(j int) bool { return ki.kmerAt(ki.pos[from+i+j]) > kmer })
+	return from + i, from + j, nil
+}
+
+// Return an array of positions for the Kmer64 string kmertext
+func (ki *Index64) KmerPositionsString(kmertext string) (positions []int, err error) {
+	switch {
+	case len(kmertext) != ki.k:
+		return nil, ErrBadKmerTextLen
+	case !ki.indexed:
+		return nil, ErrNotBuilt
+	}
+
+	var kmer Kmer64
+	if kmer, err = ki.KmerOf(kmertext); err != nil {
+		return nil, err
+	}
+
+	return ki.KmerPositions(kmer)
+}
+
+// Return an array of positions for the Kmer64 kmer
+func (ki *Index64) KmerPositions(kmer Kmer64) (positions []int, err error) {
+	i, j, err := ki.KmerRange(kmer)
+	if err != nil || i == j {
+		return nil, err
+	}
+
+	positions = make([]int, j-i)
+	copy(positions, ki.pos[i:j])
+
+	return
+}
+
+// Return a map containing absolute Kmer64 frequencies.
+func (ki *Index64) KmerFrequencies() map[Kmer64]int {
+	m := map[Kmer64]int{}
+	ki.ForEachKmerOf(ki.seq, 0, ki.seq.Len(), func(_ *Index64, _ int, kmer Kmer64) {
+		m[kmer]++
+	})
+
+	return m
+}
+
+// Returns a Kmer64-keyed map containing slices of kmer positions and true if called after Build,
+// otherwise nil and false.
+func (ki *Index64) KmerIndex() (map[Kmer64][]int, bool) {
+	if !ki.indexed {
+		return nil, false
+	}
+
+	m := make(map[Kmer64][]int)
+
+	for _, p := range ki.pos {
+		kmer := ki.kmerAt(p)
+		m[kmer] = append(m[kmer], p)
+	}
+
+	return m, true
+}
+
+// errors should be handled through a panic which will be recovered by ForEachKmerOf
+type Eval64 func(index *Index64, j int, kmer Kmer64)
+
+// Applies the f Eval64 func to all kmers in s from start to end. Returns any panic raised by f as an error.
+func (ki *Index64) ForEachKmerOf(s *linear.Seq, start, end int, f Eval64) (err error) {
+	if !Debug {
+		defer func() {
+			if r := recover(); r != nil {
+				var ok bool
+				err, ok = r.(error)
+				if !ok {
+					err = fmt.Errorf("kmerindex: %v", r)
+				}
+			}
+		}()
+	}
+
+	kmer := Kmer64(0)
+	high := 0
+	var currentBase int
+
+	// Preload the first k-1 bases of the first well defined k-mer or set high to the next position
+	basePosition := start
+	for ; basePosition < start+ki.k-1; basePosition++ {
+		currentBase = ki.lookUp[s.Seq[basePosition]]
+		if currentBase >= 0 {
+			kmer = (kmer << 2) | Kmer64(currentBase)
+		} else {
+			kmer = 0
+			high = basePosition + 1
+		}
+	}
+
+	// Call f(position, kmer) for each of the next well defined k-mers
+	for position := basePosition - ki.k + 1; basePosition < end; position++ {
+		currentBase = ki.lookUp[s.Seq[basePosition]]
+		basePosition++
+		if currentBase >= 0 {
+			kmer = ((kmer << 2) | Kmer64(currentBase)) & ki.kMask
+		} else {
+			kmer = 0
+			high = basePosition
+		}
+		if position >= high {
+			f(ki, position, kmer)
+		}
+	}
+
+	return
+}
+
+// Applies f to all kmers in s from start to end, passing the position of the kmer in s and the bounds
+// of the pos slice holding the positions of the kmer in the indexed sequence. Returns any panic raised
+// by f as an error. Not valid before Build() - will return an error.
+func (ki *Index64) ForEachRangeOf(s *linear.Seq, start, end int, f func(position, from, to int)) error {
+	if !ki.indexed {
+		return ErrNotBuilt
+	}
+	return ki.ForEachKmerOf(s, start, end, func(index *Index64, position int, kmer Kmer64) {
+		from, to, err := index.KmerRange(kmer)
+		if err != nil {
+			panic(err)
+		}
+		f(position, from, to)
+	})
+}
+
+// Return the Kmer64 length of the Index64.
+func (ki *Index64) K() int {
+	return ki.k
+}
+
+// Return the length of sequence covered by a Kmer64 of the Index64. This is always the Kmer64 length.
+func (ki *Index64) Span() int {
+	return ki.k
+}
+
+// Returns a pointer to the indexed seq.Seq.
+func (ki *Index64) Seq() *linear.Seq {
+	return ki.seq
+}
+
+// Returns the value of the finger slice at p. This signifies the absolute frequency of Kmer64 values
+// with the prefix p if called before Build() and points to the relevant position lookup if called after.
+func (ki *Index64) FingerAt(p int) int {
+	return ki.finger[p]
+}
+
+// Returns the value of the pos slice at p. This signifies the position of the pth kmer if called after Build().
+// Not valid before Build() - will panic.
+func (ki *Index64) PosAt(p int) int {
+	return ki.pos[p]
+}
+
+// Returns the length of the Kmer64 prefix used to index the finger slice.
+func (ki *Index64) FingerLen() int {
+	return ki.k - int(ki.shift/2)
+}
+
+// Convert a Kmer64 into a string of bases
+func (ki *Index64) Format(kmer Kmer64) string {
+	s, _ := Format64(kmer, ki.k, ki.seq.Alpha)
+	return s
+}
+
+// Convert a string of bases into a Kmer64, returns an error if string length does not match word length
+func (ki *Index64) KmerOf(kmertext string) (kmer Kmer64, err error) {
+	return KmerOf64(ki.k, ki.lookUp, kmertext)
+}
+
+// Return the GC fraction of a Kmer64
+func (ki *Index64) GCof(kmer Kmer64) float64 {
+	return GCof64(ki.k, kmer)
+}
+
+// Reverse complement a Kmer64. Complementation is performed according to letter index:
+//
+//	0, 1, 2, 3 = 3, 2, 1, 0
+func (ki *Index64) ComplementOf(kmer Kmer64) (c Kmer64) {
+	return ComplementOf64(ki.k, kmer)
+}
+
+// Confirm that a Build() is correct. Returns boolean indicating this and the number of kmers indexed.
+func (ki *Index64) Check() (ok bool, found int) {
+	if !ki.indexed {
+		return false, 0
+	}
+	ok = true
+	f := func(index *Index64, position int, kmer Kmer64) {
+		from, to, err := index.KmerRange(kmer)
+		if err != nil {
+			panic(err)
+		}
+		for _, p := range index.pos[from:to] {
+			if p == position {
+				found++
+				return
+			}
+		}
+		ok = false
+	}
+
+	if err := ki.ForEachKmerOf(ki.seq, 0, ki.seq.Len(), f); err != nil {
+		ok = false
+	}
+
+	return
+}
+
+// Convert a string of bases into a len k Kmer64, returns an error if string length does not match k.
+// lookUp is an index lookup table as returned by alphabet.Alphabet.LetterIndex().
+func KmerOf64(k int, lookUp alphabet.Index, kmertext string) (kmer Kmer64, err error) {
+	if len(kmertext) != k {
+		return 0, ErrBadKmerTextLen
+	}
+
+	for _, v := range kmertext {
+		x := lookUp[v]
+		if x < 0 {
+			return 0, ErrBadKmerText
+		}
+		kmer = (kmer << 2) | Kmer64(x)
+	}
+
+	return
+}
+
+// Return the GC fraction of a Kmer64 of len k
+func GCof64(k int, kmer Kmer64) float64 {
+	gc := 0
+	for i := k - 1; i >= 0; i, kmer = i-1, kmer>>2 {
+		gc += int((kmer & 1) ^ ((kmer & 2) >> 1))
+	}
+
+	return float64(gc) / float64(k)
+}
+
+// Convert a Kmer64 into a string of bases
+func Format64(kmer Kmer64, k int, alpha alphabet.Alphabet) (string, error) {
+	if alpha.Len() != 4 {
+		return "", ErrBadAlphabet
+	}
+	kmertext := make([]byte, k)
+
+	for i := k - 1; i >= 0; i, kmer = i-1, kmer>>2 {
+		kmertext[i] = byte(alpha.Letter(int(kmer & 3)))
+	}
+
+	return string(kmertext), nil
+}
+
+// Reverse complement a Kmer64 of len k. Complementation is performed according to letter index:
+//
+//	0, 1, 2, 3 = 3, 2, 1, 0
+func ComplementOf64(k int, kmer Kmer64) (c Kmer64) {
+	for i, j := uint(0), uint(k-1)*2; i <= j; i, j = i+2, j-2 {
+		c |= (^(kmer >> (j - i)) & (3 << i)) | (^(kmer>>i)&3)<<j
+	}
+
+	return
+}
+
+// Return the canonical form of the Kmer64 kmer of len k, the lesser of kmer and its reverse complement.
+func Canonical64(k int, kmer Kmer64) Kmer64 {
+	if rc := ComplementOf64(k, kmer); rc < kmer {
+		return rc
+	}
+	return kmer
+}
+
+// A Roller maintains the Kmer64 of len k ending at the last letter pushed and its reverse complement
+// over a stream of letters.
+type Roller struct {
+	k      int
+	mask   Kmer64
+	shift  uint
+	lookUp alphabet.Index
+	kmer   Kmer64
+	rc     Kmer64
+	valid  int
+}
+
+// Create a new Roller for Kmer64 values of len k, where k is in [1, MaxKmerLen64]. lookUp is an
+// index lookup table as returned by alphabet.Alphabet.LetterIndex() for a four letter alphabet.
+func NewRoller(k int, lookUp alphabet.Index) *Roller {
+	return &Roller{
+		k:      k,
+		mask:   Kmer64(1)<<(2*uint(k)) - 1,
+		shift:  2*uint(k) - 2,
+		lookUp: lookUp,
+	}
+}
+
+// Push adds l to the Roller and returns whether the Roller holds a complete Kmer64. A letter that is
+// not valid in the alphabet resets the Roller.
+func (r *Roller) Push(l alphabet.Letter) bool {
+	b := r.lookUp[l]
+	if b < 0 {
+		r.valid = 0
+		return false
+	}
+	r.kmer = (r.kmer<<2 | Kmer64(b)) & r.mask
+	r.rc = r.rc>>2 | Kmer64(3-b)<<r.shift
+	r.valid++
+	return r.valid >= r.k
+}
+
+// Return the Kmer64 held by the Roller. Not valid unless the last call to Push returned true.
+func (r *Roller) Kmer() Kmer64 { return r.kmer }
+
+// Return the reverse complement of the Kmer64 held by the Roller. Not valid unless the last call to
+// Push returned true.
+func (r *Roller) Complement() Kmer64 { return r.rc }
+
+// Return the canonical form of the Kmer64 held by the Roller. Not valid unless the last call to Push
+// returned true.
+func (r *Roller) Canonical() Kmer64 {
+	if r.rc < r.kmer {
+		return r.rc
+	}
+	return r.kmer
+}
+
+// Applies f to the canonical form of each Kmer64 of len k in l, passing the position of the start of
+// the Kmer64 in l. Kmers containing letters that are not valid in the alphabet described by lookUp are
+// skipped. k must be in [1, MaxKmerLen64].
+func ForEachCanonical64(l alphabet.Letters, k int, lookUp alphabet.Index, f func(position int, kmer Kmer64)) {
+	r := NewRoller(k, lookUp)
+	for i, c := range l {
+		if r.Push(c) {
+			f(i-k+1, r.Canonical())
+		}
+	}
+}
+
+// Return the letters of s. If s is not backed by alphabet.Letters a copy of its letters is returned.
+func Letters(s seq.Sequence) alphabet.Letters {
+	switch sl := s.Slice().(type) {
+	case alphabet.Letters:
+		return sl
+	case alphabet.QLetters:
+		l := make(alphabet.Letters, len(sl))
+		for i, ql := range sl {
+			l[i] = ql.L
+		}
+		return l
+	}
+	l := make(alphabet.Letters, s.Len())
+	for i := range l {
+		l[i] = s.At(i).L
+	}
+	return l
+}
@@ -0,0 +1,134 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmerindex
+
+import (
+	"code.google.com/p/biogo/alphabet"
+
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"strings"
+)
+
+func (s *S) TestKmerIndex64Check(c *check.C) {
+	for _, k := range []int{MinKmerLen, 9, 10, 11, 16, 17, 31, 32} {
+		if i, err := New64(k, s.Seq); err != nil {
+			c.Fatalf("New64 KmerIndex failed: %v", err)
+		} else {
+			ok, _ := i.Check()
+			c.Check(ok, check.Equals, false)
+			i.Build()
+			ok, f := i.Check()
+			c.Check(f, check.Equals, s.Seq.Len()-k+1)
+			c.Check(ok, check.Equals, true)
+		}
+	}
+	_, err := New64(MaxKmerLen64+1, s.Seq)
+	c.Check(err, check.Equals, ErrKTooLarge)
+}
+
+func (s *S) TestKmerPositions64(c *check.C) {
+	for _, k := range []int{MinKmerLen, 10, 12, 20, 32} {
+		if i, err := New64(k, s.Seq); err != nil {
+			c.Fatalf("New64 KmerIndex failed: %v", err)
+		} else {
+			i.Build()
+			hashPos := make(map[string][]int)
+			for i := 0; i+k <= s.Seq.Len(); i++ {
+				p := strings.ToLower(string(alphabet.LettersToBytes(s.Seq.Seq[i : i+k])))
+				hashPos[p] = append(hashPos[p], i)
+			}
+			for p, want := range hashPos {
+				got, err := i.KmerPositionsString(p)
+				c.Assert(err, check.Equals, nil)
+				c.Check(got, check.DeepEquals, want)
+			}
+			pos, ok := i.KmerIndex()
+			c.Check(ok, check.Equals, true)
+			c.Check(len(pos), check.Equals, len(hashPos))
+		}
+	}
+}
+
+func (s *S) TestKmer64KmerUtilities(c *check.C) {
+	for _, k := range []int{MinKmerLen, 15, 16, 17, 31, 32} {
+		for n := 0; n < 1000; n++ {
+			kmer := Kmer64(rand.Int63()) ^ Kmer64(rand.Int63())<<1
+			if k < 32 {
+				kmer &= Kmer64(1)<<(2*uint(k)) - 1
+			}
+
+			// Interconversion between string and Kmer64
+			s, err := Format64(kmer, k, alphabet.DNA)
+			c.Assert(err, check.Equals, nil)
+			rk, err := KmerOf64(k, alphabet.DNA.LetterIndex(), s)
+			c.Assert(err, check.Equals, nil)
+			c.Check(rk, check.Equals, kmer)
+
+			// Complementation
+			rc := make([]byte, k)
+			for i, b := range []byte(s) {
+				rc[k-1-i] = map[byte]byte{'a': 't', 'c': 'g', 'g': 'c', 't': 'a'}[b]
+			}
+			cs, _ := Format64(ComplementOf64(k, kmer), k, alphabet.DNA)
+			c.Check(cs, check.Equals, string(rc))
+			c.Check(ComplementOf64(k, ComplementOf64(k, kmer)), check.Equals, kmer)
+
+			// GC content
+			gc := 0
+			for _, b := range s {
+				if b == 'g' || b == 'c' {
+					gc++
+				}
+			}
+			c.Check(GCof64(k, kmer), check.Equals, float64(gc)/float64(k))
+		}
+	}
+}
+
+func (s *S) TestCanonical64(c *check.C) {
+	for _, t := range []struct {
+		k          int
+		kmer, want string
+	}{
+		{k: 4, kmer: "acgt", want: "acgt"},
+		{k: 4, kmer: "tttt", want: "aaaa"},
+		{k: 5, kmer: "ggaca", want: "ggaca"},
+		{k: 5, kmer: "tgtcc", want: "ggaca"},
+		{k: 6, kmer: "gcagta", want: "gcagta"},
+	} {
+		lookUp := alphabet.DNA.LetterIndex()
+		kmer, err := KmerOf64(t.k, lookUp, t.kmer)
+		c.Assert(err, check.Equals, nil)
+		got, err := Format64(Canonical64(t.k, kmer), t.k, alphabet.DNA)
+		c.Assert(err, check.Equals, nil)
+		c.Check(got, check.Equals, t.want)
+	}
+}
+
+func (s *S) TestForEachCanonical64(c *check.C) {
+	lookUp := alphabet.DNA.LetterIndex()
+	l := alphabet.BytesToLetters([]byte("acgTTNgattacaggcatNNcgcgtacgatcgatgcatgcaaaacgtt"))
+	for _, k := range []int{MinKmerLen, 5, 7, 32} {
+		var want []int
+		wantKmer := make(map[int]Kmer64)
+		for i := 0; i+k <= len(l); i++ {
+			text := string(alphabet.LettersToBytes(l[i : i+k]))
+			if strings.ContainsAny(text, "Nn") {
+				continue
+			}
+			kmer, err := KmerOf64(k, lookUp, text)
+			c.Assert(err, check.Equals, nil)
+			want = append(want, i)
+			wantKmer[i] = Canonical64(k, kmer)
+		}
+		var got []int
+		ForEachCanonical64(l, k, lookUp, func(position int, kmer Kmer64) {
+			got = append(got, position)
+			c.Check(kmer, check.Equals, wantKmer[position], check.Commentf("k=%d position=%d", k, position))
+		})
+		c.Check(got, check.DeepEquals, want, check.Commentf("k=%d", k))
+	}
+}
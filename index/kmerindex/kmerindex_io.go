@@ -0,0 +1,295 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmerindex
+
+import (
+	"code.google.com/p/biogo/internal/mapped"
+	"code.google.com/p/biogo/seq/linear"
+
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"unsafe"
+)
+
+// Index serialisation format.
+//
+// All values are little-endian. The header is followed by the index arrays, each aligned
+// to an 8 byte boundary from the start of the data so that they may be used in place when
+// the data is memory mapped:
+//
+//	magic     [8]byte  "bgkmidx\x00"
+//	version   uint32
+//	k         uint32
+//	seed      uint32 length followed by the seed mask
+//	letters   uint32 length followed by the alphabet letters
+//	seqlen    uint64 length of the indexed sequence
+//	nfinger   uint64
+//	npos      uint64
+//	finger    nfinger × uint32
+//	pos       npos × int64
+const (
+	indexMagic   = "bgkmidx\x00"
+	indexVersion = 1
+)
+
+var (
+	ErrNotIndex       = errors.New("kmerindex: not a kmer index")
+	ErrVersion        = errors.New("kmerindex: unsupported kmer index version")
+	ErrAlphabet       = errors.New("kmerindex: alphabet does not match kmer index")
+	ErrSeqMismatch    = errors.New("kmerindex: sequence does not match kmer index")
+	ErrTruncatedIndex = errors.New("kmerindex: truncated kmer index")
+)
+
+// indexWriter writes little-endian values, tracking the number of bytes written and the
+// first error encountered.
+type indexWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+	buf [8]byte
+}
+
+func (w *indexWriter) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	var n int
+	n, w.err = w.w.Write(b)
+	w.n += int64(n)
+}
+func (w *indexWriter) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(w.buf[:4], v)
+	w.write(w.buf[:4])
+}
+func (w *indexWriter) uint64(v uint64) {
+	binary.LittleEndian.PutUint64(w.buf[:8], v)
+	w.write(w.buf[:8])
+}
+func (w *indexWriter) string(s string) {
+	w.uint32(uint32(len(s)))
+	w.write([]byte(s))
+}
+func (w *indexWriter) align() {
+	var pad [8]byte
+	w.write(pad[:(8-w.n%8)%8])
+}
+
+// Save writes the built index to w. The indexed sequence is not written; it must be provided
+// when the index is loaded.
+func (ki *Index) Save(w io.Writer) error {
+	if !ki.indexed {
+		return ErrNotBuilt
+	}
+	iw := &indexWriter{w: bufio.NewWriter(w)}
+	iw.write([]byte(indexMagic))
+	iw.uint32(indexVersion)
+	iw.uint32(uint32(ki.k))
+	iw.string(ki.Seed())
+	iw.string(ki.seq.Alpha.Letters())
+	iw.uint64(uint64(ki.seq.Len()))
+	iw.uint64(uint64(len(ki.finger)))
+	iw.uint64(uint64(len(ki.pos)))
+
+	iw.align()
+	for _, v := range ki.finger {
+		iw.uint32(uint32(v))
+	}
+	iw.align()
+	for _, v := range ki.pos {
+		iw.uint64(uint64(v))
+	}
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// Load reads an index written by Save from r. The sequence s must be the sequence that was
+// indexed. The returned index is built and is read-only; calling Build on it has no effect.
+func Load(r io.Reader, s *linear.Seq) (*Index, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeIndex(data, s)
+}
+
+// Open returns the index saved in the file at path. Where the platform supports it the file
+// is memory mapped and the index arrays refer directly to the mapped data; the returned index
+// should be closed with Close when it is no longer needed. The sequence s must be the sequence
+// that was indexed. The returned index is built and is read-only; calling Build on it has no
+// effect.
+func Open(path string, s *linear.Seq) (*Index, error) {
+	data, unmap, err := mapped.Map(path)
+	if err != nil {
+		return nil, err
+	}
+	ki, err := decodeIndex(data, s)
+	if err != nil {
+		if unmap != nil {
+			unmap()
+		}
+		return nil, err
+	}
+	ki.unmap = unmap
+	return ki, nil
+}
+
+// Close releases any memory mapping held by an Index returned by Open. The index must not be
+// used after Close is called.
+func (ki *Index) Close() error {
+	if ki.unmap == nil {
+		return nil
+	}
+	err := ki.unmap()
+	ki.unmap = nil
+	ki.finger, ki.pos = nil, nil
+	return err
+}
+
+// indexReader reads little-endian values from a byte slice, recording truncation.
+type indexReader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func (r *indexReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.data)-r.off < n {
+		r.err = ErrTruncatedIndex
+		return nil
+	}
+	b := r.data[r.off : r.off+n : r.off+n]
+	r.off += n
+	return b
+}
+func (r *indexReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+func (r *indexReader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(b)
+}
+func (r *indexReader) string() string {
+	return string(r.next(int(r.uint32())))
+}
+func (r *indexReader) align() {
+	r.next((8 - r.off%8) % 8)
+}
+
+// kmers returns a slice of n Kmer values, referring to the underlying data where possible.
+func (r *indexReader) kmers(n int) []Kmer {
+	b := r.next(4 * n)
+	if b == nil {
+		return nil
+	}
+	u := mapped.Uint32s(b)
+	// Kmer has uint32 as its underlying type.
+	return *(*[]Kmer)(unsafe.Pointer(&u))
+}
+
+// ints returns a slice of n int values, referring to the underlying data where possible.
+func (r *indexReader) ints(n int) []int {
+	b := r.next(8 * n)
+	if b == nil {
+		return nil
+	}
+	return mapped.Ints(b)
+}
+
+func decodeIndex(data []byte, s *linear.Seq) (*Index, error) {
+	r := &indexReader{data: data}
+	if string(r.next(len(indexMagic))) != indexMagic {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, ErrNotIndex
+	}
+	if v := r.uint32(); v != indexVersion {
+		if r.err != nil {
+			return nil, r.err
+		}
+		return nil, ErrVersion
+	}
+	k := int(r.uint32())
+	seed := r.string()
+	letters := r.string()
+	seqLen, nFinger, nPos := r.uint64(), r.uint64(), r.uint64()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if s.Alpha == nil || letters != s.Alpha.Letters() {
+		return nil, ErrAlphabet
+	}
+	if seqLen != uint64(s.Len()) {
+		return nil, ErrSeqMismatch
+	}
+
+	var care []int
+	for i, c := range seed {
+		switch c {
+		case '1':
+			care = append(care, i)
+		case '0':
+		default:
+			return nil, ErrNotIndex
+		}
+	}
+	switch {
+	case len(care) != k || k > MaxKmerLen || k < MinKmerLen:
+		return nil, ErrNotIndex
+	case nFinger != uint64(1)<<(2*uint(k))+1 || nPos != seqLen-uint64(len(seed))+1:
+		return nil, ErrNotIndex
+	}
+
+	ki := &Index{
+		k:       k,
+		kMask:   Kmer(uint64(1)<<(2*uint(k)) - 1),
+		seq:     s,
+		lookUp:  s.Alpha.LetterIndex(),
+		indexed: true,
+		span:    len(seed),
+	}
+	if k != len(seed) {
+		ki.care = care
+	}
+	r.align()
+	ki.finger = r.kmers(int(nFinger))
+	r.align()
+	ki.pos = r.ints(int(nPos))
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// The finger must hold non-decreasing bounds into pos and pos must
+	// hold positions of kmers in the sequence for queries to be safe.
+	var last Kmer
+	for _, v := range ki.finger {
+		if v < last || uint64(v) > nPos {
+			return nil, ErrNotIndex
+		}
+		last = v
+	}
+	for _, p := range ki.pos {
+		if p < 0 || uint64(p) >= nPos {
+			return nil, ErrNotIndex
+		}
+	}
+
+	return ki, nil
+}
@@ -9,8 +9,13 @@ import (
 	"code.google.com/p/biogo/seq/linear"
 	"code.google.com/p/biogo/util"
 
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
 	check "launchpad.net/gocheck"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -145,3 +150,132 @@ func (s *S) TestKmerKmerUtilities(c *check.C) {
 		}
 	}
 }
+
+func (s *S) TestSpacedKmerIndex(c *check.C) {
+	seeds := []string{"1111", "11011", "1101011", "111010010100110111"}
+	for _, seed := range seeds {
+		i, err := NewSpaced(seed, s.Seq)
+		c.Assert(err, check.Equals, nil)
+		k := strings.Count(seed, "1")
+		c.Check(i.K(), check.Equals, k)
+		c.Check(i.Span(), check.Equals, len(seed))
+		c.Check(i.Seed(), check.Equals, seed)
+
+		ok, _ := i.Check()
+		c.Check(ok, check.Equals, false)
+		i.Build()
+		ok, f := i.Check()
+		c.Check(f, check.Equals, s.Seq.Len()-len(seed)+1)
+		c.Check(ok, check.Equals, true)
+
+		hashPos := make(map[string][]int)
+		for p := 0; p+len(seed) <= s.Seq.Len(); p++ {
+			var w []byte
+			for j, m := range seed {
+				if m == '1' {
+					w = append(w, byte(s.Seq.Seq[p+j]))
+				}
+			}
+			key := strings.ToLower(string(w))
+			hashPos[key] = append(hashPos[key], p)
+		}
+		pos, ok := i.StringKmerIndex()
+		c.Check(ok, check.Equals, true)
+		c.Check(len(pos), check.Equals, len(hashPos))
+		for p := range pos {
+			c.Check(pos[p], check.DeepEquals, hashPos[p], check.Commentf("seed %s", seed))
+		}
+	}
+
+	i, err := New(6, s.Seq)
+	c.Assert(err, check.Equals, nil)
+	c.Check(i.Span(), check.Equals, 6)
+	c.Check(i.Seed(), check.Equals, "111111")
+
+	for _, seed := range []string{"", "0111", "1110", "11x11", "1001"} {
+		_, err = NewSpaced(seed, s.Seq)
+		c.Check(err, check.Not(check.Equals), nil, check.Commentf("seed %q", seed))
+	}
+	_, err = NewSpaced("1"+strings.Repeat("0", 10)+strings.Repeat("1", MaxKmerLen), s.Seq)
+	c.Check(err, check.Equals, ErrKTooLarge)
+}
+
+func (s *S) TestSpacedKmerIndexAmbiguous(c *check.C) {
+	sq := linear.NewSeq("", alphabet.BytesToLetters([]byte("acgtnacgtacgta")), alphabet.DNA)
+	i, err := NewSpaced("11011", sq)
+	c.Assert(err, check.Equals, nil)
+	var got []int
+	i.ForEachKmerOf(sq, 0, sq.Len(), func(_ *Index, p, _ int) { got = append(got, p) })
+	// The n at position 4 is ignored when it falls on the seed's don't care position.
+	c.Check(got, check.DeepEquals, []int{2, 5, 6, 7, 8, 9})
+}
+
+func (s *S) TestSaveLoad(c *check.C) {
+	dir, err := ioutil.TempDir("", "kmerindex")
+	c.Assert(err, check.Equals, nil)
+	defer os.RemoveAll(dir)
+
+	for _, seed := range []string{"11111", "11011", "111010010100110111"} {
+		i, err := NewSpaced(seed, s.Seq)
+		c.Assert(err, check.Equals, nil)
+		var buf bytes.Buffer
+		c.Check(i.Save(&buf), check.Equals, ErrNotBuilt)
+		i.Build()
+		c.Assert(i.Save(&buf), check.Equals, nil)
+		data := buf.Bytes()
+		want, _ := i.KmerIndex()
+
+		path := filepath.Join(dir, seed)
+		c.Assert(ioutil.WriteFile(path, data, 0644), check.Equals, nil)
+		li, err := Load(bytes.NewReader(data), s.Seq)
+		c.Assert(err, check.Equals, nil)
+		oi, err := Open(path, s.Seq)
+		c.Assert(err, check.Equals, nil)
+		for _, got := range []*Index{li, oi} {
+			c.Check(got.Built(), check.Equals, true)
+			got.Build() // Must not alter a loaded index.
+			c.Check(got.K(), check.Equals, i.K())
+			c.Check(got.Seed(), check.Equals, seed)
+			ok, f := got.Check()
+			c.Check(ok, check.Equals, true)
+			c.Check(f, check.Equals, s.Seq.Len()-len(seed)+1)
+			pos, ok := got.KmerIndex()
+			c.Check(ok, check.Equals, true)
+			c.Check(pos, check.DeepEquals, want, check.Commentf("seed %s", seed))
+		}
+		c.Check(oi.Close(), check.Equals, nil)
+		c.Check(li.Close(), check.Equals, nil)
+
+		_, err = Load(bytes.NewReader(data[:len(data)-1]), s.Seq)
+		c.Check(err, check.Equals, ErrTruncatedIndex)
+		_, err = Load(bytes.NewReader(data), linear.NewSeq("", s.Seq.Seq[:len(s.Seq.Seq)-1], alphabet.DNA))
+		c.Check(err, check.Equals, ErrSeqMismatch)
+		_, err = Load(bytes.NewReader(data), linear.NewSeq("", s.Seq.Seq, alphabet.RNA))
+		c.Check(err, check.Equals, ErrAlphabet)
+
+		// The pos slice is held at the end of the data, preceded by the
+		// aligned finger slice.
+		nPos, nFinger := len(i.pos), len(i.finger)
+		posOff := len(data) - 8*nPos
+		lastFinger := posOff - (8-4*nFinger%8)%8 - 4
+		for _, corrupt := range []func(b []byte){
+			func(b []byte) { binary.LittleEndian.PutUint64(b[posOff:], uint64(nPos)) },
+			func(b []byte) { binary.LittleEndian.PutUint64(b[len(b)-8:], ^uint64(0)) },
+			func(b []byte) { binary.LittleEndian.PutUint32(b[lastFinger:], uint32(nPos+1)) },
+			func(b []byte) { binary.LittleEndian.PutUint32(b[lastFinger:], 0) },
+		} {
+			bad := append([]byte(nil), data...)
+			corrupt(bad)
+			_, err = Load(bytes.NewReader(bad), s.Seq)
+			c.Check(err, check.Equals, ErrNotIndex, check.Commentf("seed %s", seed))
+		}
+
+		bad := append([]byte(nil), data...)
+		bad[8] = 2
+		_, err = Load(bytes.NewReader(bad), s.Seq)
+		c.Check(err, check.Equals, ErrVersion)
+		bad[0] = 'x'
+		_, err = Load(bytes.NewReader(bad), s.Seq)
+		c.Check(err, check.Equals, ErrNotIndex)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmerset
+
+import (
+	"code.google.com/p/biogo/index/kmerindex"
+	"code.google.com/p/biogo/index/succinct"
+	"code.google.com/p/biogo/seq"
+
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// A Bloom is a Bloom filter of k-mers.
+type Bloom struct {
+	k      int
+	m      uint64 // Number of bits.
+	hashes int
+	bits   []uint64
+}
+
+// NewBloom returns an empty Bloom filter for k-mers of length k sized to hold n k-mers with a false
+// positive rate of fpr.
+func NewBloom(k, n int, fpr float64) (*Bloom, error) {
+	if err := checkK(k); err != nil {
+		return nil, err
+	}
+	switch {
+	case n < 1:
+		return nil, ErrBadCapacity
+	case !(fpr > 0 && fpr < 1):
+		return nil, ErrBadRate
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fpr) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) &^ 63
+	hashes := int(math.Ceil(float64(m) / float64(n) * math.Ln2))
+	return &Bloom{
+		k:      k,
+		m:      m,
+		hashes: hashes,
+		bits:   make([]uint64, m/64),
+	}, nil
+}
+
+// K returns the k-mer length of the Bloom filter.
+func (b *Bloom) K() int { return b.k }
+
+// Bits returns the number of bits in the Bloom filter.
+func (b *Bloom) Bits() int { return int(b.m) }
+
+// Hashes returns the number of hash functions used by the Bloom filter.
+func (b *Bloom) Hashes() int { return b.hashes }
+
+// Add adds kmer to the Bloom filter.
+func (b *Bloom) Add(kmer kmerindex.Kmer) {
+	h1, h2 := hash(kmer, 0), hash(kmer, 1)|1
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains returns whether kmer may be in the Bloom filter.
+func (b *Bloom) Contains(kmer kmerindex.Kmer) bool {
+	h1, h2 := hash(kmer, 0), hash(kmer, 1)|1
+	for i := 0; i < b.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// AddSequence adds the canonical k-mers of s to the Bloom filter.
+func (b *Bloom) AddSequence(s seq.Sequence) error {
+	return ForEachCanonical(s, b.k, b.Add)
+}
+
+// FalsePositiveRate returns an estimate of the current false positive rate of the Bloom filter
+// based on the fraction of bits set.
+func (b *Bloom) FalsePositiveRate() float64 {
+	var set int
+	for _, w := range b.bits {
+		set += succinct.PopCount(w)
+	}
+	return math.Pow(float64(set)/float64(b.m), float64(b.hashes))
+}
+
+// Merge adds the k-mers held by o to the Bloom filter. The two filters must have been created
+// with the same parameters.
+func (b *Bloom) Merge(o *Bloom) error {
+	if b.k != o.k || b.m != o.m || b.hashes != o.hashes {
+		return ErrIncompatible
+	}
+	for i, w := range o.bits {
+		b.bits[i] |= w
+	}
+	return nil
+}
+
+// Bloom filter serialisation format.
+//
+// A Bloom filter is written as a little-endian binary record:
+//
+//	magic    [8]byte "bgbloom\x00"
+//	version  uint32
+//	k        uint32
+//	hashes   uint32
+//	_        uint32 padding, written as zero
+//	m        uint64 number of bits
+//	bits     [m/64]uint64
+const (
+	bloomMagic   = "bgbloom\x00"
+	bloomVersion = 1
+)
+
+type bloomHeader struct {
+	Magic   [8]byte
+	Version uint32
+	K       uint32
+	Hashes  uint32
+	_       uint32
+	M       uint64
+}
+
+// Save writes the Bloom filter to w.
+func (b *Bloom) Save(w io.Writer) error {
+	hdr := bloomHeader{
+		Version: bloomVersion,
+		K:       uint32(b.k),
+		Hashes:  uint32(b.hashes),
+		M:       b.m,
+	}
+	copy(hdr.Magic[:], bloomMagic)
+	if err := binary.Write(w, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, b.bits)
+}
+
+// LoadBloom reads a Bloom filter written by Save from r.
+func LoadBloom(r io.Reader) (*Bloom, error) {
+	var hdr bloomHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, truncated(err)
+	}
+	switch {
+	case string(hdr.Magic[:]) != bloomMagic:
+		return nil, ErrNotFilter
+	case hdr.Version != bloomVersion:
+		return nil, ErrVersion
+	case hdr.M == 0 || hdr.M%64 != 0 || hdr.Hashes == 0 || checkK(int(hdr.K)) != nil:
+		return nil, ErrNotFilter
+	}
+	bits, err := readWords(r, hdr.M/64)
+	if err != nil {
+		return nil, err
+	}
+	return &Bloom{
+		k:      int(hdr.K),
+		m:      hdr.M,
+		hashes: int(hdr.Hashes),
+		bits:   bits,
+	}, nil
+}
+
+// readWords reads n little-endian uint64 values from r. The values are read in blocks so that
+// the memory allocated for a truncated stream is bounded by the length of the stream rather than
+// by n.
+func readWords(r io.Reader, n uint64) ([]uint64, error) {
+	const block = 1 << 16
+	if n > uint64(^uint(0)>>1) {
+		return nil, ErrNotFilter
+	}
+	var words []uint64
+	for n > 0 {
+		l := n
+		if l > block {
+			l = block
+		}
+		words = append(words, make([]uint64, l)...)
+		if err := binary.Read(r, binary.LittleEndian, words[uint64(len(words))-l:]); err != nil {
+			return nil, truncated(err)
+		}
+		n -= l
+	}
+	return words, nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncatedFilter
+	}
+	return err
+}
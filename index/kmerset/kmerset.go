@@ -0,0 +1,136 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package kmerset provides compact probabilistic sets of kmerindex.Kmer values for k-mer
+// membership queries, and classification of reads by the fraction of their k-mers held in a set.
+//
+// Two set types are provided. A Bloom filter answers membership queries with a configurable false
+// positive rate. A counting quotient filter additionally holds the number of times each k-mer has
+// been added. Neither type produces false negatives. Sequences are added and queried using their
+// canonical k-mers, the lesser of each k-mer and its reverse complement.
+package kmerset
+
+import (
+	"code.google.com/p/biogo/index/kmerindex"
+	"code.google.com/p/biogo/io/seqio"
+	"code.google.com/p/biogo/seq"
+
+	"errors"
+	"io"
+)
+
+var (
+	ErrKTooLarge       = errors.New("kmerset: k too large")
+	ErrKTooSmall       = errors.New("kmerset: k too small")
+	ErrBadAlphabet     = errors.New("kmerset: alphabet size != 4")
+	ErrBadRate         = errors.New("kmerset: false positive rate out of range")
+	ErrBadCapacity     = errors.New("kmerset: capacity < 1")
+	ErrFull            = errors.New("kmerset: filter full")
+	ErrIncompatible    = errors.New("kmerset: incompatible filters")
+	ErrNotFilter       = errors.New("kmerset: not a serialised filter")
+	ErrVersion         = errors.New("kmerset: unsupported serialisation version")
+	ErrTruncatedFilter = errors.New("kmerset: truncated filter")
+)
+
+// A Set is a set of k-mers that can be queried for membership.
+type Set interface {
+	// K returns the k-mer length of the set.
+	K() int
+
+	// Contains returns whether kmer may be in the set. If
+	// Contains returns false, kmer is not in the set.
+	Contains(kmer kmerindex.Kmer) bool
+}
+
+// checkK returns an error if k is not a valid k-mer length.
+func checkK(k int) error {
+	switch {
+	case k > kmerindex.MaxKmerLen:
+		return ErrKTooLarge
+	case k < 1:
+		return ErrKTooSmall
+	}
+	return nil
+}
+
+// hash returns a hash of kmer with the given seed using the MurmurHash3 64 bit finaliser.
+func hash(kmer kmerindex.Kmer, seed uint64) uint64 {
+	h := uint64(kmer) ^ seed*0x9e3779b97f4a7c15
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// ForEachCanonical calls fn with each canonical k-mer of length k in s. K-mers containing letters
+// that are not valid in the alphabet of s are skipped.
+func ForEachCanonical(s seq.Sequence, k int, fn func(kmer kmerindex.Kmer)) error {
+	if err := checkK(k); err != nil {
+		return err
+	}
+	alpha := s.Alphabet()
+	if alpha == nil || alpha.Len() != 4 {
+		return ErrBadAlphabet
+	}
+	kmerindex.ForEachCanonical64(kmerindex.Letters(s), k, alpha.LetterIndex(), func(_ int, kmer kmerindex.Kmer64) {
+		fn(kmerindex.Kmer(kmer))
+	})
+	return nil
+}
+
+// Hits returns the number of canonical k-mers in s and the number of those that are held by set.
+func Hits(s seq.Sequence, set Set) (kmers, hits int, err error) {
+	err = ForEachCanonical(s, set.K(), func(kmer kmerindex.Kmer) {
+		kmers++
+		if set.Contains(kmer) {
+			hits++
+		}
+	})
+	return kmers, hits, err
+}
+
+// A Classification is the result of classifying a read against a Set.
+type Classification struct {
+	Seq seq.Sequence
+
+	// Kmers is the number of canonical k-mers in the read
+	// and Hits is the number of those held by the Set.
+	Kmers, Hits int
+
+	// Fraction is Hits/Kmers, or zero if the read has no k-mers.
+	Fraction float64
+
+	// Match is true if Fraction is at least the classification threshold.
+	Match bool
+}
+
+// Classify classifies each read in r by the fraction of its k-mers held by set, calling fn with
+// the classification of each read in turn. A read matches if the fraction of its k-mers held by set
+// is at least threshold and it has at least one k-mer. Classify returns after r returns io.EOF, or
+// after an error from r or fn.
+func Classify(r seqio.Reader, set Set, threshold float64, fn func(Classification) error) error {
+	for {
+		s, err := r.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		kmers, hits, err := Hits(s, set)
+		if err != nil {
+			return err
+		}
+		c := Classification{Seq: s, Kmers: kmers, Hits: hits}
+		if kmers > 0 {
+			c.Fraction = float64(hits) / float64(kmers)
+			c.Match = c.Fraction >= threshold
+		}
+		if err = fn(c); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,346 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmerset
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/index/kmerindex"
+	"code.google.com/p/biogo/io/seqio/fasta"
+	"code.google.com/p/biogo/seq/linear"
+
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"testing"
+)
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func randomSeq(name string, n int) *linear.Seq {
+	l := make(alphabet.Letters, n)
+	for i := range l {
+		l[i] = alphabet.Letter("acgt"[rand.Intn(4)])
+	}
+	return linear.NewSeq(name, l, alphabet.DNA)
+}
+
+func canonical(c *check.C, s *linear.Seq, k int) map[kmerindex.Kmer]int {
+	m := make(map[kmerindex.Kmer]int)
+	c.Assert(ForEachCanonical(s, k, func(kmer kmerindex.Kmer) { m[kmer]++ }), check.Equals, nil)
+	return m
+}
+
+func (s *S) TestForEachCanonical(c *check.C) {
+	sq := linear.NewSeq("", alphabet.BytesToLetters([]byte("acgtnaaccgg")), alphabet.DNA)
+	var got []kmerindex.Kmer
+	c.Check(ForEachCanonical(sq, 3, func(kmer kmerindex.Kmer) { got = append(got, kmer) }), check.Equals, nil)
+	// acg, cgt→acg, aac, acc, ccg, cgg→ccg
+	c.Check(got, check.DeepEquals, []kmerindex.Kmer{0x06, 0x06, 0x01, 0x05, 0x16, 0x16})
+
+	rand.Seed(1)
+	sq = randomSeq("", 1000)
+	rc := linear.NewSeq("", append(alphabet.Letters(nil), sq.Seq...), alphabet.DNA)
+	rc.RevComp()
+	c.Check(canonical(c, rc, 11), check.DeepEquals, canonical(c, sq, 11))
+
+	c.Check(ForEachCanonical(sq, 0, nil), check.Equals, ErrKTooSmall)
+	c.Check(ForEachCanonical(sq, kmerindex.MaxKmerLen+1, nil), check.Equals, ErrKTooLarge)
+	c.Check(ForEachCanonical(linear.NewSeq("", nil, alphabet.Protein), 5, nil), check.Equals, ErrBadAlphabet)
+}
+
+func (s *S) TestNew(c *check.C) {
+	for _, t := range []struct {
+		k, n int
+		fpr  float64
+		err  error
+	}{
+		{k: 0, n: 10, fpr: 0.01, err: ErrKTooSmall},
+		{k: kmerindex.MaxKmerLen + 1, n: 10, fpr: 0.01, err: ErrKTooLarge},
+		{k: 11, n: 0, fpr: 0.01, err: ErrBadCapacity},
+		{k: 11, n: 10, fpr: 0, err: ErrBadRate},
+		{k: 11, n: 10, fpr: 1, err: ErrBadRate},
+		{k: 11, n: 10, fpr: 0.01, err: nil},
+	} {
+		_, err := NewBloom(t.k, t.n, t.fpr)
+		c.Check(err, check.Equals, t.err, check.Commentf("%+v", t))
+		_, err = NewCountingQuotient(t.k, t.n, t.fpr)
+		c.Check(err, check.Equals, t.err, check.Commentf("%+v", t))
+	}
+
+	b, err := NewBloom(11, 1000, 0.01)
+	c.Assert(err, check.Equals, nil)
+	c.Check(b.Bits(), check.Equals, 9600)
+	c.Check(b.Hashes(), check.Equals, 7)
+
+	q, err := NewCountingQuotient(11, 1000, 0.01)
+	c.Assert(err, check.Equals, nil)
+	c.Check(q.Capacity(), check.Equals, 2048)
+}
+
+// testSet checks that set has no false negatives for the k-mers of member and returns the
+// fraction of k-mers of other that are reported as held.
+func testSet(c *check.C, set Set, member, other *linear.Seq) float64 {
+	for kmer := range canonical(c, member, set.K()) {
+		c.Assert(set.Contains(kmer), check.Equals, true)
+	}
+	in := canonical(c, member, set.K())
+	var n, fp int
+	for kmer := range canonical(c, other, set.K()) {
+		if _, ok := in[kmer]; ok {
+			continue
+		}
+		n++
+		if set.Contains(kmer) {
+			fp++
+		}
+	}
+	return float64(fp) / float64(n)
+}
+
+func (s *S) TestFalsePositiveRate(c *check.C) {
+	rand.Seed(2)
+	const (
+		k = 15
+		n = 20000
+	)
+	member, other := randomSeq("member", n+k-1), randomSeq("other", 100000)
+	for _, fpr := range []float64{0.1, 0.01, 0.001} {
+		b, err := NewBloom(k, n, fpr)
+		c.Assert(err, check.Equals, nil)
+		c.Assert(b.AddSequence(member), check.Equals, nil)
+		got := testSet(c, b, member, other)
+		c.Check(got < 1.5*fpr+0.001, check.Equals, true, check.Commentf("Bloom fpr=%v got=%v", fpr, got))
+		c.Check(got > 0.5*fpr-0.001, check.Equals, true, check.Commentf("Bloom fpr=%v got=%v", fpr, got))
+		est := b.FalsePositiveRate()
+		c.Check(est < 1.5*fpr && est > 0.5*fpr, check.Equals, true, check.Commentf("Bloom fpr=%v est=%v", fpr, est))
+
+		q, err := NewCountingQuotient(k, n, fpr)
+		c.Assert(err, check.Equals, nil)
+		c.Assert(q.AddSequence(member), check.Equals, nil)
+		got = testSet(c, q, member, other)
+		c.Check(got < fpr+0.001, check.Equals, true, check.Commentf("CountingQuotient fpr=%v got=%v", fpr, got))
+	}
+}
+
+func (s *S) TestCounts(c *check.C) {
+	rand.Seed(3)
+	const k = 13
+	q, err := NewCountingQuotient(k, 5000, 1e-6)
+	c.Assert(err, check.Equals, nil)
+	want := make(map[kmerindex.Kmer]uint64)
+	for i := 0; i < 20; i++ {
+		sq := randomSeq("", 200+rand.Intn(200))
+		c.Assert(q.AddSequence(sq), check.Equals, nil)
+		for kmer, n := range canonical(c, sq, k) {
+			want[kmer] += uint64(n)
+		}
+		// Repeated sequence to give large counts.
+		if i%5 == 0 {
+			for j := 0; j < 10; j++ {
+				c.Assert(q.AddSequence(sq), check.Equals, nil)
+				for kmer, n := range canonical(c, sq, k) {
+					want[kmer] += uint64(n)
+				}
+			}
+		}
+	}
+	c.Check(q.Len(), check.Equals, len(want))
+	for kmer, n := range want {
+		c.Check(q.Count(kmer), check.Equals, n)
+	}
+	for i := 0; i < 1000; i++ {
+		kmer := kmerindex.Kmer(rand.Int63n(1 << (2 * k)))
+		if _, ok := want[kmer]; !ok {
+			c.Check(q.Count(kmer), check.Equals, uint64(0))
+		}
+	}
+}
+
+func (s *S) TestFull(c *check.C) {
+	q, err := NewCountingQuotient(11, 3, 0.01)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(q.Capacity(), check.Equals, 4)
+	var added []kmerindex.Kmer
+	for kmer := kmerindex.Kmer(0); len(added) < q.Capacity(); kmer++ {
+		if q.Contains(kmer) {
+			continue
+		}
+		c.Assert(q.Add(kmer), check.Equals, nil)
+		added = append(added, kmer)
+	}
+	c.Check(q.Len(), check.Equals, 4)
+	for _, kmer := range added {
+		c.Check(q.Count(kmer), check.Equals, uint64(1))
+	}
+	var err2 error
+	for kmer := added[len(added)-1] + 1; err2 == nil; kmer++ {
+		if !q.Contains(kmer) {
+			err2 = q.Add(kmer)
+		}
+	}
+	c.Check(err2, check.Equals, ErrFull)
+	// Held k-mers can still be counted.
+	c.Check(q.Add(added[0]), check.Equals, nil)
+	c.Check(q.Count(added[0]), check.Equals, uint64(2))
+}
+
+func (s *S) TestMerge(c *check.C) {
+	rand.Seed(4)
+	const k = 12
+	a, b := randomSeq("a", 5000), randomSeq("b", 5000)
+
+	ba, err := NewBloom(k, 10000, 0.01)
+	c.Assert(err, check.Equals, nil)
+	bb, err := NewBloom(k, 10000, 0.01)
+	c.Assert(err, check.Equals, nil)
+	both, err := NewBloom(k, 10000, 0.01)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(ba.AddSequence(a), check.Equals, nil)
+	c.Assert(bb.AddSequence(b), check.Equals, nil)
+	c.Assert(both.AddSequence(a), check.Equals, nil)
+	c.Assert(both.AddSequence(b), check.Equals, nil)
+	c.Check(ba.Merge(bb), check.Equals, nil)
+	c.Check(ba.bits, check.DeepEquals, both.bits)
+	other, err := NewBloom(k, 100, 0.01)
+	c.Assert(err, check.Equals, nil)
+	c.Check(ba.Merge(other), check.Equals, ErrIncompatible)
+
+	qa, err := NewCountingQuotient(k, 10000, 0.001)
+	c.Assert(err, check.Equals, nil)
+	qb, err := NewCountingQuotient(k, 10000, 0.001)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(qa.AddSequence(a), check.Equals, nil)
+	c.Assert(qb.AddSequence(b), check.Equals, nil)
+	c.Assert(qb.AddSequence(a), check.Equals, nil)
+	c.Check(qa.Merge(qb), check.Equals, nil)
+	want := canonical(c, a, k)
+	for kmer, n := range canonical(c, b, k) {
+		want[kmer] += n
+	}
+	for kmer, n := range want {
+		c.Check(qa.Count(kmer) >= uint64(n), check.Equals, true)
+	}
+	for kmer, n := range canonical(c, a, k) {
+		c.Check(qa.Count(kmer) >= 2*uint64(n), check.Equals, true)
+	}
+	oq, err := NewCountingQuotient(k, 100, 0.001)
+	c.Assert(err, check.Equals, nil)
+	c.Check(qa.Merge(oq), check.Equals, ErrIncompatible)
+}
+
+func (s *S) TestSaveLoad(c *check.C) {
+	rand.Seed(5)
+	sq := randomSeq("", 3000)
+
+	b, err := NewBloom(14, 3000, 0.01)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(b.AddSequence(sq), check.Equals, nil)
+	var buf bytes.Buffer
+	c.Assert(b.Save(&buf), check.Equals, nil)
+	data := buf.Bytes()
+	gb, err := LoadBloom(bytes.NewReader(data))
+	c.Assert(err, check.Equals, nil)
+	c.Check(gb, check.DeepEquals, b)
+	_, err = LoadBloom(bytes.NewReader(data[:len(data)-1]))
+	c.Check(err, check.Equals, ErrTruncatedFilter)
+	_, err = LoadCountingQuotient(bytes.NewReader(data))
+	c.Check(err, check.Equals, ErrNotFilter)
+
+	q, err := NewCountingQuotient(14, 3000, 0.01)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(q.AddSequence(sq), check.Equals, nil)
+	buf.Reset()
+	c.Assert(q.Save(&buf), check.Equals, nil)
+	data = buf.Bytes()
+	gq, err := LoadCountingQuotient(bytes.NewReader(data))
+	c.Assert(err, check.Equals, nil)
+	c.Check(gq, check.DeepEquals, q)
+	_, err = LoadCountingQuotient(bytes.NewReader(data[:10]))
+	c.Check(err, check.Equals, ErrTruncatedFilter)
+	_, err = LoadBloom(bytes.NewReader(data))
+	c.Check(err, check.Equals, ErrNotFilter)
+
+	data[8] = 2
+	_, err = LoadCountingQuotient(bytes.NewReader(data))
+	c.Check(err, check.Equals, ErrVersion)
+	data[8] = 1
+
+	// Shifting every slot, or every non-empty slot, leaves no cluster
+	// start for searches to find.
+	const slots = 32
+	for _, all := range []bool{true, false} {
+		corrupt := append([]byte(nil), data...)
+		for i := slots; i < slots+8*gq.Capacity(); i += 8 {
+			if all || corrupt[i]&metaMask != 0 {
+				corrupt[i] |= shifted
+			}
+		}
+		_, err = LoadCountingQuotient(bytes.NewReader(corrupt))
+		c.Check(err, check.Equals, ErrNotFilter, check.Commentf("all slots: %t", all))
+	}
+
+	// Headers describing very large filters are rejected when
+	// the stream is short, without allocating the whole filter.
+	hdr := append([]byte(nil), data[:slots]...)
+	binary.LittleEndian.PutUint32(hdr[16:], 40)
+	binary.LittleEndian.PutUint32(hdr[20:], 1)
+	_, err = LoadCountingQuotient(bytes.NewReader(hdr))
+	c.Check(err, check.Equals, ErrTruncatedFilter)
+	bhdr := bloomHeader{Version: bloomVersion, K: 14, Hashes: 1, M: 1 << 62}
+	copy(bhdr.Magic[:], bloomMagic)
+	buf.Reset()
+	c.Assert(binary.Write(&buf, binary.LittleEndian, &bhdr), check.Equals, nil)
+	_, err = LoadBloom(&buf)
+	c.Check(err, check.Equals, ErrTruncatedFilter)
+}
+
+func (s *S) TestClassify(c *check.C) {
+	rand.Seed(6)
+	const k = 15
+	host := randomSeq("host", 50000)
+	set, err := NewCountingQuotient(k, host.Len(), 0.001)
+	c.Assert(err, check.Equals, nil)
+	c.Assert(set.AddSequence(host), check.Equals, nil)
+
+	var (
+		buf  bytes.Buffer
+		want []bool
+	)
+	for i := 0; i < 50; i++ {
+		var sq *linear.Seq
+		if rand.Intn(2) == 0 {
+			start := rand.Intn(host.Len() - 150)
+			sq = linear.NewSeq(fmt.Sprintf("host_%d", i), append(alphabet.Letters(nil), host.Seq[start:start+150]...), alphabet.DNA)
+			if rand.Intn(2) == 0 {
+				sq.RevComp()
+			}
+			want = append(want, true)
+		} else {
+			sq = randomSeq(fmt.Sprintf("other_%d", i), 150)
+			want = append(want, false)
+		}
+		fmt.Fprintf(&buf, "%60a\n", sq)
+	}
+	fmt.Fprintf(&buf, ">short\nacgt\n")
+	want = append(want, false)
+
+	var got []bool
+	r := fasta.NewReader(&buf, linear.NewSeq("", nil, alphabet.DNA))
+	c.Assert(Classify(r, set, 0.8, func(cl Classification) error {
+		got = append(got, cl.Match)
+		if cl.Match {
+			c.Check(cl.Hits, check.Equals, cl.Kmers)
+		}
+		return nil
+	}), check.Equals, nil)
+	c.Check(got, check.DeepEquals, want)
+}
@@ -0,0 +1,381 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package kmerset
+
+import (
+	"code.google.com/p/biogo/index/kmerindex"
+	"code.google.com/p/biogo/seq"
+
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// MaxLoad is the maximum load factor used to size a CountingQuotient filter.
+const MaxLoad = 0.75
+
+// Slot metadata bits.
+const (
+	occupied     = 1 << iota // The slot is the canonical slot of a stored quotient.
+	continuation             // The slot holds a remainder that is not the first of its run.
+	shifted                  // The slot holds a remainder that is not in its canonical slot.
+
+	metaBits = 3
+	metaMask = 1<<metaBits - 1
+)
+
+// A CountingQuotient is a counting quotient filter of k-mers. Each k-mer is represented by a
+// fingerprint of q+r bits taken from its hash; the q bit quotient identifies a canonical slot
+// and the r bit remainder is stored in the slot, or in a following slot when the canonical slot
+// is in use, together with the number of times the fingerprint has been added. The false positive
+// rate of the filter is at most 2^-r.
+//
+// The filter is described in Bender et al. "Don't thrash: how to cache your hash on flash."
+// Proc. VLDB Endow. 5:1627–1637 (2012), and extended with counts in Pandey et al. "A
+// general-purpose counting filter: making every bit count." SIGMOD '17 775–787 (2017).
+type CountingQuotient struct {
+	k    int
+	q, r uint
+
+	// slots holds the remainder of each slot shifted
+	// above the metadata bits, and counts holds the
+	// number of times the fingerprint has been added.
+	slots  []uint64
+	counts []uint64
+
+	entries int
+}
+
+// NewCountingQuotient returns an empty counting quotient filter for k-mers of length k sized to
+// hold n distinct k-mers with a false positive rate of at most fpr.
+func NewCountingQuotient(k, n int, fpr float64) (*CountingQuotient, error) {
+	if err := checkK(k); err != nil {
+		return nil, err
+	}
+	switch {
+	case n < 1:
+		return nil, ErrBadCapacity
+	case !(fpr > 0 && fpr < 1):
+		return nil, ErrBadRate
+	}
+	q := uint(math.Ceil(math.Log2(float64(n) / MaxLoad)))
+	if q < 1 {
+		q = 1
+	}
+	r := uint(math.Ceil(-math.Log2(fpr)))
+	if r < 1 {
+		r = 1
+	}
+	if q+r > 64 || r > 64-metaBits {
+		return nil, ErrBadRate
+	}
+	return &CountingQuotient{
+		k:      k,
+		q:      q,
+		r:      r,
+		slots:  make([]uint64, 1<<q),
+		counts: make([]uint64, 1<<q),
+	}, nil
+}
+
+// K returns the k-mer length of the filter.
+func (f *CountingQuotient) K() int { return f.k }
+
+// Len returns the number of distinct fingerprints held by the filter.
+func (f *CountingQuotient) Len() int { return f.entries }
+
+// Capacity returns the number of slots in the filter.
+func (f *CountingQuotient) Capacity() int { return len(f.slots) }
+
+// fingerprint returns the quotient and remainder of the fingerprint of kmer.
+func (f *CountingQuotient) fingerprint(kmer kmerindex.Kmer) (fq, fr uint64) {
+	h := hash(kmer, 0) >> (64 - f.q - f.r)
+	return h >> f.r, h & (1<<f.r - 1)
+}
+
+func (f *CountingQuotient) inc(i uint64) uint64 { return (i + 1) & (1<<f.q - 1) }
+func (f *CountingQuotient) dec(i uint64) uint64 { return (i - 1) & (1<<f.q - 1) }
+
+// runStart returns the slot holding the first remainder of the run of quotient fq. The occupied
+// bit of fq must be set.
+func (f *CountingQuotient) runStart(fq uint64) uint64 {
+	// Find the start of the cluster holding fq.
+	b := fq
+	for f.slots[b]&shifted != 0 {
+		b = f.dec(b)
+	}
+	// Walk runs from the start of the cluster until the run of fq
+	// is reached. b tracks occupied quotients and s their runs.
+	s := b
+	for b != fq {
+		for {
+			s = f.inc(s)
+			if f.slots[s]&continuation == 0 {
+				break
+			}
+		}
+		for {
+			b = f.inc(b)
+			if f.slots[b]&occupied != 0 {
+				break
+			}
+		}
+	}
+	return s
+}
+
+// Add adds kmer to the filter, incrementing its count.
+func (f *CountingQuotient) Add(kmer kmerindex.Kmer) error {
+	fq, fr := f.fingerprint(kmer)
+	return f.add(fq, fr, 1)
+}
+
+// add adds n to the count of the fingerprint with quotient fq and remainder fr.
+func (f *CountingQuotient) add(fq, fr, n uint64) error {
+	if n == 0 {
+		return nil
+	}
+	entry := fr << metaBits
+	if f.slots[fq]&metaMask == 0 {
+		f.slots[fq], f.counts[fq] = entry|occupied, n
+		f.entries++
+		return nil
+	}
+
+	var s uint64
+	if f.slots[fq]&occupied != 0 {
+		// Search the run of fq for fr or its position in the run.
+		start := f.runStart(fq)
+		s = start
+		for {
+			rem := f.slots[s] >> metaBits
+			if rem == fr {
+				f.counts[s] += n
+				return nil
+			}
+			if rem > fr {
+				break
+			}
+			s = f.inc(s)
+			if f.slots[s]&continuation == 0 {
+				break
+			}
+		}
+		if f.entries >= len(f.slots) {
+			return ErrFull
+		}
+		if s == start {
+			// The new remainder becomes the head of the run.
+			f.slots[start] |= continuation
+		} else {
+			entry |= continuation
+		}
+	} else {
+		if f.entries >= len(f.slots) {
+			return ErrFull
+		}
+		f.slots[fq] |= occupied
+		s = f.runStart(fq)
+	}
+	if s != fq {
+		entry |= shifted
+	}
+	f.insertAt(s, entry, n)
+	f.entries++
+	return nil
+}
+
+// insertAt inserts the slot entry with count n at slot s, shifting following slots up to the next
+// empty slot. Occupied bits remain with their slots.
+func (f *CountingQuotient) insertAt(s, entry, n uint64) {
+	for {
+		prev, pn := f.slots[s], f.counts[s]
+		empty := prev&metaMask == 0
+		if !empty {
+			prev |= shifted
+			if prev&occupied != 0 {
+				entry |= occupied
+				prev &^= occupied
+			}
+		}
+		f.slots[s], f.counts[s] = entry, n
+		if empty {
+			return
+		}
+		entry, n = prev, pn
+		s = f.inc(s)
+	}
+}
+
+// Count returns the number of times kmer may have been added to the filter. The returned count is
+// never less than the true count, but may be greater if the fingerprint of kmer collides with that
+// of another added k-mer.
+func (f *CountingQuotient) Count(kmer kmerindex.Kmer) uint64 {
+	fq, fr := f.fingerprint(kmer)
+	return f.count(fq, fr)
+}
+
+func (f *CountingQuotient) count(fq, fr uint64) uint64 {
+	if f.slots[fq]&occupied == 0 {
+		return 0
+	}
+	s := f.runStart(fq)
+	for {
+		rem := f.slots[s] >> metaBits
+		if rem == fr {
+			return f.counts[s]
+		}
+		if rem > fr {
+			return 0
+		}
+		s = f.inc(s)
+		if f.slots[s]&continuation == 0 {
+			return 0
+		}
+	}
+}
+
+// Contains returns whether kmer may be in the filter.
+func (f *CountingQuotient) Contains(kmer kmerindex.Kmer) bool { return f.Count(kmer) != 0 }
+
+// AddSequence adds the canonical k-mers of s to the filter.
+func (f *CountingQuotient) AddSequence(s seq.Sequence) error {
+	var err error
+	ferr := ForEachCanonical(s, f.k, func(kmer kmerindex.Kmer) {
+		if err == nil {
+			err = f.Add(kmer)
+		}
+	})
+	if ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+// Merge adds the fingerprints and counts held by o to the filter. The two filters must have been
+// created with the same parameters.
+func (f *CountingQuotient) Merge(o *CountingQuotient) error {
+	if f.k != o.k || f.q != o.q || f.r != o.r {
+		return ErrIncompatible
+	}
+	for fq := range o.slots {
+		if o.slots[fq]&occupied == 0 {
+			continue
+		}
+		s := o.runStart(uint64(fq))
+		for {
+			if err := f.add(uint64(fq), o.slots[s]>>metaBits, o.counts[s]); err != nil {
+				return err
+			}
+			s = o.inc(s)
+			if o.slots[s]&continuation == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Counting quotient filter serialisation format.
+//
+// A counting quotient filter is written as a little-endian binary record:
+//
+//	magic    [8]byte "bgcqf\x00\x00\x00"
+//	version  uint32
+//	k        uint32
+//	q        uint32
+//	r        uint32
+//	entries  uint64
+//	slots    [1<<q]uint64
+//	counts   [1<<q]uint64
+const (
+	cqfMagic   = "bgcqf\x00\x00\x00"
+	cqfVersion = 1
+)
+
+type cqfHeader struct {
+	Magic   [8]byte
+	Version uint32
+	K       uint32
+	Q       uint32
+	R       uint32
+	Entries uint64
+}
+
+// Save writes the filter to w.
+func (f *CountingQuotient) Save(w io.Writer) error {
+	hdr := cqfHeader{
+		Version: cqfVersion,
+		K:       uint32(f.k),
+		Q:       uint32(f.q),
+		R:       uint32(f.r),
+		Entries: uint64(f.entries),
+	}
+	copy(hdr.Magic[:], cqfMagic)
+	if err := binary.Write(w, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, f.slots); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, f.counts)
+}
+
+// LoadCountingQuotient reads a counting quotient filter written by Save from r.
+func LoadCountingQuotient(r io.Reader) (*CountingQuotient, error) {
+	var hdr cqfHeader
+	if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+		return nil, truncated(err)
+	}
+	switch {
+	case string(hdr.Magic[:]) != cqfMagic:
+		return nil, ErrNotFilter
+	case hdr.Version != cqfVersion:
+		return nil, ErrVersion
+	case hdr.Q < 1 || hdr.Q > 40 || hdr.R < 1 || hdr.Q+hdr.R > 64 || hdr.R > 64-metaBits ||
+		hdr.Entries > 1<<hdr.Q || checkK(int(hdr.K)) != nil:
+		return nil, ErrNotFilter
+	}
+	slots, err := readWords(r, 1<<hdr.Q)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := readWords(r, 1<<hdr.Q)
+	if err != nil {
+		return nil, err
+	}
+	f := &CountingQuotient{
+		k:       int(hdr.K),
+		q:       uint(hdr.Q),
+		r:       uint(hdr.R),
+		slots:   slots,
+		counts:  counts,
+		entries: int(hdr.Entries),
+	}
+	if !f.valid() {
+		return nil, ErrNotFilter
+	}
+	return f, nil
+}
+
+// valid returns whether the slot metadata of f is consistent with its entry count. Every
+// unshifted non-empty slot must hold the head of its own run, and a filter with entries must
+// have at least one such slot, otherwise cluster and run searches would not terminate.
+func (f *CountingQuotient) valid() bool {
+	var n, heads int
+	for _, s := range f.slots {
+		if s&metaMask == 0 {
+			continue
+		}
+		n++
+		if s&shifted == 0 {
+			if s&(occupied|continuation) != occupied {
+				return false
+			}
+			heads++
+		}
+	}
+	return n == f.entries && (n == 0 || heads != 0)
+}
@@ -0,0 +1,306 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package minhash provides bottom-s MinHash sketching of canonical k-mers and Mash distance
+// estimation between sketches.
+//
+// A sketch holds the s smallest distinct hashes of the canonical k-mers of a set of sequences.
+// The Jaccard index of the k-mer sets of two sequence sets is estimated from the fraction of the
+// s smallest hashes of the union of their sketches that is present in both sketches. The Mash
+// distance, an estimate of the per-base mutation rate, and the probability of observing at least
+// as many shared hashes by chance are derived from the Jaccard estimate as described in Ondov et
+// al. 2016 doi:10.1186/s13059-016-0997-x.
+package minhash
+
+import (
+	"code.google.com/p/biogo/index/kmerindex"
+	"code.google.com/p/biogo/seq"
+
+	"container/heap"
+	"errors"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrKTooLarge   = errors.New("minhash: k too large")
+	ErrKTooSmall   = errors.New("minhash: k too small")
+	ErrBadSize     = errors.New("minhash: sketch size < 1")
+	ErrBadAlphabet = errors.New("minhash: alphabet size != 4")
+	ErrKMismatch   = errors.New("minhash: k-mer length mismatch")
+)
+
+// MaxKmerLen is the maximum k-mer length that can be sketched.
+const MaxKmerLen = 32
+
+// hash returns the hash of a 2-bit encoded k-mer using the MurmurHash3 64 bit finaliser.
+func hash(kmer uint64) uint64 {
+	kmer ^= kmer >> 33
+	kmer *= 0xff51afd7ed558ccd
+	kmer ^= kmer >> 33
+	kmer *= 0xc4ceb9fe1a85ec53
+	kmer ^= kmer >> 33
+	return kmer
+}
+
+// A Sketch is a bottom-s MinHash sketch of the canonical k-mers of a set of sequences.
+type Sketch struct {
+	// Name is the name of the sketched sequence set.
+	Name string
+
+	k    int
+	size int
+
+	// kmers is the number of valid k-mers added to the sketch,
+	// used as the size of the k-mer set for p-value estimation.
+	kmers int64
+
+	hashes maxHeap
+	set    map[uint64]struct{}
+	sorted bool
+}
+
+// NewSketch returns an empty Sketch of k-mers of length k holding at most size hashes.
+func NewSketch(k, size int) (*Sketch, error) { return newSketch(k, size, size) }
+
+// newSketch returns an empty Sketch of k-mers of length k holding at most size hashes, with its
+// hash set sized to hold n hashes.
+func newSketch(k, size, n int) (*Sketch, error) {
+	switch {
+	case k > MaxKmerLen:
+		return nil, ErrKTooLarge
+	case k < 1:
+		return nil, ErrKTooSmall
+	case size < 1:
+		return nil, ErrBadSize
+	}
+	return &Sketch{
+		k:    k,
+		size: size,
+		set:  make(map[uint64]struct{}, n),
+	}, nil
+}
+
+// K returns the k-mer length of the Sketch.
+func (s *Sketch) K() int { return s.k }
+
+// Size returns the maximum number of hashes held by the Sketch.
+func (s *Sketch) Size() int { return s.size }
+
+// Kmers returns the number of valid k-mers that have been added to the Sketch.
+func (s *Sketch) Kmers() int64 { return s.kmers }
+
+// Hashes returns the hashes held by the Sketch in increasing order. The returned slice should not
+// be altered.
+func (s *Sketch) Hashes() []uint64 {
+	if !s.sorted {
+		sort.Sort(uint64s(s.hashes))
+		s.sorted = true
+	}
+	return []uint64(s.hashes)
+}
+
+// Add adds the canonical k-mers of sq to the Sketch. K-mers containing letters that are not valid
+// in the alphabet of sq are skipped.
+func (s *Sketch) Add(sq seq.Sequence) error {
+	alpha := sq.Alphabet()
+	if alpha == nil || alpha.Len() != 4 {
+		return ErrBadAlphabet
+	}
+	if s.sorted {
+		heap.Init(&s.hashes)
+		s.sorted = false
+	}
+
+	kmerindex.ForEachCanonical64(kmerindex.Letters(sq), s.k, alpha.LetterIndex(), func(_ int, kmer kmerindex.Kmer64) {
+		s.kmers++
+		s.push(hash(uint64(kmer)))
+	})
+	return nil
+}
+
+// push adds h to the sketch if it is smaller than the largest hash held.
+func (s *Sketch) push(h uint64) {
+	if len(s.hashes) == s.size && h >= s.hashes[0] {
+		return
+	}
+	if _, ok := s.set[h]; ok {
+		return
+	}
+	s.set[h] = struct{}{}
+	heap.Push(&s.hashes, h)
+	if len(s.hashes) > s.size {
+		delete(s.set, heap.Pop(&s.hashes).(uint64))
+	}
+}
+
+// Merge adds the hashes of o to the Sketch, so that the Sketch represents the union of the two
+// sketched sequence sets.
+func (s *Sketch) Merge(o *Sketch) error {
+	if o.k != s.k {
+		return ErrKMismatch
+	}
+	if s.sorted {
+		heap.Init(&s.hashes)
+		s.sorted = false
+	}
+	for _, h := range o.hashes {
+		s.push(h)
+	}
+	s.kmers += o.kmers
+	return nil
+}
+
+type uint64s []uint64
+
+func (h uint64s) Len() int           { return len(h) }
+func (h uint64s) Less(i, j int) bool { return h[i] < h[j] }
+func (h uint64s) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+// maxHeap is a max-heap of hashes.
+type maxHeap []uint64
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Jaccard returns an estimate of the Jaccard index of the k-mer sets sketched by a and b, and the
+// number of hashes shared by the sketches out of the number of the smallest hashes of their union
+// that were compared. The number compared is the lesser of the sizes of the two sketches, or the
+// number of hashes in the union if that is smaller.
+func Jaccard(a, b *Sketch) (j float64, shared, compared int, err error) {
+	if a.k != b.k {
+		return 0, 0, 0, ErrKMismatch
+	}
+	size := a.size
+	if b.size < size {
+		size = b.size
+	}
+	ha, hb := a.Hashes(), b.Hashes()
+	var i, k int
+	for compared < size && (i < len(ha) || k < len(hb)) {
+		switch {
+		case k == len(hb) || (i < len(ha) && ha[i] < hb[k]):
+			i++
+		case i == len(ha) || hb[k] < ha[i]:
+			k++
+		default:
+			shared++
+			i++
+			k++
+		}
+		compared++
+	}
+	if compared == 0 {
+		return 0, 0, 0, nil
+	}
+	return float64(shared) / float64(compared), shared, compared, nil
+}
+
+// MashDistance returns the Mash distance corresponding to the Jaccard index j of k-mer sets of
+// length k. If j is zero, the distance is 1.
+func MashDistance(j float64, k int) float64 {
+	if j <= 0 {
+		return 1
+	}
+	d := -math.Log(2*j/(1+j)) / float64(k)
+	if d > 1 {
+		return 1
+	}
+	return d
+}
+
+// PValue returns the probability of observing at least shared hashes in common between sketches
+// of compared hashes by chance, given random k-mer sets with m and n members drawn from the space
+// of 4^k k-mers.
+func PValue(shared, compared, k int, m, n int64) float64 {
+	if shared <= 0 || m <= 0 || n <= 0 {
+		return 1
+	}
+	space := math.Pow(4, float64(k))
+	px := 1 / (1 + space/float64(m))
+	py := 1 / (1 + space/float64(n))
+	r := px * py / (px + py - px*py)
+
+	// P(X >= shared) for X ~ Binomial(compared, r).
+	return incBeta(float64(shared), float64(compared-shared+1), r)
+}
+
+// A Distance holds the estimated distance between two sketched sequence sets.
+type Distance struct {
+	Jaccard  float64
+	Mash     float64
+	PValue   float64
+	Shared   int
+	Compared int
+}
+
+// Compare returns the estimated distance between the sequence sets sketched by a and b.
+func Compare(a, b *Sketch) (Distance, error) {
+	j, shared, compared, err := Jaccard(a, b)
+	if err != nil {
+		return Distance{}, err
+	}
+	return Distance{
+		Jaccard:  j,
+		Mash:     MashDistance(j, a.k),
+		PValue:   PValue(shared, compared, a.k, a.kmers, b.kmers),
+		Shared:   shared,
+		Compared: compared,
+	}, nil
+}
+
+// Matrix returns the all-versus-all distance matrix of the provided sketches using the given
+// number of threads. If threads is less than one or greater than GOMAXPROCS, GOMAXPROCS threads
+// are used. The returned matrix is symmetric.
+func Matrix(sketches []*Sketch, threads int) ([][]Distance, error) {
+	if available := runtime.GOMAXPROCS(0); threads > available || threads < 1 {
+		threads = available
+	}
+	for _, s := range sketches {
+		if s.k != sketches[0].k {
+			return nil, ErrKMismatch
+		}
+	}
+	// Sort hashes before concurrent comparison.
+	for _, s := range sketches {
+		s.Hashes()
+	}
+
+	d := make([][]Distance, len(sketches))
+	for i := range d {
+		d[i] = make([]Distance, len(sketches))
+	}
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	for t := 0; t < threads; t++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rows {
+				for j := i; j < len(sketches); j++ {
+					// Errors are not possible since k has been checked.
+					d[i][j], _ = Compare(sketches[i], sketches[j])
+					d[j][i] = d[i][j]
+				}
+			}
+		}()
+	}
+	for i := range sketches {
+		rows <- i
+	}
+	close(rows)
+	wg.Wait()
+
+	return d, nil
+}
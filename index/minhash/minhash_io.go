@@ -0,0 +1,124 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package minhash
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Sketch format.
+//
+// A sketch is written as a little-endian binary record, so multiple sketches may be written to
+// and read from a single stream:
+//
+//	magic    [8]byte "bgmnhsh\x00"
+//	version  uint32
+//	k        uint32
+//	size     uint32
+//	n        uint32 number of hashes
+//	kmers    int64
+//	name     uint32 length followed by the name bytes
+//	hashes   [n]uint64 in increasing order
+const (
+	sketchMagic   = "bgmnhsh\x00"
+	sketchVersion = 1
+)
+
+var (
+	ErrNotSketch = errors.New("minhash: not a sketch")
+	ErrVersion   = errors.New("minhash: unsupported sketch version")
+	ErrTruncated = errors.New("minhash: truncated sketch")
+	ErrCorrupt   = errors.New("minhash: corrupt sketch")
+)
+
+type sketchHeader struct {
+	Magic   [8]byte
+	Version uint32
+	K       uint32
+	Size    uint32
+	N       uint32
+	Kmers   int64
+	NameLen uint32
+}
+
+// Save writes the Sketch to w.
+func (s *Sketch) Save(w io.Writer) error {
+	h := s.Hashes()
+	hdr := sketchHeader{
+		Version: sketchVersion,
+		K:       uint32(s.k),
+		Size:    uint32(s.size),
+		N:       uint32(len(h)),
+		Kmers:   s.kmers,
+		NameLen: uint32(len(s.Name)),
+	}
+	copy(hdr.Magic[:], sketchMagic)
+	if err := binary.Write(w, binary.LittleEndian, &hdr); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, s.Name); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, h)
+}
+
+// Load reads a Sketch written by Save from r. Load reads no further than the end of the sketch,
+// so sketches written consecutively to a stream may be read by repeated calls to Load. If r is
+// at the end of the stream, Load returns io.EOF.
+func Load(r io.Reader) (*Sketch, error) {
+	var hdr sketchHeader
+	err := binary.Read(r, binary.LittleEndian, &hdr)
+	switch err {
+	case nil:
+	case io.EOF:
+		return nil, io.EOF
+	case io.ErrUnexpectedEOF:
+		return nil, ErrTruncated
+	default:
+		return nil, err
+	}
+	switch {
+	case string(hdr.Magic[:]) != sketchMagic:
+		return nil, ErrNotSketch
+	case hdr.Version != sketchVersion:
+		return nil, ErrVersion
+	case hdr.N > hdr.Size:
+		return nil, ErrCorrupt
+	}
+	s, err := newSketch(int(hdr.K), int(hdr.Size), int(hdr.N))
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	s.kmers = hdr.Kmers
+
+	name := make([]byte, hdr.NameLen)
+	if _, err = io.ReadFull(r, name); err != nil {
+		return nil, truncated(err)
+	}
+	s.Name = string(name)
+
+	s.hashes = make(maxHeap, hdr.N)
+	if err = binary.Read(r, binary.LittleEndian, []uint64(s.hashes)); err != nil {
+		return nil, truncated(err)
+	}
+	for i, h := range s.hashes {
+		if i > 0 && h <= s.hashes[i-1] {
+			return nil, ErrCorrupt
+		}
+		s.set[h] = struct{}{}
+	}
+	s.sorted = true
+
+	return s, nil
+}
+
+func truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTruncated
+	}
+	return err
+}
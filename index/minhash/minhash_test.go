@@ -0,0 +1,251 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package minhash
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	"bytes"
+	"fmt"
+	"io"
+	check "launchpad.net/gocheck"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func randomSeq(name string, n int) *linear.Seq {
+	l := make(alphabet.Letters, n)
+	for i := range l {
+		l[i] = alphabet.Letter("acgt"[rand.Intn(4)])
+	}
+	return linear.NewSeq(name, l, alphabet.DNA)
+}
+
+// mutate returns a copy of s with a substitution rate of r.
+func mutate(s *linear.Seq, r float64) *linear.Seq {
+	l := append(alphabet.Letters(nil), s.Seq...)
+	for i := range l {
+		if rand.Float64() < r {
+			l[i] = alphabet.Letter("acgt"[(alphabet.DNA.IndexOf(l[i])+1+rand.Intn(3))%4])
+		}
+	}
+	return linear.NewSeq(s.Name()+"'", l, alphabet.DNA)
+}
+
+func sketchOf(c *check.C, k, size int, seqs ...*linear.Seq) *Sketch {
+	sk, err := NewSketch(k, size)
+	c.Assert(err, check.Equals, nil)
+	for _, s := range seqs {
+		c.Assert(sk.Add(s), check.Equals, nil)
+	}
+	return sk
+}
+
+func (s *S) TestBottomS(c *check.C) {
+	rand.Seed(1)
+	sq := randomSeq("s", 5000)
+	sq.Seq[2500] = 'n'
+	for _, k := range []int{5, 16, 21, 32} {
+		lookUp := alphabet.DNA.LetterIndex()
+		set := make(map[uint64]struct{})
+	kmers:
+		for i := 0; i+k <= sq.Len(); i++ {
+			var fwd, rc uint64
+			for j := 0; j < k; j++ {
+				b := lookUp[sq.Seq[i+j]]
+				if b < 0 {
+					continue kmers
+				}
+				fwd = fwd<<2 | uint64(b)
+				rc |= uint64(3-b) << (2 * uint(j))
+			}
+			if rc < fwd {
+				fwd = rc
+			}
+			set[hash(fwd)] = struct{}{}
+		}
+		var want []uint64
+		for h := range set {
+			want = append(want, h)
+		}
+		sort.Sort(uint64s(want))
+		if len(want) > 500 {
+			want = want[:500]
+		}
+
+		sk := sketchOf(c, k, 500, sq)
+		c.Check(sk.Hashes(), check.DeepEquals, want, check.Commentf("k=%d", k))
+		c.Check(sk.Kmers(), check.Equals, int64(sq.Len()-2*k+1))
+
+		rc := linear.NewSeq("rc", append(alphabet.Letters(nil), sq.Seq...), alphabet.DNA)
+		rc.RevComp()
+		c.Check(sketchOf(c, k, 500, rc).Hashes(), check.DeepEquals, want)
+	}
+}
+
+func (s *S) TestMerge(c *check.C) {
+	rand.Seed(2)
+	a, b := randomSeq("a", 3000), randomSeq("b", 3000)
+	sa, sb := sketchOf(c, 21, 200, a), sketchOf(c, 21, 200, b)
+	c.Check(sa.Merge(sb), check.Equals, nil)
+	both := sketchOf(c, 21, 200, a, b)
+	c.Check(sa.Hashes(), check.DeepEquals, both.Hashes())
+	c.Check(sa.Kmers(), check.Equals, both.Kmers())
+
+	// Adding after sorting must maintain the bottom-s set.
+	sa.Add(randomSeq("c", 3000))
+	c.Check(len(sa.Hashes()), check.Equals, 200)
+	c.Check(sort.IsSorted(uint64s(sa.Hashes())), check.Equals, true)
+
+	c.Check(sa.Merge(sketchOf(c, 15, 200, b)), check.Equals, ErrKMismatch)
+}
+
+func (s *S) TestDistance(c *check.C) {
+	rand.Seed(3)
+	const k = 21
+	ref := randomSeq("ref", 200000)
+	for _, rate := range []float64{0.001, 0.01, 0.05} {
+		d, err := Compare(sketchOf(c, k, 2000, ref), sketchOf(c, k, 2000, mutate(ref, rate)))
+		c.Assert(err, check.Equals, nil)
+		c.Check(math.Abs(d.Mash-rate) < 0.2*rate+0.0005, check.Equals, true, check.Commentf("rate=%v %+v", rate, d))
+		c.Check(d.Compared, check.Equals, 2000)
+		c.Check(d.PValue < 1e-10, check.Equals, true, check.Commentf("rate=%v %+v", rate, d))
+	}
+
+	d, err := Compare(sketchOf(c, k, 1000, ref), sketchOf(c, k, 1000, ref))
+	c.Assert(err, check.Equals, nil)
+	c.Check(d.Jaccard, check.Equals, 1.)
+	c.Check(d.Mash, check.Equals, 0.)
+
+	d, err = Compare(sketchOf(c, k, 1000, ref), sketchOf(c, k, 1000, randomSeq("u", 200000)))
+	c.Assert(err, check.Equals, nil)
+	c.Check(d.Shared, check.Equals, 0)
+	c.Check(d.Mash, check.Equals, 1.)
+	c.Check(d.PValue, check.Equals, 1.)
+
+	// Sketches of different sizes are compared at the smaller size.
+	d, err = Compare(sketchOf(c, k, 1000, ref), sketchOf(c, k, 500, ref))
+	c.Assert(err, check.Equals, nil)
+	c.Check(d.Compared, check.Equals, 500)
+	c.Check(d.Jaccard, check.Equals, 1.)
+
+	_, err = Compare(sketchOf(c, k, 1000, ref), sketchOf(c, 15, 1000, ref))
+	c.Check(err, check.Equals, ErrKMismatch)
+}
+
+func (s *S) TestPValue(c *check.C) {
+	binomTail := func(x, n int, p float64) float64 {
+		var sum float64
+		for i := x; i <= n; i++ {
+			lc, _ := math.Lgamma(float64(n + 1))
+			li, _ := math.Lgamma(float64(i + 1))
+			lni, _ := math.Lgamma(float64(n - i + 1))
+			sum += math.Exp(lc - li - lni + float64(i)*math.Log(p) + float64(n-i)*math.Log1p(-p))
+		}
+		return sum
+	}
+	for _, t := range []struct {
+		x, n int
+		p    float64
+	}{
+		{1, 10, 0.1},
+		{5, 10, 0.3},
+		{3, 1000, 0.001},
+		{50, 1000, 0.04},
+		{900, 1000, 0.95},
+	} {
+		got, want := incBeta(float64(t.x), float64(t.n-t.x+1), t.p), binomTail(t.x, t.n, t.p)
+		c.Check(math.Abs(got-want) < 1e-10*math.Max(1, want), check.Equals, true, check.Commentf("%+v got=%v want=%v", t, got, want))
+	}
+
+	c.Check(PValue(0, 1000, 21, 1e6, 1e6), check.Equals, 1.)
+	small, large := PValue(1, 1000, 11, 1e6, 1e6), PValue(1, 1000, 21, 1e6, 1e6)
+	c.Check(small > large, check.Equals, true)
+}
+
+func (s *S) TestSaveLoad(c *check.C) {
+	rand.Seed(4)
+	a := sketchOf(c, 21, 300, randomSeq("a", 10000))
+	a.Name = "a"
+	b := sketchOf(c, 17, 1000, randomSeq("b", 200))
+	b.Name = "sequence b"
+
+	var buf bytes.Buffer
+	c.Assert(a.Save(&buf), check.Equals, nil)
+	c.Assert(b.Save(&buf), check.Equals, nil)
+	data := buf.Bytes()
+
+	for _, want := range []*Sketch{a, b} {
+		got, err := Load(&buf)
+		c.Assert(err, check.Equals, nil)
+		c.Check(got.Name, check.Equals, want.Name)
+		c.Check(got.K(), check.Equals, want.K())
+		c.Check(got.Size(), check.Equals, want.Size())
+		c.Check(got.Kmers(), check.Equals, want.Kmers())
+		c.Check(got.Hashes(), check.DeepEquals, want.Hashes())
+		d, err := Compare(got, want)
+		c.Assert(err, check.Equals, nil)
+		c.Check(d.Jaccard, check.Equals, 1.)
+	}
+	_, err := Load(&buf)
+	c.Check(err, check.Equals, io.EOF)
+
+	_, err = Load(bytes.NewReader(data[:100]))
+	c.Check(err, check.Equals, ErrTruncated)
+	_, err = Load(bytes.NewReader([]byte("not a sketch but long enough for a header")))
+	c.Check(err, check.Equals, ErrNotSketch)
+
+	// A loaded sketch can be extended.
+	got, err := Load(bytes.NewReader(data))
+	c.Assert(err, check.Equals, nil)
+	c.Assert(got.Add(randomSeq("c", 10000)), check.Equals, nil)
+	c.Check(len(got.Hashes()), check.Equals, 300)
+	c.Check(sort.IsSorted(uint64s(got.Hashes())), check.Equals, true)
+}
+
+func (s *S) TestMatrix(c *check.C) {
+	rand.Seed(5)
+	ref := randomSeq("ref", 50000)
+	var sketches []*Sketch
+	for i := 0; i < 10; i++ {
+		sk := sketchOf(c, 21, 1000, mutate(ref, 0.005*float64(i)))
+		sk.Name = fmt.Sprint(i)
+		sketches = append(sketches, sk)
+	}
+	for _, threads := range []int{1, 4} {
+		m, err := Matrix(sketches, threads)
+		c.Assert(err, check.Equals, nil)
+		c.Assert(len(m), check.Equals, len(sketches))
+		for i := range m {
+			c.Check(m[i][i].Mash, check.Equals, 0.)
+			for j := range m[i] {
+				want, err := Compare(sketches[i], sketches[j])
+				c.Assert(err, check.Equals, nil)
+				c.Check(m[i][j], check.Equals, want)
+				c.Check(m[j][i], check.Equals, m[i][j])
+			}
+		}
+		// Distance from the reference increases with mutation rate.
+		for i := 2; i < len(sketches); i++ {
+			c.Check(m[0][i].Mash > m[0][i-1].Mash, check.Equals, true)
+		}
+	}
+
+	_, err := Matrix(append(sketches, sketchOf(c, 15, 100, ref)), 2)
+	c.Check(err, check.Equals, ErrKMismatch)
+	m, err := Matrix(nil, 2)
+	c.Check(err, check.Equals, nil)
+	c.Check(len(m), check.Equals, 0)
+}
@@ -0,0 +1,234 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package minimizer
+
+import (
+	"code.google.com/p/biogo/feat"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ChainParams holds the parameters used to chain anchors.
+type ChainParams struct {
+	// MaxGap is the maximum distance between adjacent anchors
+	// of a chain in either sequence.
+	MaxGap int
+
+	// Bandwidth is the maximum difference in diagonal between
+	// adjacent anchors of a chain.
+	Bandwidth int
+
+	// Lookback is the maximum number of preceding anchors
+	// considered as predecessors of an anchor.
+	Lookback int
+
+	// MinAnchors and MinScore are the minimum number of anchors
+	// and the minimum score of a reported chain.
+	MinAnchors int
+	MinScore   int
+}
+
+// DefaultChainParams are chaining parameters suitable for overlap detection between long reads.
+var DefaultChainParams = ChainParams{
+	MaxGap:     5000,
+	Bandwidth:  500,
+	Lookback:   50,
+	MinAnchors: 3,
+	MinScore:   40,
+}
+
+// A Chain is a colinear set of anchors between a query and an indexed sequence. A Chain satisfies
+// the feat.Pair interface, with the indexed sequence feature first and the query feature second.
+type Chain struct {
+	Target, Query *linear.Seq
+
+	// Strand is seq.Plus if the query and target are
+	// on the same strand, and seq.Minus otherwise.
+	Strand seq.Strand
+
+	// Score is the chaining score of the chain.
+	Score int
+
+	// Anchors holds the anchors of the chain in order
+	// of target position.
+	Anchors []Anchor
+
+	// The extent of the chain in the target and query.
+	TargetStart, TargetEnd int
+	QueryStart, QueryEnd   int
+}
+
+// Features returns the target and query features of the chain.
+func (c *Chain) Features() [2]feat.Feature {
+	return [2]feat.Feature{
+		segment{start: c.TargetStart, end: c.TargetEnd, loc: c.Target},
+		segment{start: c.QueryStart, end: c.QueryEnd, loc: c.Query},
+	}
+}
+
+func (c *Chain) String() string {
+	return fmt.Sprintf("%s[%d,%d)/%s[%d,%d)%v score=%d anchors=%d",
+		c.Target.Name(), c.TargetStart, c.TargetEnd,
+		c.Query.Name(), c.QueryStart, c.QueryEnd,
+		c.Strand, c.Score, len(c.Anchors),
+	)
+}
+
+// segment is a feature describing a chain segment.
+type segment struct {
+	start, end int
+	loc        feat.Feature
+}
+
+func (s segment) Name() string           { return s.loc.Name() }
+func (s segment) Description() string    { return s.loc.Description() }
+func (s segment) Location() feat.Feature { return s.loc }
+func (s segment) Start() int             { return s.start }
+func (s segment) End() int               { return s.end }
+func (s segment) Len() int               { return s.end - s.start }
+
+// Chains returns the chains of anchors shared by query and the indexed sequences that satisfy
+// the parameters in p. Chains are returned as *Chain sorted by decreasing score.
+func (ix *Index) Chains(query *linear.Seq, p ChainParams) ([]feat.Pair, error) {
+	a, err := ix.Anchors(query)
+	if err != nil {
+		return nil, err
+	}
+	var chains []feat.Pair
+	for i := 0; i < len(a); {
+		j := i + 1
+		for j < len(a) && a[j].Seq == a[i].Seq && a[j].Strand == a[i].Strand {
+			j++
+		}
+		chains = append(chains, ix.chain(query, a[i:j], p)...)
+		i = j
+	}
+	sort.Stable(byScore(chains))
+	return chains, nil
+}
+
+// chain returns the chains of a, which must all be on the same target sequence and strand and
+// be sorted by target position.
+func (ix *Index) chain(query *linear.Seq, a []Anchor, p ChainParams) []feat.Pair {
+	k := ix.k
+	strand := a[0].Strand
+
+	// x holds query positions in the orientation of the target.
+	x := make([]int, len(a))
+	for i, an := range a {
+		if strand == seq.Plus {
+			x[i] = an.QueryPos
+		} else {
+			x[i] = query.Len() - an.QueryPos - k
+		}
+	}
+
+	f := make([]int, len(a))
+	pred := make([]int, len(a))
+	for i := range a {
+		f[i], pred[i] = k, -1
+		for j := i - 1; j >= 0 && (p.Lookback <= 0 || i-j <= p.Lookback); j-- {
+			dt := a[i].Pos - a[j].Pos
+			if dt > p.MaxGap {
+				break
+			}
+			dq := x[i] - x[j]
+			if dt == 0 || dq <= 0 || dq > p.MaxGap {
+				continue
+			}
+			gap := dt - dq
+			if gap < 0 {
+				gap = -gap
+			}
+			if gap > p.Bandwidth {
+				continue
+			}
+			match := k
+			if dt < match {
+				match = dt
+			}
+			if dq < match {
+				match = dq
+			}
+			if sc := f[j] + match - gapCost(k, gap); sc > f[i] {
+				f[i], pred[i] = sc, j
+			}
+		}
+	}
+
+	order := make([]int, len(a))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Stable(byChainScore{order, f})
+
+	var chains []feat.Pair
+	used := make([]bool, len(a))
+	for _, end := range order {
+		if used[end] {
+			continue
+		}
+		var idx []int
+		i := end
+		for ; i >= 0 && !used[i]; i = pred[i] {
+			used[i] = true
+			idx = append(idx, i)
+		}
+		score := f[end]
+		if i >= 0 {
+			score -= f[i]
+		}
+		if len(idx) < p.MinAnchors || score < p.MinScore {
+			continue
+		}
+
+		c := &Chain{
+			Target:  ix.seqs[a[0].Seq],
+			Query:   query,
+			Strand:  strand,
+			Score:   score,
+			Anchors: make([]Anchor, len(idx)),
+		}
+		for n, i := range idx {
+			c.Anchors[len(idx)-1-n] = a[i]
+		}
+		first, last := idx[len(idx)-1], idx[0]
+		c.TargetStart, c.TargetEnd = a[first].Pos, a[last].Pos+k
+		if strand == seq.Plus {
+			c.QueryStart, c.QueryEnd = x[first], x[last]+k
+		} else {
+			c.QueryStart, c.QueryEnd = query.Len()-x[last]-k, query.Len()-x[first]
+		}
+		chains = append(chains, c)
+	}
+	return chains
+}
+
+// gapCost returns the cost of a gap of length gap between adjacent anchors of k-mers.
+func gapCost(k, gap int) int {
+	if gap == 0 {
+		return 0
+	}
+	return int(0.01*float64(k*gap) + 0.5*math.Log2(float64(gap)))
+}
+
+type byChainScore struct {
+	order []int
+	f     []int
+}
+
+func (c byChainScore) Len() int           { return len(c.order) }
+func (c byChainScore) Less(i, j int) bool { return c.f[c.order[i]] > c.f[c.order[j]] }
+func (c byChainScore) Swap(i, j int)      { c.order[i], c.order[j] = c.order[j], c.order[i] }
+
+type byScore []feat.Pair
+
+func (c byScore) Len() int           { return len(c) }
+func (c byScore) Less(i, j int) bool { return c[i].(*Chain).Score > c[j].(*Chain).Score }
+func (c byScore) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
@@ -0,0 +1,155 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package minimizer
+
+import (
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+
+	"sort"
+)
+
+// A Position is the location of a sketched k-mer in an indexed sequence.
+type Position struct {
+	Seq    int
+	Pos    int
+	Strand seq.Strand
+}
+
+// An Index holds the positions of the sketched k-mers of a set of sequences.
+type Index struct {
+	k, w int
+
+	syncmer bool
+	s, t    int
+
+	// MaxOccurrence is the maximum number of occurrences of a sketched
+	// k-mer in the Index for it to be used by Anchors. If MaxOccurrence
+	// is zero, all sketched k-mers are used.
+	MaxOccurrence int
+
+	seqs  []*linear.Seq
+	table map[uint64][]Position
+}
+
+// New returns an empty Index of (w, k)-minimizers.
+func New(k, w int) (*Index, error) {
+	switch {
+	case k > MaxKmerLen:
+		return nil, ErrKTooLarge
+	case k < 1:
+		return nil, ErrKTooSmall
+	case w < 1:
+		return nil, ErrBadWindow
+	}
+	return &Index{k: k, w: w, table: make(map[uint64][]Position)}, nil
+}
+
+// NewSyncmer returns an empty Index of open (k, s) syncmers with offset t.
+func NewSyncmer(k, s, t int) (*Index, error) {
+	switch {
+	case k > MaxKmerLen:
+		return nil, ErrKTooLarge
+	case k < 1:
+		return nil, ErrKTooSmall
+	case s < 1 || s > k || t < 0 || t > k-s:
+		return nil, ErrBadSyncmer
+	}
+	return &Index{k: k, syncmer: true, s: s, t: t, table: make(map[uint64][]Position)}, nil
+}
+
+// K returns the k-mer length of the Index.
+func (ix *Index) K() int { return ix.k }
+
+// W returns the minimizer window size of the Index, or zero for a syncmer Index.
+func (ix *Index) W() int { return ix.w }
+
+// Syncmer returns whether the Index holds syncmers, and the syncmer s-mer length and offset.
+func (ix *Index) Syncmer() (ok bool, s, t int) { return ix.syncmer, ix.s, ix.t }
+
+// Sketch returns the sketch of s using the parameters of the Index.
+func (ix *Index) Sketch(s *linear.Seq) ([]Minimizer, error) {
+	if ix.syncmer {
+		return Syncmers(s, ix.k, ix.s, ix.t)
+	}
+	return Minimizers(s, ix.k, ix.w)
+}
+
+// Add sketches the provided sequences and adds them to the Index.
+func (ix *Index) Add(seqs ...*linear.Seq) error {
+	for _, s := range seqs {
+		mins, err := ix.Sketch(s)
+		if err != nil {
+			return err
+		}
+		id := len(ix.seqs)
+		ix.seqs = append(ix.seqs, s)
+		for _, m := range mins {
+			ix.table[m.Hash] = append(ix.table[m.Hash], Position{Seq: id, Pos: m.Pos, Strand: m.Strand})
+		}
+	}
+	return nil
+}
+
+// Len returns the number of sequences in the Index.
+func (ix *Index) Len() int { return len(ix.seqs) }
+
+// Seq returns the ith sequence in the Index.
+func (ix *Index) Seq(i int) *linear.Seq { return ix.seqs[i] }
+
+// Lookup returns the positions of the sketched k-mer with the given hash. The returned slice
+// should not be altered.
+func (ix *Index) Lookup(h uint64) []Position { return ix.table[h] }
+
+// An Anchor is a sketched k-mer shared by a query and an indexed sequence.
+type Anchor struct {
+	// Seq is the index of the indexed sequence and
+	// Pos is the position of the k-mer in it.
+	Seq, Pos int
+
+	// QueryPos is the position of the k-mer in the query.
+	QueryPos int
+
+	// Strand is seq.Plus if the k-mer occurs on the same strand
+	// of the query and the indexed sequence, and seq.Minus otherwise.
+	Strand seq.Strand
+}
+
+// Anchors returns the anchors shared by query and the indexed sequences, sorted by indexed
+// sequence, strand, indexed sequence position and query position.
+func (ix *Index) Anchors(query *linear.Seq) ([]Anchor, error) {
+	mins, err := ix.Sketch(query)
+	if err != nil {
+		return nil, err
+	}
+	var a []Anchor
+	for _, m := range mins {
+		hits := ix.table[m.Hash]
+		if ix.MaxOccurrence > 0 && len(hits) > ix.MaxOccurrence {
+			continue
+		}
+		for _, p := range hits {
+			a = append(a, Anchor{Seq: p.Seq, Pos: p.Pos, QueryPos: m.Pos, Strand: p.Strand * m.Strand})
+		}
+	}
+	sort.Sort(anchors(a))
+	return a, nil
+}
+
+type anchors []Anchor
+
+func (a anchors) Len() int { return len(a) }
+func (a anchors) Less(i, j int) bool {
+	switch {
+	case a[i].Seq != a[j].Seq:
+		return a[i].Seq < a[j].Seq
+	case a[i].Strand != a[j].Strand:
+		return a[i].Strand > a[j].Strand
+	case a[i].Pos != a[j].Pos:
+		return a[i].Pos < a[j].Pos
+	}
+	return a[i].QueryPos < a[j].QueryPos
+}
+func (a anchors) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,220 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package minimizer provides minimizer and syncmer sketching of nucleotide sequences, an index of
+// sketched sequences and chaining of shared sketch anchors for long read overlap detection.
+//
+// K-mers are ordered by an invertible hash of their canonical 2-bit encoding, so a k-mer and its
+// reverse complement are sketched identically and low complexity k-mers are not favoured. The
+// minimizer of a window of w consecutive k-mers is chosen by robust winnowing; the rightmost
+// minimal k-mer is chosen and a previously chosen k-mer is retained while it remains minimal
+// within the window. An open syncmer is a k-mer whose minimal s-mer is at offset t.
+package minimizer
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/index/kmerindex"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+
+	"errors"
+)
+
+var (
+	ErrKTooLarge   = errors.New("minimizer: k too large")
+	ErrKTooSmall   = errors.New("minimizer: k too small")
+	ErrBadWindow   = errors.New("minimizer: window size < 1")
+	ErrBadSyncmer  = errors.New("minimizer: invalid syncmer parameters")
+	ErrBadAlphabet = errors.New("minimizer: alphabet size != 4")
+)
+
+// MaxKmerLen is the maximum k-mer length that can be sketched.
+const MaxKmerLen = 32
+
+// A Minimizer is a sketched k-mer of a sequence.
+type Minimizer struct {
+	// Hash is the hash of the canonical k-mer.
+	Hash uint64
+
+	// Pos is the position of the start of the k-mer.
+	Pos int
+
+	// Strand is seq.Plus if the k-mer is its own canonical
+	// form, and seq.Minus if its reverse complement is.
+	Strand seq.Strand
+}
+
+// hash returns an invertible hash of the 2k bit key where mask has the low 2k bits set.
+func hash(key, mask uint64) uint64 {
+	key = (^key + key<<21) & mask
+	key = key ^ key>>24
+	key = (key + key<<3 + key<<8) & mask
+	key = key ^ key>>14
+	key = (key + key<<2 + key<<4) & mask
+	key = key ^ key>>28
+	key = (key + key<<31) & mask
+	return key
+}
+
+// canonical returns the hash of the canonical k-mer held by r and its strand, where mask has the
+// low 2k bits set. If the k-mer is its own reverse complement, ok is returned false.
+func canonical(r *kmerindex.Roller, mask uint64) (h uint64, strand seq.Strand, ok bool) {
+	fwd, rc := uint64(r.Kmer()), uint64(r.Complement())
+	switch {
+	case fwd < rc:
+		return hash(fwd, mask), seq.Plus, true
+	case rc < fwd:
+		return hash(rc, mask), seq.Minus, true
+	}
+	return 0, 0, false
+}
+
+// checkAlphabet returns the letter index of the alphabet of s.
+func checkAlphabet(s *linear.Seq) (alphabet.Index, error) {
+	if s.Alpha == nil || s.Alpha.Len() != 4 {
+		return nil, ErrBadAlphabet
+	}
+	return s.Alpha.LetterIndex(), nil
+}
+
+// Minimizers returns the (w, k)-minimizers of s in order of position. Windows do not span letters
+// that are not valid in the alphabet of s, and a run of fewer than w valid k-mers contributes its
+// minimal k-mer. K-mers that are their own reverse complement are not sketched.
+func Minimizers(s *linear.Seq, k, w int) ([]Minimizer, error) {
+	switch {
+	case k > MaxKmerLen:
+		return nil, ErrKTooLarge
+	case k < 1:
+		return nil, ErrKTooSmall
+	case w < 1:
+		return nil, ErrBadWindow
+	}
+	lookUp, err := checkAlphabet(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		mins []Minimizer
+		last = Minimizer{Pos: -1}
+
+		// window is a monotone queue of candidate k-mers in the current
+		// window with strictly increasing hashes, so the front of the
+		// queue is the rightmost minimal k-mer of the window.
+		window []Minimizer
+
+		// run is the number of consecutive valid k-mers.
+		run int
+	)
+	flush := func() {
+		if run > 0 && run < w && len(window) != 0 {
+			mins = append(mins, window[0])
+		}
+		window = window[:0]
+		last = Minimizer{Pos: -1}
+		run = 0
+	}
+	r := kmerindex.NewRoller(k, lookUp)
+	mask := uint64(1)<<(2*uint(k)) - 1
+	for i, l := range s.Seq {
+		if !r.Push(l) {
+			if lookUp[l] < 0 {
+				flush()
+			}
+			continue
+		}
+		pos := i - k + 1
+		run++
+		if h, strand, ok := canonical(r, mask); ok {
+			for len(window) != 0 && window[len(window)-1].Hash >= h {
+				window = window[:len(window)-1]
+			}
+			window = append(window, Minimizer{Hash: h, Pos: pos, Strand: strand})
+		}
+		for len(window) != 0 && window[0].Pos <= pos-w {
+			window = window[1:]
+		}
+		if run < w || len(window) == 0 {
+			continue
+		}
+		m := window[0]
+		if last.Pos > pos-w && last.Hash == m.Hash {
+			// Robust winnowing: retain the previous minimizer.
+			continue
+		}
+		if m.Pos != last.Pos {
+			mins = append(mins, m)
+			last = m
+		}
+	}
+	flush()
+
+	return mins, nil
+}
+
+// Syncmers returns the open (k, s) syncmers of s with offset t in order of position. An open
+// syncmer is a k-mer whose leftmost minimal canonical s-mer, ordered by hash, starts at offset t
+// within the k-mer. K-mers that are their own reverse complement are not sketched.
+func Syncmers(sq *linear.Seq, k, s, t int) ([]Minimizer, error) {
+	switch {
+	case k > MaxKmerLen:
+		return nil, ErrKTooLarge
+	case k < 1:
+		return nil, ErrKTooSmall
+	case s < 1 || s > k || t < 0 || t > k-s:
+		return nil, ErrBadSyncmer
+	}
+	lookUp, err := checkAlphabet(sq)
+	if err != nil {
+		return nil, err
+	}
+
+	type smer struct {
+		hash uint64
+		pos  int
+	}
+	var (
+		syncs []Minimizer
+
+		// window is a monotone queue of s-mers in the current k-mer
+		// with non-decreasing hashes, so the front of the queue is the
+		// leftmost minimal s-mer of the k-mer.
+		window []smer
+	)
+	var (
+		kr, kmask = kmerindex.NewRoller(k, lookUp), uint64(1)<<(2*uint(k)) - 1
+		sr, smask = kmerindex.NewRoller(s, lookUp), uint64(1)<<(2*uint(s)) - 1
+	)
+	for i, l := range sq.Seq {
+		full := kr.Push(l)
+		if !sr.Push(l) {
+			if lookUp[l] < 0 {
+				window = window[:0]
+			}
+			continue
+		}
+		spos := i - s + 1
+		h := hash(uint64(sr.Canonical()), smask)
+		for len(window) != 0 && window[len(window)-1].hash > h {
+			window = window[:len(window)-1]
+		}
+		window = append(window, smer{hash: h, pos: spos})
+
+		if !full {
+			continue
+		}
+		pos := i - k + 1
+		for window[0].pos < pos {
+			window = window[1:]
+		}
+		if window[0].pos-pos != t {
+			continue
+		}
+		if h, strand, ok := canonical(kr, kmask); ok {
+			syncs = append(syncs, Minimizer{Hash: h, Pos: pos, Strand: strand})
+		}
+	}
+
+	return syncs, nil
+}
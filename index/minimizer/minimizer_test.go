@@ -0,0 +1,256 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package minimizer
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq"
+	"code.google.com/p/biogo/seq/linear"
+
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"testing"
+)
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func randomSeq(name string, n int, letters string) *linear.Seq {
+	l := make(alphabet.Letters, n)
+	for i := range l {
+		l[i] = alphabet.Letter(letters[rand.Intn(len(letters))])
+	}
+	return linear.NewSeq(name, l, alphabet.DNA)
+}
+
+// kmerHashes returns the canonical hashes and strands of the k-mers of s, with ok false for
+// k-mers that are not sketched.
+func kmerHashes(s *linear.Seq, k int) (h []uint64, strand []seq.Strand, ok []bool) {
+	lookUp := alphabet.DNA.LetterIndex()
+	mask := uint64(1)<<(2*uint(k)) - 1
+	for i := 0; i+k <= s.Len(); i++ {
+		var fwd, rc uint64
+		valid := true
+		for j := 0; j < k; j++ {
+			b := lookUp[s.Seq[i+j]]
+			if b < 0 {
+				valid = false
+				break
+			}
+			fwd = fwd<<2 | uint64(b)
+			rc |= uint64(3-b) << (2 * uint(j))
+		}
+		switch {
+		case !valid || fwd == rc:
+			h, strand, ok = append(h, 0), append(strand, 0), append(ok, false)
+		case fwd < rc:
+			h, strand, ok = append(h, hash(fwd, mask)), append(strand, seq.Plus), append(ok, true)
+		default:
+			h, strand, ok = append(h, hash(rc, mask)), append(strand, seq.Minus), append(ok, true)
+		}
+	}
+	return h, strand, ok
+}
+
+func (s *S) TestMinimizers(c *check.C) {
+	rand.Seed(1)
+	for _, t := range []struct {
+		k, w    int
+		letters string
+	}{
+		{k: 5, w: 1, letters: "acgt"},
+		{k: 5, w: 4, letters: "acgt"},
+		{k: 15, w: 10, letters: "acgt"},
+		{k: 6, w: 5, letters: "at"},
+		{k: 32, w: 20, letters: "acgt"},
+	} {
+		sq := randomSeq("s", 2000, t.letters)
+		got, err := Minimizers(sq, t.k, t.w)
+		c.Assert(err, check.Equals, nil)
+
+		// Naive robust winnowing over each window of w k-mers.
+		h, strand, ok := kmerHashes(sq, t.k)
+		var want []Minimizer
+		last := -1
+		for i := 0; i+t.w <= len(h); i++ {
+			min := -1
+			for j := i; j < i+t.w; j++ {
+				if ok[j] && (min < 0 || h[j] <= h[min]) {
+					min = j
+				}
+			}
+			if min < 0 || (last >= i && h[last] == h[min]) || min == last {
+				continue
+			}
+			want = append(want, Minimizer{Hash: h[min], Pos: min, Strand: strand[min]})
+			last = min
+		}
+		c.Check(got, check.DeepEquals, want, check.Commentf("k=%d w=%d", t.k, t.w))
+	}
+}
+
+func (s *S) TestMinimizersAmbiguous(c *check.C) {
+	sq := linear.NewSeq("s", alphabet.BytesToLetters([]byte("acgtacggtannacgttgcaacgtnacgatcgatgg")), alphabet.DNA)
+	got, err := Minimizers(sq, 4, 10)
+	c.Assert(err, check.Equals, nil)
+	h, _, ok := kmerHashes(sq, 4)
+	for _, m := range got {
+		c.Check(ok[m.Pos], check.Equals, true)
+		c.Check(m.Hash, check.Equals, h[m.Pos])
+	}
+	// Each run of k-mers between ambiguous letters is shorter than
+	// the window and contributes exactly one minimizer.
+	c.Check(len(got), check.Equals, 3)
+}
+
+func (s *S) TestStrandInvariance(c *check.C) {
+	rand.Seed(2)
+	sq := randomSeq("s", 5000, "acgt")
+	fwd, err := Minimizers(sq, 15, 10)
+	c.Assert(err, check.Equals, nil)
+	rc := linear.NewSeq("rc", append(alphabet.Letters(nil), sq.Seq...), alphabet.DNA)
+	rc.RevComp()
+	rev, err := Minimizers(rc, 15, 10)
+	c.Assert(err, check.Equals, nil)
+
+	c.Assert(len(rev), check.Equals, len(fwd))
+	for i, m := range fwd {
+		r := rev[len(rev)-1-i]
+		c.Check(r.Hash, check.Equals, m.Hash)
+		c.Check(r.Pos, check.Equals, sq.Len()-m.Pos-15)
+		c.Check(r.Strand, check.Equals, -m.Strand)
+	}
+}
+
+func (s *S) TestSyncmers(c *check.C) {
+	rand.Seed(3)
+	sq := randomSeq("s", 3000, "acgt")
+	sq.Seq[1500] = 'n'
+	for _, t := range []struct{ k, s, t int }{
+		{k: 15, s: 5, t: 0},
+		{k: 15, s: 5, t: 3},
+		{k: 11, s: 11, t: 0},
+	} {
+		got, err := Syncmers(sq, t.k, t.s, t.t)
+		c.Assert(err, check.Equals, nil)
+
+		// Palindromic s-mers are ranked, unlike palindromic k-mers.
+		sh := make([]uint64, sq.Len()-t.s+1)
+		lookUp := alphabet.DNA.LetterIndex()
+		for i := range sh {
+			var fwd, rc uint64
+			for j := 0; j < t.s; j++ {
+				b := lookUp[sq.Seq[i+j]] & 3
+				fwd = fwd<<2 | uint64(b)
+				rc |= uint64(3-b) << (2 * uint(j))
+			}
+			if rc < fwd {
+				fwd = rc
+			}
+			sh[i] = hash(fwd, uint64(1)<<(2*uint(t.s))-1)
+		}
+		h, strand, ok := kmerHashes(sq, t.k)
+		var want []Minimizer
+		for i := range h {
+			if !ok[i] {
+				continue
+			}
+			min := i
+			for j := i; j <= i+t.k-t.s; j++ {
+				if sh[j] < sh[min] {
+					min = j
+				}
+			}
+			if min-i == t.t {
+				want = append(want, Minimizer{Hash: h[i], Pos: i, Strand: strand[i]})
+			}
+		}
+		c.Check(got, check.DeepEquals, want, check.Commentf("k=%d s=%d t=%d", t.k, t.s, t.t))
+		// Open syncmers with t=0 sample about 1/(k-s+1) of k-mers.
+		if t.s < t.k {
+			c.Check(float64(len(got)) > 0.5*float64(len(h))/float64(t.k-t.s+1), check.Equals, true)
+		}
+	}
+}
+
+func (s *S) TestErrors(c *check.C) {
+	sq := randomSeq("s", 100, "acgt")
+	_, err := Minimizers(sq, 33, 10)
+	c.Check(err, check.Equals, ErrKTooLarge)
+	_, err = Minimizers(sq, 0, 10)
+	c.Check(err, check.Equals, ErrKTooSmall)
+	_, err = Minimizers(sq, 15, 0)
+	c.Check(err, check.Equals, ErrBadWindow)
+	_, err = Syncmers(sq, 15, 5, 11)
+	c.Check(err, check.Equals, ErrBadSyncmer)
+	_, err = NewSyncmer(15, 16, 0)
+	c.Check(err, check.Equals, ErrBadSyncmer)
+	_, err = Minimizers(linear.NewSeq("p", alphabet.BytesToLetters([]byte("ACDEF")), alphabet.Protein), 3, 2)
+	c.Check(err, check.Equals, ErrBadAlphabet)
+}
+
+// mutate returns a copy of the letters of s[from:to] with a substitution rate of r.
+func mutate(s *linear.Seq, from, to int, r float64) alphabet.Letters {
+	l := append(alphabet.Letters(nil), s.Seq[from:to]...)
+	for i := range l {
+		if rand.Float64() < r {
+			l[i] = alphabet.Letter("acgt"[rand.Intn(4)])
+		}
+	}
+	return l
+}
+
+func (s *S) TestChains(c *check.C) {
+	rand.Seed(4)
+	ref := randomSeq("ref", 20000, "acgt")
+	other := randomSeq("other", 20000, "acgt")
+	for _, newIndex := range []func() (*Index, error){
+		func() (*Index, error) { return New(15, 10) },
+		func() (*Index, error) { return NewSyncmer(15, 9, 3) },
+	} {
+		ix, err := newIndex()
+		c.Assert(err, check.Equals, nil)
+		c.Assert(ix.Add(other, ref), check.Equals, nil)
+		c.Check(ix.Len(), check.Equals, 2)
+
+		q := linear.NewSeq("q", mutate(ref, 5000, 9000, 0.02), alphabet.DNA)
+		chains, err := ix.Chains(q, DefaultChainParams)
+		c.Assert(err, check.Equals, nil)
+		c.Assert(len(chains) > 0, check.Equals, true)
+		best := chains[0].(*Chain)
+		c.Check(best.Target, check.Equals, ref)
+		c.Check(best.Strand, check.Equals, seq.Plus)
+		f := best.Features()
+		c.Check(f[0].Start() < 5200 && f[0].End() > 8800, check.Equals, true, check.Commentf("%v", best))
+		c.Check(f[1].Start() < 200 && f[1].End() > 3800, check.Equals, true, check.Commentf("%v", best))
+		c.Check(f[0].Start()-5000-f[1].Start(), check.Equals, 0)
+		c.Check(f[1].Location(), check.Equals, q)
+
+		q.RevComp()
+		chains, err = ix.Chains(q, DefaultChainParams)
+		c.Assert(err, check.Equals, nil)
+		c.Assert(len(chains) > 0, check.Equals, true)
+		best = chains[0].(*Chain)
+		c.Check(best.Target, check.Equals, ref)
+		c.Check(best.Strand, check.Equals, seq.Minus)
+		f = best.Features()
+		c.Check(f[0].Start() < 5200 && f[0].End() > 8800, check.Equals, true, check.Commentf("%v", best))
+		c.Check(f[1].Start() < 200 && f[1].End() > 3800, check.Equals, true, check.Commentf("%v", best))
+		c.Check(f[0].End()-9000+f[1].Start(), check.Equals, 0)
+		for i, a := range best.Anchors[1:] {
+			c.Check(a.Pos > best.Anchors[i].Pos, check.Equals, true)
+			c.Check(a.QueryPos < best.Anchors[i].QueryPos, check.Equals, true)
+		}
+
+		unrelated := randomSeq("u", 4000, "acgt")
+		chains, err = ix.Chains(unrelated, DefaultChainParams)
+		c.Assert(err, check.Equals, nil)
+		c.Check(len(chains), check.Equals, 0)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package succinct provides succinct data structures supporting rank and select queries: a
+// bit vector with a two level rank directory and a wavelet matrix over byte sequences.
+package succinct
+
+import "errors"
+
+var ErrBadWords = errors.New("succinct: words do not hold a bit vector of the given length")
+
+const (
+	wordBits  = 64
+	superBits = 512
+	perSuper  = superBits / wordBits
+)
+
+// A BitVector is a fixed length vector of bits supporting constant time rank queries and
+// logarithmic time select queries. Bits are set with Set and the rank directory is built by
+// calling Build. Rank and Select must not be called before Build, and Build must be called
+// again after any bits are changed.
+//
+// The rank directory holds the number of set bits before each 512 bit superblock and before
+// each word within its superblock, adding 37.5% to the space used by the bits.
+type BitVector struct {
+	n     int
+	words []uint64
+
+	super  []uint64 // Number of set bits before each superblock.
+	blocks []uint16 // Number of set bits before each word within its superblock.
+	ones   int
+}
+
+// NewBitVector returns a BitVector of n unset bits.
+func NewBitVector(n int) *BitVector {
+	if n < 0 {
+		panic("succinct: negative length")
+	}
+	return &BitVector{n: n, words: make([]uint64, (n+wordBits-1)/wordBits)}
+}
+
+// NewBitVectorOf returns a BitVector of n bits held in words, with bit i of the vector held in
+// bit i%64 of words[i/64]. The words are used directly by the returned BitVector and must not be
+// altered while it is in use. The rank directory of the returned BitVector is built. If words does
+// not hold exactly the words needed for n bits or has bits set beyond position n, NewBitVectorOf
+// returns ErrBadWords.
+func NewBitVectorOf(words []uint64, n int) (*BitVector, error) {
+	if n < 0 || len(words) != (n+wordBits-1)/wordBits {
+		return nil, ErrBadWords
+	}
+	if n%wordBits != 0 && words[len(words)-1]>>uint(n%wordBits) != 0 {
+		return nil, ErrBadWords
+	}
+	b := &BitVector{n: n, words: words}
+	b.Build()
+	return b, nil
+}
+
+// Words returns the words holding the bits of the vector, with bit i of the vector held in bit
+// i%64 of word i/64. The returned slice is shared with the vector and must not be altered.
+func (b *BitVector) Words() []uint64 { return b.words }
+
+// Len returns the number of bits in the vector.
+func (b *BitVector) Len() int { return b.n }
+
+// Set sets the bit at position i.
+func (b *BitVector) Set(i int) {
+	b.check(i)
+	b.words[i/wordBits] |= 1 << uint(i%wordBits)
+}
+
+// Unset clears the bit at position i.
+func (b *BitVector) Unset(i int) {
+	b.check(i)
+	b.words[i/wordBits] &^= 1 << uint(i%wordBits)
+}
+
+// Get returns whether the bit at position i is set.
+func (b *BitVector) Get(i int) bool {
+	b.check(i)
+	return b.words[i/wordBits]&(1<<uint(i%wordBits)) != 0
+}
+
+func (b *BitVector) check(i int) {
+	if i < 0 || i >= b.n {
+		panic("succinct: index out of range")
+	}
+}
+
+// Build builds the rank directory of the vector.
+func (b *BitVector) Build() {
+	b.super = make([]uint64, (len(b.words)+perSuper-1)/perSuper+1)
+	b.blocks = make([]uint16, len(b.words))
+	var n, inSuper int
+	for i, w := range b.words {
+		if i%perSuper == 0 {
+			b.super[i/perSuper] = uint64(n)
+			inSuper = 0
+		}
+		b.blocks[i] = uint16(inSuper)
+		c := PopCount(w)
+		n += c
+		inSuper += c
+	}
+	b.super[len(b.super)-1] = uint64(n)
+	b.ones = n
+}
+
+// Ones returns the number of set bits in the vector.
+func (b *BitVector) Ones() int { return b.ones }
+
+// Rank1 returns the number of set bits before position i.
+func (b *BitVector) Rank1(i int) int {
+	if i < 0 || i > b.n {
+		panic("succinct: index out of range")
+	}
+	w := i / wordBits
+	if w == len(b.words) {
+		return b.ones
+	}
+	return int(b.super[w/perSuper]) + int(b.blocks[w]) + PopCount(b.words[w]&(1<<uint(i%wordBits)-1))
+}
+
+// Rank0 returns the number of unset bits before position i.
+func (b *BitVector) Rank0(i int) int { return i - b.Rank1(i) }
+
+// Select1 returns the position of the kth set bit, counting from zero. If there are not more
+// than k set bits, Select1 returns -1.
+func (b *BitVector) Select1(k int) int {
+	if k < 0 || k >= b.ones {
+		return -1
+	}
+	// Find the last superblock with fewer than k+1 set bits before it.
+	lo, hi := 0, len(b.super)-1
+	for lo < hi {
+		m := int(uint(lo+hi+1) >> 1)
+		if int(b.super[m]) <= k {
+			lo = m
+		} else {
+			hi = m - 1
+		}
+	}
+	k -= int(b.super[lo])
+	w := lo * perSuper
+	for end := min(w+perSuper, len(b.words)); w+1 < end && int(b.blocks[w+1]) <= k; w++ {
+	}
+	return w*wordBits + selectInWord(b.words[w], k-int(b.blocks[w]))
+}
+
+// Select0 returns the position of the kth unset bit, counting from zero. If there are not more
+// than k unset bits, Select0 returns -1.
+func (b *BitVector) Select0(k int) int {
+	if k < 0 || k >= b.n-b.ones {
+		return -1
+	}
+	zerosBefore := func(s int) int { return s*superBits - int(b.super[s]) }
+	lo, hi := 0, len(b.super)-2
+	for lo < hi {
+		m := int(uint(lo+hi+1) >> 1)
+		if zerosBefore(m) <= k {
+			lo = m
+		} else {
+			hi = m - 1
+		}
+	}
+	k -= zerosBefore(lo)
+	w := lo * perSuper
+	for end := min(w+perSuper, len(b.words)); w+1 < end && (w+1-lo*perSuper)*wordBits-int(b.blocks[w+1]) <= k; w++ {
+	}
+	return w*wordBits + selectInWord(^b.words[w], k-((w-lo*perSuper)*wordBits-int(b.blocks[w])))
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// PopCount returns the number of set bits in x.
+func PopCount(x uint64) int {
+	x -= (x >> 1) & 0x5555555555555555
+	x = (x & 0x3333333333333333) + ((x >> 2) & 0x3333333333333333)
+	x = (x + (x >> 4)) & 0x0f0f0f0f0f0f0f0f
+	return int((x * 0x0101010101010101) >> 56)
+}
+
+// selectInWord returns the position of the kth set bit of x, counting from zero. x must have
+// more than k set bits.
+func selectInWord(x uint64, k int) int {
+	var pos int
+	for _, width := range [...]uint{32, 16, 8} {
+		if c := PopCount(x & (1<<width - 1)); c <= k {
+			k -= c
+			x >>= width
+			pos += int(width)
+		}
+	}
+	for ; ; x >>= 1 {
+		if x&1 != 0 {
+			if k == 0 {
+				return pos
+			}
+			k--
+		}
+		pos++
+	}
+}
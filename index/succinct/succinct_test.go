@@ -0,0 +1,201 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package succinct
+
+import (
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"testing"
+)
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestSelectInWord(c *check.C) {
+	for i := 0; i < 1000; i++ {
+		x := uint64(rand.Int63())<<1 | uint64(rand.Intn(2))
+		var k int
+		for pos := 0; pos < 64; pos++ {
+			if x&(1<<uint(pos)) != 0 {
+				c.Check(selectInWord(x, k), check.Equals, pos)
+				k++
+			}
+		}
+	}
+}
+
+func (s *S) TestBitVector(c *check.C) {
+	rand.Seed(1)
+	for _, n := range []int{0, 1, 63, 64, 65, 511, 512, 513, 1000, 5000} {
+		for _, density := range []float64{0, 0.01, 0.5, 0.99, 1} {
+			b := NewBitVector(n)
+			c.Check(b.Len(), check.Equals, n)
+			want := make([]bool, n)
+			for i := range want {
+				if rand.Float64() < density {
+					want[i] = true
+					b.Set(i)
+				}
+			}
+			b.Build()
+
+			var ones, zeros int
+			for i, v := range want {
+				c.Assert(b.Get(i), check.Equals, v)
+				c.Assert(b.Rank1(i), check.Equals, ones)
+				c.Assert(b.Rank0(i), check.Equals, zeros)
+				if v {
+					c.Assert(b.Select1(ones), check.Equals, i, check.Commentf("n=%d density=%v", n, density))
+					ones++
+				} else {
+					c.Assert(b.Select0(zeros), check.Equals, i, check.Commentf("n=%d density=%v", n, density))
+					zeros++
+				}
+			}
+			c.Check(b.Rank1(n), check.Equals, ones)
+			c.Check(b.Ones(), check.Equals, ones)
+			c.Check(b.Select1(ones), check.Equals, -1)
+			c.Check(b.Select0(zeros), check.Equals, -1)
+			c.Check(b.Select1(-1), check.Equals, -1)
+		}
+	}
+
+	b := NewBitVector(100)
+	b.Set(10)
+	b.Set(20)
+	b.Unset(10)
+	b.Build()
+	c.Check(b.Ones(), check.Equals, 1)
+	c.Check(b.Select1(0), check.Equals, 20)
+	c.Check(func() { b.Get(100) }, check.Panics, "succinct: index out of range")
+	c.Check(func() { b.Rank1(101) }, check.Panics, "succinct: index out of range")
+}
+
+func (s *S) TestBitVectorOf(c *check.C) {
+	rand.Seed(3)
+	for _, n := range []int{0, 1, 63, 64, 65, 1000} {
+		b := NewBitVector(n)
+		for i := 0; i < n; i++ {
+			if rand.Intn(2) == 0 {
+				b.Set(i)
+			}
+		}
+		b.Build()
+		words := append([]uint64(nil), b.Words()...)
+		o, err := NewBitVectorOf(words, n)
+		c.Assert(err, check.Equals, nil)
+		c.Check(o.Len(), check.Equals, n)
+		c.Check(o.Ones(), check.Equals, b.Ones())
+		for i := 0; i <= n; i++ {
+			c.Assert(o.Rank1(i), check.Equals, b.Rank1(i))
+		}
+	}
+
+	_, err := NewBitVectorOf(make([]uint64, 2), 64)
+	c.Check(err, check.Equals, ErrBadWords)
+	_, err = NewBitVectorOf([]uint64{1 << 10}, 10)
+	c.Check(err, check.Equals, ErrBadWords)
+	_, err = NewBitVectorOf([]uint64{1 << 9}, 10)
+	c.Check(err, check.Equals, nil)
+}
+
+func randomBytes(n, sigma int) []byte {
+	s := make([]byte, n)
+	for i := range s {
+		s[i] = byte(rand.Intn(sigma))
+	}
+	return s
+}
+
+func (s *S) TestWaveletMatrix(c *check.C) {
+	rand.Seed(2)
+	for _, t := range []struct {
+		n     int
+		sigma int
+	}{
+		{0, 1},
+		{1, 1},
+		{100, 1},
+		{1000, 2},
+		{1000, 5},
+		{2000, 20},
+		{3000, 256},
+	} {
+		seq := randomBytes(t.n, t.sigma)
+		w := NewWaveletMatrix(seq)
+		c.Check(w.Len(), check.Equals, t.n)
+		counts := make([]int, 256)
+		for i, v := range seq {
+			c.Assert(w.Access(i), check.Equals, v)
+			for _, sym := range []byte{v, byte(rand.Intn(256))} {
+				c.Assert(w.Rank(sym, i), check.Equals, counts[sym], check.Commentf("%+v i=%d c=%d", t, i, sym))
+			}
+			c.Assert(w.Select(v, counts[v]), check.Equals, i, check.Commentf("%+v i=%d", t, i))
+			counts[v]++
+		}
+		for sym, n := range counts {
+			c.Check(w.Rank(byte(sym), t.n), check.Equals, n)
+			c.Check(w.Select(byte(sym), n), check.Equals, -1)
+		}
+	}
+}
+
+func naiveRank(s []byte, c byte, i int) int {
+	var n int
+	for _, b := range s[:i] {
+		if b == c {
+			n++
+		}
+	}
+	return n
+}
+
+var benchSeq = randomBytes(1e6, 5)
+
+func BenchmarkRankNaive(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		naiveRank(benchSeq, byte(i%5), (i*7919)%len(benchSeq))
+	}
+}
+
+func BenchmarkRankWaveletMatrix(b *testing.B) {
+	w := NewWaveletMatrix(benchSeq)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Rank(byte(i%5), (i*7919)%len(benchSeq))
+	}
+}
+
+func BenchmarkRankBitVector(b *testing.B) {
+	bv := NewBitVector(len(benchSeq))
+	for i, c := range benchSeq {
+		if c == 1 {
+			bv.Set(i)
+		}
+	}
+	bv.Build()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bv.Rank1((i * 7919) % len(benchSeq))
+	}
+}
+
+func BenchmarkSelectWaveletMatrix(b *testing.B) {
+	w := NewWaveletMatrix(benchSeq)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Select(byte(i%5), i%150000)
+	}
+}
+
+func BenchmarkBuildWaveletMatrix(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewWaveletMatrix(benchSeq)
+	}
+}
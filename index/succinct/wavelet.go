@@ -0,0 +1,143 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package succinct
+
+// A WaveletMatrix is a wavelet matrix over a sequence of bytes supporting access, rank and
+// select queries in time proportional to the number of bits needed to represent the largest
+// symbol in the sequence. The matrix uses one bit vector of the sequence length for each bit
+// of the largest symbol.
+//
+// The wavelet matrix is described in Claude, Navarro and Ordóñez "The wavelet matrix: an
+// efficient wavelet tree for large alphabets." Information Systems 47:15–32 (2015).
+type WaveletMatrix struct {
+	n      int
+	levels []*BitVector // Levels from the most significant bit.
+	zeros  []int        // Number of unset bits in each level.
+}
+
+// NewWaveletMatrix returns a WaveletMatrix of the sequence s.
+func NewWaveletMatrix(s []byte) *WaveletMatrix {
+	var max byte
+	for _, c := range s {
+		if c > max {
+			max = c
+		}
+	}
+	depth := 1
+	for max>>uint(depth) != 0 {
+		depth++
+	}
+
+	w := &WaveletMatrix{
+		n:      len(s),
+		levels: make([]*BitVector, depth),
+		zeros:  make([]int, depth),
+	}
+	cur := append([]byte(nil), s...)
+	next := make([]byte, len(s))
+	for l := range w.levels {
+		bit := uint(depth - 1 - l)
+		bv := NewBitVector(len(s))
+		var z int
+		for i, c := range cur {
+			if c>>bit&1 != 0 {
+				bv.Set(i)
+			} else {
+				z++
+			}
+		}
+		bv.Build()
+		w.levels[l] = bv
+		w.zeros[l] = z
+
+		// Stably partition the sequence by the
+		// current bit to form the next level.
+		zi, oi := 0, z
+		for _, c := range cur {
+			if c>>bit&1 == 0 {
+				next[zi] = c
+				zi++
+			} else {
+				next[oi] = c
+				oi++
+			}
+		}
+		cur, next = next, cur
+	}
+	return w
+}
+
+// Len returns the length of the sequence represented by the matrix.
+func (w *WaveletMatrix) Len() int { return w.n }
+
+// Access returns the symbol at position i of the sequence.
+func (w *WaveletMatrix) Access(i int) byte {
+	if i < 0 || i >= w.n {
+		panic("succinct: index out of range")
+	}
+	var c byte
+	for l, bv := range w.levels {
+		c <<= 1
+		if bv.Get(i) {
+			c |= 1
+			i = w.zeros[l] + bv.Rank1(i)
+		} else {
+			i = bv.Rank0(i)
+		}
+	}
+	return c
+}
+
+// Rank returns the number of occurrences of c in the sequence before position i.
+func (w *WaveletMatrix) Rank(c byte, i int) int {
+	if i < 0 || i > w.n {
+		panic("succinct: index out of range")
+	}
+	if int(c)>>uint(len(w.levels)) != 0 {
+		return 0
+	}
+	var p int
+	for l, bv := range w.levels {
+		if c>>uint(len(w.levels)-1-l)&1 != 0 {
+			p, i = w.zeros[l]+bv.Rank1(p), w.zeros[l]+bv.Rank1(i)
+		} else {
+			p, i = bv.Rank0(p), bv.Rank0(i)
+		}
+	}
+	return i - p
+}
+
+// Select returns the position of the kth occurrence of c in the sequence, counting from zero.
+// If c occurs no more than k times, Select returns -1.
+func (w *WaveletMatrix) Select(c byte, k int) int {
+	if k < 0 || int(c)>>uint(len(w.levels)) != 0 {
+		return -1
+	}
+
+	// Find the start of the block of c in the last level.
+	var p, e int
+	e = w.n
+	for l, bv := range w.levels {
+		if c>>uint(len(w.levels)-1-l)&1 != 0 {
+			p, e = w.zeros[l]+bv.Rank1(p), w.zeros[l]+bv.Rank1(e)
+		} else {
+			p, e = bv.Rank0(p), bv.Rank0(e)
+		}
+	}
+	if k >= e-p {
+		return -1
+	}
+
+	// Map the position of the kth c back through the levels.
+	i := p + k
+	for l := len(w.levels) - 1; l >= 0; l-- {
+		if c>>uint(len(w.levels)-1-l)&1 != 0 {
+			i = w.levels[l].Select1(i - w.zeros[l])
+		} else {
+			i = w.levels[l].Select0(i)
+		}
+	}
+	return i
+}
@@ -0,0 +1,176 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package suffixarray
+
+// This is an implementation of the SA-IS algorithm described in
+// "Two Efficient Algorithms for Linear Time Suffix Array Construction"
+//   by G. Nong, S. Zhang and W. H. Chan
+// paper: http://dx.doi.org/10.1109/TC.2010.188
+
+// sais computes the suffix array of text into sa. The symbols of text must be in [0, k) and
+// the last symbol of text must be unique and the smallest symbol in text. The length of sa
+// must equal the length of text.
+func sais(text, sa []int, k int) {
+	n := len(text)
+	if n == 1 {
+		sa[0] = 0
+		return
+	}
+
+	// Classify suffixes as S-type (true) or L-type (false).
+	t := make([]bool, n)
+	t[n-1] = true
+	for i := n - 2; i >= 0; i-- {
+		t[i] = text[i] < text[i+1] || (text[i] == text[i+1] && t[i+1])
+	}
+	isLMS := func(i int) bool { return i > 0 && t[i] && !t[i-1] }
+
+	bkt := make([]int, k)
+	buckets := func(end bool) {
+		for i := range bkt {
+			bkt[i] = 0
+		}
+		for _, c := range text {
+			bkt[c]++
+		}
+		var sum int
+		for i, c := range bkt {
+			sum += c
+			if end {
+				bkt[i] = sum
+			} else {
+				bkt[i] = sum - c
+			}
+		}
+	}
+	induce := func() {
+		buckets(false)
+		for i := 0; i < n; i++ {
+			if j := sa[i] - 1; j >= 0 && !t[j] {
+				sa[bkt[text[j]]] = j
+				bkt[text[j]]++
+			}
+		}
+		buckets(true)
+		for i := n - 1; i >= 0; i-- {
+			if j := sa[i] - 1; j >= 0 && t[j] {
+				bkt[text[j]]--
+				sa[bkt[text[j]]] = j
+			}
+		}
+	}
+
+	// Stage 1: sort the LMS substrings.
+	buckets(true)
+	for i := range sa {
+		sa[i] = -1
+	}
+	for i := 1; i < n; i++ {
+		if isLMS(i) {
+			bkt[text[i]]--
+			sa[bkt[text[i]]] = i
+		}
+	}
+	induce()
+
+	// Compact the sorted LMS substrings into the start of sa.
+	var n1 int
+	for i := 0; i < n; i++ {
+		if isLMS(sa[i]) {
+			sa[n1] = sa[i]
+			n1++
+		}
+	}
+
+	// Name the LMS substrings.
+	for i := n1; i < n; i++ {
+		sa[i] = -1
+	}
+	name, prev := 0, -1
+	for i := 0; i < n1; i++ {
+		pos := sa[i]
+		diff := false
+		for d := 0; ; d++ {
+			if prev == -1 || text[pos+d] != text[prev+d] || t[pos+d] != t[prev+d] {
+				diff = true
+				break
+			}
+			if d > 0 && (isLMS(pos+d) || isLMS(prev+d)) {
+				break
+			}
+		}
+		if diff {
+			name++
+			prev = pos
+		}
+		sa[n1+pos/2] = name - 1
+	}
+	for i, j := n-1, n-1; i >= n1; i-- {
+		if sa[i] >= 0 {
+			sa[j] = sa[i]
+			j--
+		}
+	}
+
+	// Stage 2: sort the reduced problem, recurring if names are not unique.
+	s1, sa1 := sa[n-n1:], sa[:n1]
+	if name < n1 {
+		sais(s1, sa1, name)
+	} else {
+		for i, c := range s1 {
+			sa1[c] = i
+		}
+	}
+
+	// Stage 3: induce the suffix array from the sorted LMS suffixes.
+	buckets(true)
+	for i, j := 1, 0; i < n; i++ {
+		if isLMS(i) {
+			s1[j] = i
+			j++
+		}
+	}
+	for i, c := range sa1 {
+		sa1[i] = s1[c]
+	}
+	for i := n1; i < n; i++ {
+		sa[i] = -1
+	}
+	for i := n1 - 1; i >= 0; i-- {
+		j := sa[i]
+		sa[i] = -1
+		bkt[text[j]]--
+		sa[bkt[text[j]]] = j
+	}
+	induce()
+}
+
+// kasai returns the LCP array of text given its suffix array sa. The ith element of the
+// returned slice is the length of the longest common prefix of the suffixes at sa[i-1] and
+// sa[i]; the first element is zero.
+func kasai(text, sa []int) []int {
+	n := len(text)
+	rank := make([]int, n)
+	for i, p := range sa {
+		rank[p] = i
+	}
+	lcp := make([]int, n)
+	var h int
+	for i := 0; i < n; i++ {
+		if rank[i] == 0 {
+			h = 0
+			continue
+		}
+		j := sa[rank[i]-1]
+		for i+h < n && j+h < n && text[i+h] == text[j+h] {
+			h++
+		}
+		lcp[rank[i]] = h
+		if h > 0 {
+			h--
+		}
+	}
+	return lcp
+}
@@ -0,0 +1,449 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package suffixarray provides generalised suffix arrays over sets of sequences, with LCP arrays
+// and repeat and matching statistics queries.
+//
+// Suffix arrays are constructed in linear time using the SA-IS algorithm and LCP arrays using
+// Kasai's algorithm. The sequences of a suffix array are each terminated by a unique separator,
+// so no common prefix extends beyond the end of a sequence.
+package suffixarray
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// A SuffixArray is a generalised suffix array over a set of sequences.
+type SuffixArray struct {
+	alpha alphabet.Alphabet
+	index alphabet.Index
+
+	text []int
+	sa   []int
+	lcp  []int
+
+	names  []string
+	starts []int
+
+	// links is built on the first call to
+	// MatchingStatistics and then reused.
+	linksOnce sync.Once
+	links     *suffixLinks
+}
+
+// New returns a SuffixArray of the provided sequences. All the sequences must share an alphabet
+// and contain only valid letters.
+func New(seqs []*linear.Seq) (*SuffixArray, error) {
+	if len(seqs) == 0 {
+		return nil, errors.New("suffixarray: no sequences")
+	}
+	alpha := seqs[0].Alpha
+	if alpha == nil {
+		return nil, errors.New("suffixarray: no alphabet")
+	}
+	s := &SuffixArray{
+		alpha:  alpha,
+		index:  alpha.LetterIndex(),
+		names:  make([]string, len(seqs)),
+		starts: make([]int, len(seqs)),
+	}
+
+	var n int
+	for _, sq := range seqs {
+		n += len(sq.Seq) + 1
+	}
+	// Separators are coded below the letters of the alphabet in
+	// decreasing order so that the final separator is the smallest.
+	sep := len(seqs)
+	s.text = make([]int, 0, n)
+	for i, sq := range seqs {
+		if sq.Alpha != alpha {
+			return nil, fmt.Errorf("suffixarray: alphabet mismatch for %q", sq.Name())
+		}
+		s.names[i] = sq.Name()
+		s.starts[i] = len(s.text)
+		for j, l := range sq.Seq {
+			c := s.index[l]
+			if c < 0 {
+				return nil, fmt.Errorf("suffixarray: invalid letter %q at %s:%d", l, sq.Name(), j)
+			}
+			s.text = append(s.text, c+sep)
+		}
+		s.text = append(s.text, sep-1-i)
+	}
+
+	s.sa = make([]int, n)
+	sais(s.text, s.sa, sep+alpha.Len())
+	s.lcp = kasai(s.text, s.sa)
+
+	return s, nil
+}
+
+// Alphabet returns the alphabet of the indexed sequences.
+func (s *SuffixArray) Alphabet() alphabet.Alphabet { return s.alpha }
+
+// Len returns the length of the indexed text, including sequence separators.
+func (s *SuffixArray) Len() int { return len(s.text) }
+
+// Sequences returns the number of sequences in the suffix array.
+func (s *SuffixArray) Sequences() int { return len(s.names) }
+
+// Name returns the name of the ith indexed sequence.
+func (s *SuffixArray) Name(i int) string { return s.names[i] }
+
+// SA returns the suffix array of the concatenated text. The returned slice should not be altered.
+func (s *SuffixArray) SA() []int { return s.sa }
+
+// LCP returns the LCP array of the concatenated text. The ith element of the returned slice is
+// the length of the longest common prefix of the suffixes at SA()[i-1] and SA()[i]; the first
+// element is zero. The returned slice should not be altered.
+func (s *SuffixArray) LCP() []int { return s.lcp }
+
+// Resolve returns the index of the sequence holding the text position pos and the offset of
+// pos within that sequence.
+func (s *SuffixArray) Resolve(pos int) (seq, offset int) {
+	seq = sort.SearchInts(s.starts, pos+1) - 1
+	return seq, pos - s.starts[seq]
+}
+
+// A Location is a position in an indexed sequence.
+type Location struct {
+	Seq    int
+	Name   string
+	Offset int
+}
+
+func (l Location) String() string { return fmt.Sprintf("%s:%d", l.Name, l.Offset) }
+
+// A Repeat is a substring occurring more than once in the indexed sequences.
+type Repeat struct {
+	Length    int
+	Locations []Location
+}
+
+type locations []Location
+
+func (l locations) Len() int { return len(l) }
+func (l locations) Less(i, j int) bool {
+	return l[i].Seq < l[j].Seq || (l[i].Seq == l[j].Seq && l[i].Offset < l[j].Offset)
+}
+func (l locations) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
+
+// repeat returns the Repeat of length n described by the suffixes in sa[lb:rb].
+func (s *SuffixArray) repeat(n, lb, rb int) Repeat {
+	r := Repeat{Length: n, Locations: make(locations, 0, rb-lb)}
+	for _, p := range s.sa[lb:rb] {
+		seq, off := s.Resolve(p)
+		r.Locations = append(r.Locations, Location{Seq: seq, Name: s.names[seq], Offset: off})
+	}
+	sort.Sort(locations(r.Locations))
+	return r
+}
+
+// LongestRepeats returns the longest substrings that occur more than once in the indexed
+// sequences. If no letter occurs more than once, LongestRepeats returns nil.
+func (s *SuffixArray) LongestRepeats() []Repeat {
+	var max int
+	for _, l := range s.lcp {
+		if l > max {
+			max = l
+		}
+	}
+	if max == 0 {
+		return nil
+	}
+	var r []Repeat
+	for i := 1; i < len(s.lcp); i++ {
+		if s.lcp[i] != max {
+			continue
+		}
+		lb := i - 1
+		for i < len(s.lcp) && s.lcp[i] == max {
+			i++
+		}
+		r = append(r, s.repeat(max, lb, i))
+	}
+	return r
+}
+
+const (
+	unseen  = -1
+	diverse = -2
+)
+
+// leftOf returns the symbol preceding the suffix at pos, or diverse if the suffix is at the start
+// of the text or is preceded by a separator.
+func (s *SuffixArray) leftOf(pos int) int {
+	if pos == 0 || s.text[pos-1] < len(s.names) {
+		return diverse
+	}
+	return s.text[pos-1]
+}
+
+func mergeLeft(a, b int) int {
+	switch {
+	case a == unseen:
+		return b
+	case b == unseen:
+		return a
+	case a != b:
+		return diverse
+	}
+	return a
+}
+
+// MaximalRepeats returns the maximal repeats in the indexed sequences with a length of at least
+// min. A maximal repeat is a repeated substring that cannot be extended to the left or the right
+// without losing an occurrence. Repeats are sorted by the location of their first occurrence and
+// then by length.
+func (s *SuffixArray) MaximalRepeats(min int) []Repeat {
+	if min < 1 {
+		min = 1
+	}
+	type lcpInterval struct {
+		lcp, lb int
+		left    int
+	}
+	var (
+		r     []Repeat
+		stack = []lcpInterval{{lcp: 0, lb: 0, left: unseen}}
+		n     = len(s.sa)
+	)
+	for i := 1; i <= n; i++ {
+		l := 0
+		if i < n {
+			l = s.lcp[i]
+		}
+		c := s.leftOf(s.sa[i-1])
+		top := &stack[len(stack)-1]
+		top.left = mergeLeft(top.left, c)
+		lb := i - 1
+		last := unseen
+		popped := false
+		for l < stack[len(stack)-1].lcp {
+			iv := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if iv.lcp >= min && iv.left == diverse {
+				r = append(r, s.repeat(iv.lcp, iv.lb, i))
+			}
+			lb = iv.lb
+			if top := &stack[len(stack)-1]; l <= top.lcp {
+				top.left = mergeLeft(top.left, iv.left)
+				popped = false
+			} else {
+				last = iv.left
+				popped = true
+			}
+		}
+		if l > stack[len(stack)-1].lcp {
+			if !popped {
+				last = c
+			}
+			stack = append(stack, lcpInterval{lcp: l, lb: lb, left: last})
+		}
+	}
+	sort.Sort(repeats(r))
+	return r
+}
+
+// repeats sorts repeats by the location of their first occurrence and then by length.
+type repeats []Repeat
+
+func (r repeats) Len() int { return len(r) }
+func (r repeats) Less(i, j int) bool {
+	a, b := r[i].Locations[0], r[j].Locations[0]
+	switch {
+	case a.Seq != b.Seq:
+		return a.Seq < b.Seq
+	case a.Offset != b.Offset:
+		return a.Offset < b.Offset
+	}
+	return r[i].Length < r[j].Length
+}
+func (r repeats) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+
+// MatchingStatistics returns the matching statistics of query against the indexed sequences. The
+// ith element of the returned slice is the length of the longest prefix of query[i:] that occurs
+// in the indexed sequences. Letters of query that are not valid in the suffix array alphabet do
+// not match.
+//
+// The suffix array interval of each match is found from the interval of the previous match using
+// the inverse suffix array and the LCP array, taking O(log n) time per query position in addition
+// to the time taken to extend matches. The inverse suffix array and an index of the LCP array,
+// together taking a little over n words, are built on the first call and retained.
+func (s *SuffixArray) MatchingStatistics(query alphabet.Letters) []int {
+	s.linksOnce.Do(func() { s.links = newSuffixLinks(s.sa, s.lcp) })
+
+	ms := make([]int, len(query))
+	code := make([]int, len(query))
+	sep := len(s.names)
+	for i, l := range query {
+		if c := s.index[l]; c >= 0 {
+			code[i] = c + sep
+		} else {
+			code[i] = -1
+		}
+	}
+
+	var (
+		l      int
+		lo, hi = 0, len(s.sa)
+	)
+	for i := range query {
+		// The prefix of length l-1 of query[i:] is known to occur at
+		// the position following the previous match, so its interval
+		// is the LCP interval of at least l-1 around that suffix.
+		if l > 0 {
+			l--
+			if l == 0 {
+				lo, hi = 0, len(s.sa)
+			} else {
+				lo, hi = s.links.interval(s.links.isa[s.sa[lo]+1], l)
+			}
+		}
+		for i+l < len(query) && code[i+l] >= 0 {
+			nlo, nhi := s.narrow(lo, hi, l, code[i+l])
+			if nlo >= nhi {
+				break
+			}
+			lo, hi = nlo, nhi
+			l++
+		}
+		ms[i] = l
+	}
+	return ms
+}
+
+// lcpBlock is the number of LCP values summarised by each block minimum of a suffixLinks.
+const lcpBlock = 64
+
+// suffixLinks holds the inverse suffix array and a sparse table of the minima of blocks of the
+// LCP array, allowing the suffix array interval of the prefixes of a suffix to be found in
+// O(log n) time.
+type suffixLinks struct {
+	isa []int
+	lcp []int
+
+	// min[j][b] is the minimum LCP value in blocks b to b+2^j-1.
+	min [][]int
+}
+
+func newSuffixLinks(sa, lcp []int) *suffixLinks {
+	l := &suffixLinks{isa: make([]int, len(sa)), lcp: lcp}
+	for i, p := range sa {
+		l.isa[p] = i
+	}
+	nb := (len(lcp) + lcpBlock - 1) / lcpBlock
+	if nb == 0 {
+		return l
+	}
+	base := make([]int, nb)
+	for b := range base {
+		m := lcp[b*lcpBlock]
+		for _, v := range lcp[b*lcpBlock : min(b*lcpBlock+lcpBlock, len(lcp))] {
+			if v < m {
+				m = v
+			}
+		}
+		base[b] = m
+	}
+	l.min = [][]int{base}
+	for w := 1; 2*w <= nb; w *= 2 {
+		prev := l.min[len(l.min)-1]
+		next := make([]int, nb-2*w+1)
+		for b := range next {
+			next[b] = min(prev[b], prev[b+w])
+		}
+		l.min = append(l.min, next)
+	}
+	return l
+}
+
+// blockMin returns the minimum LCP value in blocks a to b inclusive.
+func (l *suffixLinks) blockMin(a, b int) int {
+	j := 0
+	for 2<<uint(j) <= b-a+1 {
+		j++
+	}
+	return min(l.min[j][a], l.min[j][b-1<<uint(j)+1])
+}
+
+// interval returns the suffix array interval of the suffixes sharing a prefix of length d > 0
+// with the suffix at row r.
+func (l *suffixLinks) interval(r, d int) (lo, hi int) {
+	// lo is the last row at or before r with an LCP less than d.
+	// lcp[0] is zero, so there is always such a row.
+	lo = -1
+	br := r / lcpBlock
+	for t := r; t >= br*lcpBlock; t-- {
+		if l.lcp[t] < d {
+			lo = t
+			break
+		}
+	}
+	if lo < 0 {
+		// Find the last whole block before br holding a value less than d.
+		b := sort.Search(br, func(b int) bool { return l.blockMin(b, br-1) >= d }) - 1
+		for t := b*lcpBlock + lcpBlock - 1; ; t-- {
+			if l.lcp[t] < d {
+				lo = t
+				break
+			}
+		}
+	}
+
+	// hi is the first row after r with an LCP less than d, or
+	// the end of the suffix array.
+	hi = -1
+	end := min(br*lcpBlock+lcpBlock, len(l.lcp))
+	for t := r + 1; t < end; t++ {
+		if l.lcp[t] < d {
+			hi = t
+			break
+		}
+	}
+	if hi < 0 {
+		nb := len(l.min[0])
+		if br+1 == nb || l.blockMin(br+1, nb-1) >= d {
+			return lo, len(l.lcp)
+		}
+		// Find the first whole block after br holding a value less than d.
+		b := br + 1 + sort.Search(nb-br-1, func(i int) bool { return l.blockMin(br+1, br+1+i) < d })
+		for t := b * lcpBlock; ; t++ {
+			if l.lcp[t] < d {
+				hi = t
+				break
+			}
+		}
+	}
+	return lo, hi
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// narrow returns the interval of suffixes in sa[lo:hi], which share a prefix of length d,
+// that have c at offset d.
+func (s *SuffixArray) narrow(lo, hi, d, c int) (int, int) {
+	sym := func(i int) int {
+		p := s.sa[i] + d
+		if p >= len(s.text) {
+			return -1
+		}
+		return s.text[p]
+	}
+	l := lo + sort.Search(hi-lo, func(i int) bool { return sym(lo+i) >= c })
+	h := l + sort.Search(hi-l, func(i int) bool { return sym(l+i) > c })
+	return l, h
+}
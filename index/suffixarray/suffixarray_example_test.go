@@ -0,0 +1,35 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package suffixarray_test
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/index/suffixarray"
+	"code.google.com/p/biogo/seq/linear"
+
+	"fmt"
+)
+
+func ExampleSuffixArray_MaximalRepeats() {
+	sa, err := suffixarray.New([]*linear.Seq{
+		linear.NewSeq("chr1", alphabet.BytesToLetters([]byte("gattacagattaccc")), alphabet.DNA),
+		linear.NewSeq("chr2", alphabet.BytesToLetters([]byte("tttacagatt")), alphabet.DNA),
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, r := range sa.MaximalRepeats(4) {
+		fmt.Println(r.Length, r.Locations)
+	}
+
+	fmt.Println(sa.MatchingStatistics(alphabet.Letters("acagattt")))
+	// Output:
+	// 4 [chr1:0 chr1:7 chr2:6]
+	// 6 [chr1:0 chr1:7]
+	// 4 [chr1:2 chr1:9 chr2:1]
+	// 9 [chr1:2 chr2:1]
+	// [7 6 5 4 3 3 2 1]
+}
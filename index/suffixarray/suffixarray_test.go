@@ -0,0 +1,231 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package suffixarray
+
+import (
+	"code.google.com/p/biogo/alphabet"
+	"code.google.com/p/biogo/seq/linear"
+
+	check "launchpad.net/gocheck"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Helpers
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func randomSeqs(n, l int, letters string) []*linear.Seq {
+	s := make([]*linear.Seq, n)
+	for i := range s {
+		b := make([]byte, rand.Intn(l)+1)
+		for j := range b {
+			b[j] = letters[rand.Intn(len(letters))]
+		}
+		s[i] = linear.NewSeq(string('a'+byte(i)), alphabet.BytesToLetters(b), alphabet.DNA)
+	}
+	return s
+}
+
+// naiveSA returns the suffix array of text by comparison sorting.
+func naiveSA(text []int) []int {
+	sa := make([]int, len(text))
+	for i := range sa {
+		sa[i] = i
+	}
+	sort.Sort(suffixes{text, sa})
+	return sa
+}
+
+type suffixes struct {
+	text, sa []int
+}
+
+func (s suffixes) Len() int { return len(s.sa) }
+func (s suffixes) Less(i, j int) bool {
+	a, b := s.text[s.sa[i]:], s.text[s.sa[j]:]
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if a[k] != b[k] {
+			return a[k] < b[k]
+		}
+	}
+	return len(a) < len(b)
+}
+func (s suffixes) Swap(i, j int) { s.sa[i], s.sa[j] = s.sa[j], s.sa[i] }
+
+func (s *S) TestSAIS(c *check.C) {
+	for _, k := range []int{2, 3, 4, 20, 300} {
+		for trial := 0; trial < 50; trial++ {
+			text := make([]int, rand.Intn(200)+1)
+			for i := range text {
+				text[i] = rand.Intn(k-1) + 1
+			}
+			text[len(text)-1] = 0
+			sa := make([]int, len(text))
+			sais(text, sa, k)
+			c.Check(sa, check.DeepEquals, naiveSA(text), check.Commentf("k=%d text=%v", k, text))
+		}
+	}
+}
+
+func (s *S) TestLCP(c *check.C) {
+	for trial := 0; trial < 20; trial++ {
+		sa, err := New(randomSeqs(rand.Intn(5)+1, 100, "ac"))
+		c.Assert(err, check.Equals, nil)
+		c.Check(sa.SA(), check.DeepEquals, naiveSA(sa.text))
+		for i := 1; i < sa.Len(); i++ {
+			a, b := sa.text[sa.sa[i-1]:], sa.text[sa.sa[i]:]
+			var l int
+			for l < len(a) && l < len(b) && a[l] == b[l] {
+				l++
+			}
+			c.Check(sa.LCP()[i], check.Equals, l)
+		}
+	}
+}
+
+func (s *S) TestLongestRepeats(c *check.C) {
+	sa, err := New([]*linear.Seq{
+		linear.NewSeq("a", alphabet.BytesToLetters([]byte("gattacagatta")), alphabet.DNA),
+		linear.NewSeq("b", alphabet.BytesToLetters([]byte("ccgattacc")), alphabet.DNA),
+	})
+	c.Assert(err, check.Equals, nil)
+	c.Check(sa.LongestRepeats(), check.DeepEquals, []Repeat{
+		{Length: 6, Locations: []Location{{0, "a", 0}, {1, "b", 2}}},
+	})
+
+	sa, err = New([]*linear.Seq{linear.NewSeq("a", alphabet.BytesToLetters([]byte("acgt")), alphabet.DNA)})
+	c.Assert(err, check.Equals, nil)
+	c.Check(sa.LongestRepeats(), check.IsNil)
+}
+
+// naiveMaximalRepeats returns the maximal repeats of seqs of at least length min.
+func naiveMaximalRepeats(seqs []*linear.Seq, min int) []Repeat {
+	var text []string
+	for _, s := range seqs {
+		text = append(text, string(alphabet.LettersToBytes(s.Seq)))
+	}
+	occ := make(map[string][]Location)
+	for i, t := range text {
+		for p := 0; p < len(t); p++ {
+			for e := p + min; e <= len(t); e++ {
+				occ[t[p:e]] = append(occ[t[p:e]], Location{i, seqs[i].Name(), p})
+			}
+		}
+	}
+	var r []Repeat
+	for sub, locs := range occ {
+		if len(locs) < 2 {
+			continue
+		}
+		left, right := make(map[int]bool), make(map[int]bool)
+		for i, l := range locs {
+			if l.Offset == 0 {
+				left[-1-i] = true
+			} else {
+				left[int(text[l.Seq][l.Offset-1])] = true
+			}
+			if e := l.Offset + len(sub); e == len(text[l.Seq]) {
+				right[-1-i] = true
+			} else {
+				right[int(text[l.Seq][e])] = true
+			}
+		}
+		if len(left) > 1 && len(right) > 1 {
+			sort.Sort(locations(locs))
+			r = append(r, Repeat{Length: len(sub), Locations: locs})
+		}
+	}
+	sort.Sort(repeats(r))
+	return r
+}
+
+func (s *S) TestMaximalRepeats(c *check.C) {
+	for trial := 0; trial < 20; trial++ {
+		seqs := randomSeqs(rand.Intn(3)+1, 60, "acg")
+		for _, min := range []int{1, 3} {
+			sa, err := New(seqs)
+			c.Assert(err, check.Equals, nil)
+			c.Check(sa.MaximalRepeats(min), check.DeepEquals, naiveMaximalRepeats(seqs, min))
+		}
+	}
+}
+
+func (s *S) TestMatchingStatistics(c *check.C) {
+	for trial := 0; trial < 20; trial++ {
+		seqs := randomSeqs(rand.Intn(3)+1, 60, "acgt")
+		var text []string
+		for _, s := range seqs {
+			text = append(text, string(alphabet.LettersToBytes(s.Seq)))
+		}
+		sa, err := New(seqs)
+		c.Assert(err, check.Equals, nil)
+
+		q := randomSeqs(1, 40, "acgtn")[0]
+		ms := sa.MatchingStatistics(q.Seq)
+		qs := string(alphabet.LettersToBytes(q.Seq))
+		for i := range qs {
+			var want int
+			for e := i + 1; e <= len(qs); e++ {
+				found := false
+				for _, t := range text {
+					if strings.Contains(t, qs[i:e]) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					break
+				}
+				want = e - i
+			}
+			c.Check(ms[i], check.Equals, want, check.Commentf("query %q at %d", qs, i))
+		}
+	}
+}
+
+// scratchMatchingStatistics returns the matching statistics of query by finding the interval
+// of each match from the full suffix array.
+func scratchMatchingStatistics(s *SuffixArray, query alphabet.Letters) []int {
+	ms := make([]int, len(query))
+	sep := len(s.names)
+	for i := range query {
+		lo, hi := 0, len(s.sa)
+		l := 0
+		for ; i+l < len(query) && s.index[query[i+l]] >= 0; l++ {
+			nlo, nhi := s.narrow(lo, hi, l, s.index[query[i+l]]+sep)
+			if nlo >= nhi {
+				break
+			}
+			lo, hi = nlo, nhi
+		}
+		ms[i] = l
+	}
+	return ms
+}
+
+func (s *S) TestMatchingStatisticsLong(c *check.C) {
+	for trial := 0; trial < 5; trial++ {
+		seqs := randomSeqs(rand.Intn(3)+1, 5000, "acgt")
+		sa, err := New(seqs)
+		c.Assert(err, check.Equals, nil)
+
+		// Build a query from mutated pieces of the indexed sequences
+		// so that matches are long and span many LCP blocks.
+		var q alphabet.Letters
+		for len(q) < 2000 {
+			src := seqs[rand.Intn(len(seqs))].Seq
+			start := rand.Intn(len(src))
+			q = append(q, src[start:min(len(src), start+rand.Intn(100))]...)
+			q = append(q, alphabet.Letter("acgtn"[rand.Intn(5)]))
+		}
+		c.Check(sa.MatchingStatistics(q), check.DeepEquals, scratchMatchingStatistics(sa, q))
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package mapped provides memory mapping of files and access to little-endian integer arrays
+// held in byte slices, so that serialised indexes may be used in place.
+package mapped
+
+import (
+	"encoding/binary"
+	"reflect"
+	"unsafe"
+)
+
+var (
+	littleEndian = func() bool {
+		x := uint16(1)
+		return *(*byte)(unsafe.Pointer(&x)) == 1
+	}()
+	int64Ints = unsafe.Sizeof(int(0)) == 8
+)
+
+// aligned returns whether the non-empty b starts at a multiple of n bytes.
+func aligned(b []byte, n uintptr) bool {
+	return uintptr(unsafe.Pointer(&b[0]))%n == 0
+}
+
+// alias sets the slice pointed to by s to refer to the data in the non-empty b with length and
+// capacity n.
+func alias(s unsafe.Pointer, b []byte, n int) {
+	h := (*reflect.SliceHeader)(s)
+	h.Data = uintptr(unsafe.Pointer(&b[0]))
+	h.Len = n
+	h.Cap = n
+}
+
+// Uint32s returns the little-endian uint32 values held in b. Where the platform is little-endian
+// and b is suitably aligned, the returned slice refers to the data in b, otherwise the values are
+// copied. The length of b must be a multiple of 4.
+func Uint32s(b []byte) []uint32 {
+	n := len(b) / 4
+	if n == 0 {
+		return []uint32{}
+	}
+	if littleEndian && aligned(b, unsafe.Alignof(uint32(0))) {
+		var s []uint32
+		alias(unsafe.Pointer(&s), b, n)
+		return s
+	}
+	s := make([]uint32, n)
+	for i := range s {
+		s[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+	return s
+}
+
+// Uint64s returns the little-endian uint64 values held in b. Where the platform is little-endian
+// and b is suitably aligned, the returned slice refers to the data in b, otherwise the values are
+// copied. The length of b must be a multiple of 8.
+func Uint64s(b []byte) []uint64 {
+	n := len(b) / 8
+	if n == 0 {
+		return []uint64{}
+	}
+	if littleEndian && aligned(b, unsafe.Alignof(uint64(0))) {
+		var s []uint64
+		alias(unsafe.Pointer(&s), b, n)
+		return s
+	}
+	s := make([]uint64, n)
+	for i := range s {
+		s[i] = binary.LittleEndian.Uint64(b[8*i:])
+	}
+	return s
+}
+
+// Ints returns the little-endian 64 bit integer values held in b as ints. Where the platform is
+// little-endian with 64 bit ints and b is suitably aligned, the returned slice refers to the data
+// in b, otherwise the values are copied. The length of b must be a multiple of 8.
+func Ints(b []byte) []int {
+	n := len(b) / 8
+	if n == 0 {
+		return []int{}
+	}
+	if littleEndian && int64Ints && aligned(b, unsafe.Alignof(int(0))) {
+		var s []int
+		alias(unsafe.Pointer(&s), b, n)
+		return s
+	}
+	s := make([]int, n)
+	for i := range s {
+		s[i] = int(binary.LittleEndian.Uint64(b[8*i:]))
+	}
+	return s
+}
@@ -0,0 +1,57 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mapped
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	check "launchpad.net/gocheck"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests
+func Test(t *testing.T) { check.TestingT(t) }
+
+type S struct{}
+
+var _ = check.Suite(&S{})
+
+func (s *S) TestSlices(c *check.C) {
+	u64 := []uint64{0, 1, 1 << 40, ^uint64(0)}
+	buf := make([]byte, 8*len(u64)+1)
+	for _, off := range []int{0, 1} {
+		b := buf[off : off+8*len(u64)]
+		for i, v := range u64 {
+			binary.LittleEndian.PutUint64(b[8*i:], v)
+		}
+		c.Check(Uint64s(b), check.DeepEquals, u64)
+		c.Check(Ints(b), check.DeepEquals, []int{0, 1, 1 << 40, -1})
+		c.Check(Uint32s(b), check.DeepEquals, []uint32{0, 0, 1, 0, 0, 1 << 8, ^uint32(0), ^uint32(0)})
+	}
+	c.Check(Uint32s(nil), check.HasLen, 0)
+	c.Check(Uint64s(nil), check.HasLen, 0)
+	c.Check(Ints(nil), check.HasLen, 0)
+}
+
+func (s *S) TestMap(c *check.C) {
+	dir, err := ioutil.TempDir("", "mapped")
+	c.Assert(err, check.Equals, nil)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "data")
+	want := []byte("mapped data")
+	c.Assert(ioutil.WriteFile(path, want, 0600), check.Equals, nil)
+	data, unmap, err := Map(path)
+	c.Assert(err, check.Equals, nil)
+	c.Check(data, check.DeepEquals, want)
+	if unmap != nil {
+		c.Check(unmap(), check.Equals, nil)
+	}
+
+	_, _, err = Map(filepath.Join(dir, "missing"))
+	c.Check(err, check.Not(check.Equals), nil)
+}
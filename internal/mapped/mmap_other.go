@@ -0,0 +1,18 @@
+// Copyright ©2013 The bíogo Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package mapped
+
+import (
+	"io/ioutil"
+)
+
+// Map returns the contents of the file at path. On this platform the file is read into memory,
+// so the returned unmap function is nil.
+func Map(path string) ([]byte, func() error, error) {
+	data, err := ioutil.ReadFile(path)
+	return data, nil, err
+}